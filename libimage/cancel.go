@@ -0,0 +1,57 @@
+package libimage
+
+import (
+	"context"
+	"io"
+
+	"github.com/containers/image/v5/types"
+)
+
+// cancelableReference is an image reference whose image sources stop reading
+// blobs once the context of GetBlob is done.  Local reads (e.g., of layers in
+// the containers storage or of archives) cannot be cancelled otherwise, such
+// that a cancelled copy would continue writing the remaining layers to the
+// destination.
+type cancelableReference struct {
+	types.ImageReference
+}
+
+// NewImageSource returns a cancelableSource for the reference.
+func (r cancelableReference) NewImageSource(ctx context.Context, sys *types.SystemContext) (types.ImageSource, error) {
+	src, err := r.ImageReference.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return cancelableSource{src}, nil
+}
+
+// cancelableSource is the image source of a cancelableReference.
+type cancelableSource struct {
+	types.ImageSource
+}
+
+// GetBlob returns the blob as a contextReader.
+func (s cancelableSource) GetBlob(ctx context.Context, info types.BlobInfo, cache types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	blob, size, err := s.ImageSource.GetBlob(ctx, info, cache)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &contextReader{ctx: ctx, ReadCloser: blob}, size, nil
+}
+
+// contextReader is a reader returning the error of its context once the
+// context is done.
+type contextReader struct {
+	ctx context.Context
+	io.ReadCloser
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadCloser.Read(p)
+}
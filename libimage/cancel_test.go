@@ -0,0 +1,41 @@
+package libimage
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/containers/image/v5/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blobSource struct {
+	types.ImageSource
+	blob string
+}
+
+func (s blobSource) GetBlob(context.Context, types.BlobInfo, types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	return ioutil.NopCloser(strings.NewReader(s.blob)), int64(len(s.blob)), nil
+}
+
+func TestCancelableSource(t *testing.T) {
+	src := cancelableSource{blobSource{blob: "0123456789"}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	blob, size, err := src.GetBlob(ctx, types.BlobInfo{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+	buf := make([]byte, 4)
+	n, err := blob.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "0123", string(buf[:n]))
+
+	cancel()
+	_, err = blob.Read(buf)
+	assert.Equal(t, context.Canceled, err)
+	_, _, err = src.GetBlob(ctx, types.BlobInfo{}, nil)
+	assert.Equal(t, context.Canceled, err)
+}
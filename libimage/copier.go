@@ -331,7 +331,9 @@ func (c *copier) copy(ctx context.Context, source, destination types.ImageRefere
 		}
 
 		var err error
-		copiedManifest, err = copy.Image(ctx, c.policyContext, destination, source, &opts)
+		// Make sure that a cancelled copy stops reading blobs, even if
+		// they're read from local storage or archives.
+		copiedManifest, err = copy.Image(ctx, c.policyContext, destination, cancelableReference{source}, &opts)
 		return err
 	}
 	return copiedManifest, retry.RetryIfNecessary(ctx, f, &c.retryOptions)
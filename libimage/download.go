@@ -1,12 +1,12 @@
 package libimage
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 
+	"github.com/containers/common/pkg/download"
 	"github.com/pkg/errors"
 )
 
@@ -22,25 +22,19 @@ func (r *Runtime) tmpdir() string {
 
 // downloadFromURL downloads an image in the format "https:/example.com/myimage.tar"
 // and temporarily saves in it $TMPDIR/importxyz, which is deleted after the image is imported
-func (r *Runtime) downloadFromURL(source string) (string, error) {
+func (r *Runtime) downloadFromURL(ctx context.Context, source string) (string, error) {
 	fmt.Printf("Downloading from %q\n", source)
 
 	outFile, err := ioutil.TempFile(r.tmpdir(), "import")
 	if err != nil {
 		return "", errors.Wrap(err, "error creating file")
 	}
-	defer outFile.Close()
+	outFile.Close()
 
-	response, err := http.Get(source) // nolint:noctx
-	if err != nil {
+	if err := download.FromURL(ctx, source, outFile.Name(), &download.Options{MaxRetry: 3}); err != nil {
+		os.Remove(outFile.Name())
 		return "", errors.Wrapf(err, "error downloading %q", source)
 	}
-	defer response.Body.Close()
-
-	_, err = io.Copy(outFile, response.Body)
-	if err != nil {
-		return "", errors.Wrapf(err, "error saving %s to %s", source, outFile.Name())
-	}
 
 	return outFile.Name(), nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"sort"
+	"sync"
 	"time"
 
 	libimageTypes "github.com/containers/common/libimage/types"
@@ -21,11 +22,21 @@ import (
 
 // Image represents an image in the containers storage and allows for further
 // operations and data manipulation.
+//
+// Images are safe for concurrent use.  Methods reading the image share a
+// consistent snapshot of its data, while methods modifying the image (e.g.,
+// Tag, Untag and Remove) are serialized.
 type Image struct {
 	// Backwards pointer to the runtime.
 	runtime *Runtime
 
-	// Counterpart in the local containers storage.
+	// writeLock serializes the operations modifying the image.
+	writeLock sync.Mutex
+	// dataLock protects storageImage and cached.
+	dataLock sync.RWMutex
+
+	// Counterpart in the local containers storage.  Never modified but
+	// replaced on reload.
 	storageImage *storage.Image
 
 	// Image reference to the containers storage.
@@ -53,6 +64,8 @@ func (i *Image) reload() error {
 	if err != nil {
 		return errors.Wrap(err, "error reloading image")
 	}
+	i.dataLock.Lock()
+	defer i.dataLock.Unlock()
 	i.storageImage = img
 	i.cached.imageSource = nil
 	i.cached.partialInspectData = nil
@@ -61,44 +74,51 @@ func (i *Image) reload() error {
 	return nil
 }
 
+// snapshot returns the current storage.Image, which must not be modified.
+func (i *Image) snapshot() *storage.Image {
+	i.dataLock.RLock()
+	defer i.dataLock.RUnlock()
+	return i.storageImage
+}
+
 // Names returns associated names with the image which may be a mix of tags and
 // digests.
 func (i *Image) Names() []string {
-	return i.storageImage.Names
+	return i.snapshot().Names
 }
 
 // StorageImage returns the underlying storage.Image.
 func (i *Image) StorageImage() *storage.Image {
-	return i.storageImage
+	return i.snapshot()
 }
 
 // NamesHistory returns a string array of names previously associated with the
 // image, which may be a mixture of tags and digests.
 func (i *Image) NamesHistory() []string {
-	return i.storageImage.NamesHistory
+	return i.snapshot().NamesHistory
 }
 
 // ID returns the ID of the image.
 func (i *Image) ID() string {
-	return i.storageImage.ID
+	return i.snapshot().ID
 }
 
 // Digest is a digest value that we can use to locate the image, if one was
 // specified at creation-time.
 func (i *Image) Digest() digest.Digest {
-	return i.storageImage.Digest
+	return i.snapshot().Digest
 }
 
 // Digests is a list of digest values of the image's manifests, and possibly a
 // manually-specified value, that we can use to locate the image.  If Digest is
 // set, its value is also in this list.
 func (i *Image) Digests() []digest.Digest {
-	return i.storageImage.Digests
+	return i.snapshot().Digests
 }
 
 // IsReadOnly returns whether the image is set read only.
 func (i *Image) IsReadOnly() bool {
-	return i.storageImage.ReadOnly
+	return i.snapshot().ReadOnly
 }
 
 // IsDangling returns true if the image is dangling.  An image is considered
@@ -124,7 +144,7 @@ func (i *Image) IsIntermediate(ctx context.Context) (bool, error) {
 
 // Created returns the time the image was created.
 func (i *Image) Created() time.Time {
-	return i.storageImage.Created
+	return i.snapshot().Created
 }
 
 // Labels returns the label of the image.
@@ -146,7 +166,7 @@ func (i *Image) Labels(ctx context.Context) (map[string]string, error) {
 
 // TopLayer returns the top layer id as a string
 func (i *Image) TopLayer() string {
-	return i.storageImage.TopLayer
+	return i.snapshot().TopLayer
 }
 
 // Parent returns the parent image or nil if there is none
@@ -255,6 +275,9 @@ type RemoveImageOptions struct {
 // If the image is used by containers return storage.ErrImageUsedByContainer.
 // Use force to remove these containers.
 func (i *Image) Remove(ctx context.Context, options *RemoveImageOptions) error {
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
 	logrus.Debugf("Removing image %s", i.ID())
 	if i.IsReadOnly() {
 		return errors.Errorf("cannot remove read-only image %q", i.ID())
@@ -280,7 +303,7 @@ func (i *Image) Remove(ctx context.Context, options *RemoveImageOptions) error {
 	if _, err := i.runtime.store.DeleteImage(i.ID(), true); err != nil {
 		return err
 	}
-	delete(i.runtime.imageIDmap, i.ID())
+	i.runtime.forgetImage(i.ID())
 
 	if parent == nil || !parent.IsDangling() {
 		return nil
@@ -297,6 +320,9 @@ func (i *Image) Tag(name string) error {
 		return errors.Wrapf(err, "error normalizing name %q", name)
 	}
 
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
 	logrus.Debugf("Tagging image %s with %q", i.ID(), ref.String())
 
 	newNames := append(i.Names(), ref.String())
@@ -317,6 +343,9 @@ func (i *Image) Untag(name string) error {
 	}
 	name = ref.String()
 
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
 	removedName := false
 	newNames := []string{}
 	for _, n := range i.Names() {
@@ -443,6 +472,20 @@ func (i *Image) MountPoint() (string, error) {
 	return filepath.EvalSymlinks(layer.MountPoint)
 }
 
+// layers returns the layers of the image from the bottom to the top layer.
+func (i *Image) layers() ([]*storage.Layer, error) {
+	var layers []*storage.Layer
+	for id := i.TopLayer(); id != ""; {
+		layer, err := i.runtime.store.Layer(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "looking up layer %s", id)
+		}
+		layers = append([]*storage.Layer{layer}, layers...)
+		id = layer.Parent
+	}
+	return layers, nil
+}
+
 // Size computes the size of the image layers and associated data.
 func (i *Image) Size() (int64, error) {
 	return i.runtime.store.ImageSize(i.ID())
@@ -547,8 +590,11 @@ func (i *Image) isManifestList(ctx context.Context) (bool, error) {
 
 // source returns the possibly cached image reference.
 func (i *Image) source(ctx context.Context) (types.ImageSource, error) {
-	if i.cached.imageSource != nil {
-		return i.cached.imageSource, nil
+	i.dataLock.RLock()
+	cached := i.cached.imageSource
+	i.dataLock.RUnlock()
+	if cached != nil {
+		return cached, nil
 	}
 	ref, err := i.StorageReference()
 	if err != nil {
@@ -558,6 +604,14 @@ func (i *Image) source(ctx context.Context) (types.ImageSource, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	i.dataLock.Lock()
+	defer i.dataLock.Unlock()
+	if i.cached.imageSource != nil {
+		// Another goroutine has been faster.
+		src.Close()
+		return i.cached.imageSource, nil
+	}
 	i.cached.imageSource = src
 	return src, nil
 }
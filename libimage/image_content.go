@@ -0,0 +1,298 @@
+package libimage
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/containers/storage"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/pkg/errors"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// FileMatch is a file of an image matching a pattern of FindFiles.
+type FileMatch struct {
+	// Path is the absolute path of the file in the image.
+	Path string
+	// Layer is the ID of the layer the file is in.
+	Layer string
+	Size  int64
+	Mode  int64
+	// Linkname is the target of links.
+	Linkname string
+}
+
+// Package is a package installed in an image.
+type Package struct {
+	Name    string
+	Version string
+	Arch    string
+	// Manager is the package manager, "dpkg" or "apk".
+	Manager string
+}
+
+// packageDatabases are the package databases parsed by ListPackages.
+var packageDatabases = map[string]func([]byte) []Package{
+	"/var/lib/dpkg/status":  parseDpkgStatus,
+	"/lib/apk/db/installed": parseApkInstalled,
+}
+
+// FindFiles returns the files of the image matching any of the patterns,
+// sorted by path.  Patterns containing a slash are matched against the
+// absolute path, other patterns against the base name, using the syntax of
+// path.Match.  The layers are read as tar streams without extracting
+// them, and files removed by upper layers are not returned.
+func (i *Image) FindFiles(ctx context.Context, patterns []string) ([]FileMatch, error) {
+	finder, err := newFileFinder(patterns)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.walkLayers(ctx, finder.visit); err != nil {
+		return nil, err
+	}
+	return finder.result(), nil
+}
+
+// fileFinder collects the files matching the patterns of FindFiles.
+type fileFinder struct {
+	patterns []string
+	matches  map[string]FileMatch
+}
+
+func newFileFinder(patterns []string) (*fileFinder, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+	}
+	return &fileFinder{patterns: patterns, matches: make(map[string]FileMatch)}, nil
+}
+
+func (f *fileFinder) visit(layer string, removed func(string) bool, hdr *tar.Header, content io.Reader) error {
+	if hdr == nil {
+		for p, m := range f.matches {
+			if m.Layer != layer && removed(p) {
+				delete(f.matches, p)
+			}
+		}
+		return nil
+	}
+	for _, pattern := range f.patterns {
+		subject := path.Base(hdr.Name)
+		if strings.Contains(pattern, "/") {
+			subject = hdr.Name
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			f.matches[hdr.Name] = FileMatch{Path: hdr.Name, Layer: layer, Size: hdr.Size, Mode: hdr.Mode, Linkname: hdr.Linkname}
+			return nil
+		}
+	}
+	// An upper layer may replace a matching file with another name.
+	delete(f.matches, hdr.Name)
+	return nil
+}
+
+func (f *fileFinder) result() []FileMatch {
+	result := make([]FileMatch, 0, len(f.matches))
+	for _, m := range f.matches {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Path < result[b].Path })
+	return result
+}
+
+// ListPackages returns the packages installed in the image by dpkg or apk,
+// sorted by name.  RPM databases are not supported.
+func (i *Image) ListPackages(ctx context.Context) ([]Package, error) {
+	collector := &packageCollector{databases: make(map[string]packageDatabase)}
+	if err := i.walkLayers(ctx, collector.visit); err != nil {
+		return nil, err
+	}
+	return collector.result(), nil
+}
+
+// packageDatabase is the content of a package database in a layer.
+type packageDatabase struct {
+	layer string
+	data  []byte
+}
+
+// packageCollector collects the package databases of ListPackages.
+type packageCollector struct {
+	databases map[string]packageDatabase
+}
+
+func (c *packageCollector) visit(layer string, removed func(string) bool, hdr *tar.Header, content io.Reader) error {
+	if hdr == nil {
+		for p, db := range c.databases {
+			if db.layer != layer && removed(p) {
+				delete(c.databases, p)
+			}
+		}
+		return nil
+	}
+	if _, ok := packageDatabases[hdr.Name]; !ok {
+		return nil
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		delete(c.databases, hdr.Name)
+		return nil
+	}
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s of layer %s", hdr.Name, layer)
+	}
+	c.databases[hdr.Name] = packageDatabase{layer: layer, data: data}
+	return nil
+}
+
+func (c *packageCollector) result() []Package {
+	var packages []Package
+	for p, db := range c.databases {
+		packages = append(packages, packageDatabases[p](db.data)...)
+	}
+	sort.Slice(packages, func(a, b int) bool {
+		if packages[a].Name != packages[b].Name {
+			return packages[a].Name < packages[b].Name
+		}
+		return packages[a].Manager < packages[b].Manager
+	})
+	return packages
+}
+
+// layerWalkFunc is called for each entry of a layer with its absolute path in
+// hdr.Name.  For whiteouts, hdr is nil and removed returns true for the paths
+// of lower layers removed by the whiteout.
+type layerWalkFunc func(layer string, removed func(path string) bool, hdr *tar.Header, content io.Reader) error
+
+// walkLayers calls fn for the entries of the layers of the image from the
+// bottom to the top layer.
+func (i *Image) walkLayers(ctx context.Context, fn layerWalkFunc) error {
+	layers, err := i.layers()
+	if err != nil {
+		return err
+	}
+
+	uncompressed := archive.Uncompressed
+	for _, layer := range layers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		diff, err := i.runtime.store.Diff("", layer.ID, &storage.DiffOptions{Compression: &uncompressed})
+		if err != nil {
+			return errors.Wrapf(err, "reading layer %s", layer.ID)
+		}
+		err = walkLayer(layer.ID, &contextReader{ctx: ctx, ReadCloser: diff}, fn)
+		diff.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkLayer(layer string, diff io.Reader, fn layerWalkFunc) error {
+	tr := tar.NewReader(diff)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "reading layer %s", layer)
+		}
+		p := path.Clean("/" + hdr.Name)
+		dir, base := path.Split(p)
+		switch {
+		case base == whiteoutOpaque:
+			// The contents of the directory in lower layers are
+			// removed.
+			dir = path.Clean(dir)
+			prefix := strings.TrimSuffix(dir, "/") + "/"
+			err = fn(layer, func(p string) bool { return strings.HasPrefix(p, prefix) }, nil, nil)
+		case strings.HasPrefix(base, whiteoutPrefix):
+			target := path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+			err = fn(layer, func(p string) bool { return p == target || strings.HasPrefix(p, target+"/") }, nil, nil)
+		default:
+			hdr.Name = p
+			err = fn(layer, func(string) bool { return false }, hdr, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseDpkgStatus parses the installed packages of /var/lib/dpkg/status.
+func parseDpkgStatus(data []byte) []Package {
+	var packages []Package
+	for _, paragraph := range strings.Split(string(data), "\n\n") {
+		pkg := Package{Manager: "dpkg"}
+		installed := true
+		for _, line := range strings.Split(paragraph, "\n") {
+			kv := strings.SplitN(line, ":", 2)
+			if len(kv) != 2 || strings.HasPrefix(line, " ") {
+				continue
+			}
+			value := strings.TrimSpace(kv[1])
+			switch kv[0] {
+			case "Package":
+				pkg.Name = value
+			case "Version":
+				pkg.Version = value
+			case "Architecture":
+				pkg.Arch = value
+			case "Status":
+				installed = strings.HasSuffix(value, " installed")
+			}
+		}
+		if pkg.Name != "" && installed {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
+
+// parseApkInstalled parses the packages of /lib/apk/db/installed.
+func parseApkInstalled(data []byte) []Package {
+	var packages []Package
+	pkg := Package{Manager: "apk"}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if pkg.Name != "" {
+				packages = append(packages, pkg)
+			}
+			pkg = Package{Manager: "apk"}
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		switch line[0] {
+		case 'P':
+			pkg.Name = line[2:]
+		case 'V':
+			pkg.Version = line[2:]
+		case 'A':
+			pkg.Arch = line[2:]
+		}
+	}
+	if pkg.Name != "" {
+		packages = append(packages, pkg)
+	}
+	return packages
+}
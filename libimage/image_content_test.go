@@ -0,0 +1,99 @@
+package libimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name    string
+	content string
+}
+
+func layerTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestFileFinder(t *testing.T) {
+	finder, err := newFileFinder([]string{"libssl.so*", "/etc/*.conf"})
+	require.NoError(t, err)
+
+	layers := map[string]*bytes.Buffer{
+		"base": layerTar(t,
+			tarEntry{"usr/lib64/libssl.so.1.1", "old"},
+			tarEntry{"./usr/lib/libssl.so.3", "3"},
+			tarEntry{"etc/app.conf", ""},
+			tarEntry{"etc/sub/app.conf", ""},
+			tarEntry{"opt/libssl.so.1", ""},
+		),
+		"upper": layerTar(t,
+			tarEntry{"usr/lib64/libssl.so.1.1", "newer"},
+			tarEntry{"usr/lib/.wh.libssl.so.3", ""},
+			tarEntry{"opt/libssl.so.2", ""},
+			tarEntry{"opt/.wh..wh..opq", ""},
+		),
+	}
+	for _, layer := range []string{"base", "upper"} {
+		require.NoError(t, walkLayer(layer, layers[layer], finder.visit))
+	}
+
+	assert.Equal(t, []FileMatch{
+		{Path: "/etc/app.conf", Layer: "base", Mode: 0644},
+		{Path: "/opt/libssl.so.2", Layer: "upper", Mode: 0644},
+		{Path: "/usr/lib64/libssl.so.1.1", Layer: "upper", Size: 5, Mode: 0644},
+	}, finder.result())
+
+	_, err = newFileFinder([]string{"["})
+	assert.Error(t, err)
+}
+
+func TestPackageCollector(t *testing.T) {
+	collector := &packageCollector{databases: make(map[string]packageDatabase)}
+	dpkg := `Package: libssl1.1
+Status: install ok installed
+Architecture: amd64
+Version: 1.1.1n-0+deb11u3
+Description: Secure Sockets Layer toolkit
+ multi-line: description
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+
+Package: bash
+Status: install ok installed
+Architecture: amd64
+Version: 5.1-2
+`
+	apk := "P:musl\nV:1.2.3-r0\nA:x86_64\n\nP:libssl3\nV:3.0.8-r0\nA:x86_64\n"
+	require.NoError(t, walkLayer("base", layerTar(t,
+		tarEntry{"var/lib/dpkg/status", "Package: stale\nStatus: install ok installed\n"},
+		tarEntry{"lib/apk/db/installed", apk},
+	), collector.visit))
+	require.NoError(t, walkLayer("upper", layerTar(t,
+		tarEntry{"var/lib/dpkg/status", dpkg},
+	), collector.visit))
+
+	assert.Equal(t, []Package{
+		{Name: "bash", Version: "5.1-2", Arch: "amd64", Manager: "dpkg"},
+		{Name: "libssl1.1", Version: "1.1.1n-0+deb11u3", Arch: "amd64", Manager: "dpkg"},
+		{Name: "libssl3", Version: "3.0.8-r0", Arch: "x86_64", Manager: "apk"},
+		{Name: "musl", Version: "1.2.3-r0", Arch: "x86_64", Manager: "apk"},
+	}, collector.result())
+
+	require.NoError(t, walkLayer("top", layerTar(t, tarEntry{"lib/apk/db/.wh.installed", ""}), collector.visit))
+	assert.Len(t, collector.result(), 2)
+}
@@ -55,7 +55,7 @@ func (r *Runtime) Import(ctx context.Context, path string, options *ImportOption
 	u, err := url.ParseRequestURI(path)
 	if err == nil && u.Scheme != "" {
 		// If source is a URL, download the file.
-		file, err := r.downloadFromURL(path)
+		file, err := r.downloadFromURL(ctx, path)
 		if err != nil {
 			return "", err
 		}
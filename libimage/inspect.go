@@ -16,8 +16,11 @@ import (
 func (i *Image) Inspect(ctx context.Context, withSize bool) (*libimageTypes.ImageData, error) {
 	logrus.Debugf("Inspecting image %s", i.ID())
 
-	if i.cached.completeInspectData != nil {
-		return i.cached.completeInspectData, nil
+	i.dataLock.RLock()
+	cached := i.cached.completeInspectData
+	i.dataLock.RUnlock()
+	if cached != nil {
+		return cached, nil
 	}
 
 	// First assemble data that does not depend on the format of the image.
@@ -117,15 +120,20 @@ func (i *Image) Inspect(ctx context.Context, withSize bool) (*libimageTypes.Imag
 		data.HealthCheck = dockerManifest.ContainerConfig.Healthcheck
 	}
 
+	i.dataLock.Lock()
 	i.cached.completeInspectData = data
+	i.dataLock.Unlock()
 
 	return data, nil
 }
 
 // inspectInfo returns the image inspect info.
 func (i *Image) inspectInfo(ctx context.Context) (*types.ImageInspectInfo, error) {
-	if i.cached.partialInspectData != nil {
-		return i.cached.partialInspectData, nil
+	i.dataLock.RLock()
+	cached := i.cached.partialInspectData
+	i.dataLock.RUnlock()
+	if cached != nil {
+		return cached, nil
 	}
 
 	ref, err := i.StorageReference()
@@ -145,6 +153,8 @@ func (i *Image) inspectInfo(ctx context.Context) (*types.ImageInspectInfo, error
 		return nil, err
 	}
 
+	i.dataLock.Lock()
 	i.cached.partialInspectData = data
+	i.dataLock.Unlock()
 	return data, nil
 }
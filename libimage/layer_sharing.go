@@ -0,0 +1,148 @@
+package libimage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// LayerSharingReport describes how much space the layers of the images in
+// the local containers storage share.  Sizes are uncompressed sizes of the
+// layers.  Layers of unknown size count as empty.
+type LayerSharingReport struct {
+	// TotalSize is the sum of the sizes of all images, as if they did not
+	// share any layer.
+	TotalSize int64
+	// StoreSize is the size of all layers used by the images.
+	StoreSize int64
+	// SharedSize is the size of the layers used by more than one image.
+	SharedSize int64
+	// Images are the images sorted by their unique size, largest first.
+	Images []ImageSharing
+	// Duplicates are layers with the same content stored more than once
+	// since they are on top of different parent layers.
+	Duplicates []DuplicateLayers
+}
+
+// PotentialSavings returns the size that can be reclaimed by re-basing the
+// images such that duplicate layers share a common parent.
+func (r *LayerSharingReport) PotentialSavings() int64 {
+	var savings int64
+	for _, d := range r.Duplicates {
+		savings += d.Savings()
+	}
+	return savings
+}
+
+// ImageSharing describes the layers of an image shared with other images.
+type ImageSharing struct {
+	Image *Image
+	// Size is the size of all layers of the image.
+	Size int64
+	// SharedSize is the size of the layers also used by other images.
+	SharedSize int64
+	// UniqueSize is the size of the layers only used by the image, which
+	// is reclaimed by removing the image.
+	UniqueSize int64
+}
+
+// DuplicateLayers are layers with the same uncompressed digest.
+type DuplicateLayers struct {
+	Digest digest.Digest
+	// Size is the size of one of the layers.
+	Size int64
+	// Layers are the IDs of the layers.
+	Layers []string
+}
+
+// Savings returns the size of all but one of the layers.
+func (d *DuplicateLayers) Savings() int64 {
+	return d.Size * int64(len(d.Layers)-1)
+}
+
+// LayerSharing computes the LayerSharingReport of the images in the local
+// containers storage.  Only the metadata of the layers is read.
+func (r *Runtime) LayerSharing(ctx context.Context) (*LayerSharingReport, error) {
+	tree, err := r.layerTree()
+	if err != nil {
+		return nil, err
+	}
+	images, err := r.ListImages(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tree.sharingReport(images), nil
+}
+
+// sharingReport computes the LayerSharingReport of the images.
+func (t *layerTree) sharingReport(images []*Image) *LayerSharingReport {
+	// The layers of each image, and the number of images using each layer.
+	imageLayers := make([][]*layerNode, len(images))
+	users := make(map[*layerNode]int)
+	for i, image := range images {
+		for node := t.nodes[image.TopLayer()]; node != nil && node.layer != nil; node = node.parent {
+			imageLayers[i] = append(imageLayers[i], node)
+			users[node]++
+		}
+	}
+
+	report := &LayerSharingReport{Images: make([]ImageSharing, 0, len(images))}
+	for i, image := range images {
+		sharing := ImageSharing{Image: image}
+		for _, node := range imageLayers[i] {
+			size := layerSize(node)
+			sharing.Size += size
+			if users[node] > 1 {
+				sharing.SharedSize += size
+			} else {
+				sharing.UniqueSize += size
+			}
+		}
+		report.TotalSize += sharing.Size
+		report.Images = append(report.Images, sharing)
+	}
+	sort.SliceStable(report.Images, func(a, b int) bool {
+		return report.Images[a].UniqueSize > report.Images[b].UniqueSize
+	})
+
+	duplicates := make(map[digest.Digest]*DuplicateLayers)
+	for node, n := range users {
+		size := layerSize(node)
+		report.StoreSize += size
+		if n > 1 {
+			report.SharedSize += size
+		}
+		d := node.layer.UncompressedDigest
+		if d == "" {
+			continue
+		}
+		if duplicates[d] == nil {
+			duplicates[d] = &DuplicateLayers{Digest: d, Size: size}
+		}
+		duplicates[d].Layers = append(duplicates[d].Layers, node.layer.ID)
+	}
+	for _, d := range duplicates {
+		if len(d.Layers) < 2 {
+			continue
+		}
+		sort.Strings(d.Layers)
+		report.Duplicates = append(report.Duplicates, *d)
+	}
+	sort.Slice(report.Duplicates, func(a, b int) bool {
+		da, db := report.Duplicates[a], report.Duplicates[b]
+		if da.Savings() != db.Savings() {
+			return da.Savings() > db.Savings()
+		}
+		return da.Digest < db.Digest
+	})
+	return report
+}
+
+// layerSize returns the uncompressed size of the layer, or 0 if unknown.
+func layerSize(node *layerNode) int64 {
+	if node.layer.UncompressedSize < 0 {
+		return 0
+	}
+	return node.layer.UncompressedSize
+}
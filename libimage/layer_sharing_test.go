@@ -0,0 +1,53 @@
+package libimage
+
+import (
+	"testing"
+
+	"github.com/containers/storage"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharingReport(t *testing.T) {
+	// base <- app1 <- app1-config
+	//      <- app2
+	// other <- copy (same content as app2)
+	layers := []storage.Layer{
+		{ID: "base", UncompressedSize: 100, UncompressedDigest: digest.FromString("base")},
+		{ID: "app1", Parent: "base", UncompressedSize: 50, UncompressedDigest: digest.FromString("app1")},
+		{ID: "app1-config", Parent: "app1", UncompressedSize: -1},
+		{ID: "app2", Parent: "base", UncompressedSize: 30, UncompressedDigest: digest.FromString("app2")},
+		{ID: "other", UncompressedSize: 200, UncompressedDigest: digest.FromString("other")},
+		{ID: "copy", Parent: "other", UncompressedSize: 30, UncompressedDigest: digest.FromString("app2")},
+		{ID: "unused", UncompressedSize: 1000},
+	}
+	tree := &layerTree{nodes: make(map[string]*layerNode)}
+	for i := range layers {
+		node := tree.node(layers[i].ID)
+		node.layer = &layers[i]
+		if layers[i].Parent != "" {
+			node.parent = tree.node(layers[i].Parent)
+		}
+	}
+	image := func(id, topLayer string) *Image {
+		return &Image{storageImage: &storage.Image{ID: id, TopLayer: topLayer}}
+	}
+	images := []*Image{image("i1", "app1"), image("i1-config", "app1-config"), image("i2", "app2"), image("i3", "copy"), image("scratch", "")}
+
+	report := tree.sharingReport(images)
+	assert.Equal(t, int64(150+150+130+230), report.TotalSize)
+	assert.Equal(t, int64(100+50+30+200+30), report.StoreSize)
+	assert.Equal(t, int64(150), report.SharedSize)
+
+	var order []string
+	for _, i := range report.Images {
+		order = append(order, i.Image.ID())
+	}
+	assert.Equal(t, []string{"i3", "i2", "i1", "i1-config", "scratch"}, order)
+	assert.Equal(t, ImageSharing{Image: images[2], Size: 130, SharedSize: 100, UniqueSize: 30}, report.Images[1])
+
+	require.Len(t, report.Duplicates, 1)
+	assert.Equal(t, []string{"app2", "copy"}, report.Duplicates[0].Layers)
+	assert.Equal(t, int64(30), report.PotentialSavings())
+}
@@ -8,8 +8,11 @@ import (
 
 // toOCI returns the image as OCI v1 image.
 func (i *Image) toOCI(ctx context.Context) (*ociv1.Image, error) {
-	if i.cached.ociv1Image != nil {
-		return i.cached.ociv1Image, nil
+	i.dataLock.RLock()
+	cached := i.cached.ociv1Image
+	i.dataLock.RUnlock()
+	if cached != nil {
+		return cached, nil
 	}
 	ref, err := i.StorageReference()
 	if err != nil {
@@ -13,7 +13,6 @@ import (
 	"github.com/containers/image/v5/docker/reference"
 	ociArchiveTransport "github.com/containers/image/v5/oci/archive"
 	ociTransport "github.com/containers/image/v5/oci/layout"
-	"github.com/containers/image/v5/pkg/shortnames"
 	storageTransport "github.com/containers/image/v5/storage"
 	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
@@ -29,6 +28,10 @@ type PullOptions struct {
 	// If true, all tags of the image will be pulled from the container
 	// registry.  Only supported for the docker transport.
 	AllTags bool
+
+	// ShortNamePrompter, if set, selects the image to pull for ambiguous
+	// short names instead of prompting on the terminal.
+	ShortNamePrompter ShortNamePrompter
 }
 
 // Pull pulls the specified name.  Name may refer to any of the supported
@@ -353,7 +356,7 @@ func (r *Runtime) copySingleImageFromRegistry(ctx context.Context, imageName str
 		}
 		imageName = resolvedImageName
 	}
-	resolved, err := shortnames.Resolve(&r.systemContext, imageName)
+	resolved, record, err := r.resolveShortName(ctx, imageName, options.ShortNamePrompter)
 	if err != nil {
 		return nil, err
 	}
@@ -428,7 +431,7 @@ func (r *Runtime) copySingleImageFromRegistry(ctx context.Context, imageName str
 			pullErrors = append(pullErrors, err)
 			continue
 		}
-		if err := candidate.Record(); err != nil {
+		if err := record(&candidate); err != nil {
 			// Only log the recording errors.  Podman has seen
 			// reports where users set most of the system to
 			// read-only which can cause issues.
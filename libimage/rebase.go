@@ -0,0 +1,265 @@
+package libimage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/storage"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RebaseOptions allow for customizing image rebases.
+type RebaseOptions struct {
+	// OldBase is the base image to replace.  If not set, the closest image
+	// with a name the image is based on is used.
+	OldBase *Image
+}
+
+// Rebase creates an image replacing the layers of the base image of i with
+// the layers of newBase.  The configuration and the history of i above the
+// base are preserved, while the root file system and the history below are
+// taken from newBase.  The names of i are moved to the returned image, such
+// that i can still be referred to by its ID.
+//
+// The layers above the base are copied onto the new base without rebuilding
+// them.  This is only correct if they do not depend on the contents of the
+// base layers, as is usually the case for a security update of the base
+// image.  The new base must have the same OS and architecture as the old one.
+func (i *Image) Rebase(ctx context.Context, newBase *Image, options *RebaseOptions) (*Image, error) {
+	if options == nil {
+		options = &RebaseOptions{}
+	}
+	oldBase := options.OldBase
+	if oldBase == nil {
+		var err error
+		if oldBase, err = i.base(ctx); err != nil {
+			return nil, err
+		}
+		if oldBase == nil {
+			return nil, errors.Errorf("cannot rebase image %s: no base image found", i.ID())
+		}
+	}
+	if oldBase.ID() == i.ID() {
+		return nil, errors.Errorf("cannot rebase image %s onto itself", i.ID())
+	}
+	if newBase.ID() == oldBase.ID() {
+		return nil, errors.Errorf("image %s is already based on image %s", i.ID(), newBase.ID())
+	}
+
+	imageOCI, err := i.toOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oldOCI, err := oldBase.toOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newOCI, err := newBase.toOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if newOCI.OS != oldOCI.OS || newOCI.Architecture != oldOCI.Architecture {
+		return nil, errors.Errorf("cannot rebase image %s: new base is %s/%s but old base is %s/%s", i.ID(), newOCI.OS, newOCI.Architecture, oldOCI.OS, oldOCI.Architecture)
+	}
+
+	layers, err := i.layers()
+	if err != nil {
+		return nil, err
+	}
+	oldLayers, err := oldBase.layers()
+	if err != nil {
+		return nil, err
+	}
+	newLayers, err := newBase.layers()
+	if err != nil {
+		return nil, err
+	}
+	if !isBasedOn(imageOCI, layers, oldOCI, oldLayers) {
+		return nil, errors.Errorf("image %s is not based on image %s", i.ID(), oldBase.ID())
+	}
+	upperLayers := layers[len(oldLayers):]
+
+	config := *imageOCI
+	config.RootFS.DiffIDs = append(append([]digest.Digest{}, newOCI.RootFS.DiffIDs...), imageOCI.RootFS.DiffIDs[len(oldOCI.RootFS.DiffIDs):]...)
+	config.History = append(append([]ociv1.History{}, newOCI.History...), imageOCI.History[len(oldOCI.History):]...)
+	configBlob, err := json.Marshal(&config)
+	if err != nil {
+		return nil, err
+	}
+	configDigest := digest.FromBytes(configBlob)
+
+	// Use the config digest as the ID like containers/image, such that
+	// rebasing an image again reuses the rebased image.
+	store := i.runtime.store
+	rebased, err := store.Image(configDigest.Encoded())
+	if err != nil {
+		if errors.Cause(err) != storage.ErrImageUnknown {
+			return nil, err
+		}
+		if rebased, err = i.createRebased(ctx, config, configBlob, newLayers, upperLayers); err != nil {
+			return nil, err
+		}
+	}
+
+	logrus.Debugf("Rebased image %s onto %s as %s", i.ID(), newBase.ID(), rebased.ID)
+	if names := i.Names(); len(names) > 0 {
+		i.writeLock.Lock()
+		defer i.writeLock.Unlock()
+		if err := store.SetNames(rebased.ID, append(rebased.Names, names...)); err != nil {
+			return nil, err
+		}
+		if rebased, err = store.Image(rebased.ID); err != nil {
+			return nil, err
+		}
+		if err := i.reload(); err != nil {
+			return nil, err
+		}
+	}
+	return i.runtime.storageToImage(rebased, nil), nil
+}
+
+// createRebased copies the upper layers onto the top layer of the new base
+// and creates the image with the config.
+func (i *Image) createRebased(ctx context.Context, config ociv1.Image, configBlob []byte, newLayers, upperLayers []*storage.Layer) (_ *storage.Image, retErr error) {
+	store := i.runtime.store
+	var created []string
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for n := len(created) - 1; n >= 0; n-- {
+			if err := store.DeleteLayer(created[n]); err != nil {
+				logrus.Errorf("Removing layer %s of failed rebase: %v", created[n], err)
+			}
+		}
+	}()
+
+	var descriptors []ociv1.Descriptor
+	for _, layer := range newLayers {
+		descriptors = append(descriptors, ociv1.Descriptor{MediaType: ociv1.MediaTypeImageLayer, Digest: layer.UncompressedDigest, Size: layer.UncompressedSize})
+	}
+	parent := ""
+	if len(newLayers) > 0 {
+		parent = newLayers[len(newLayers)-1].ID
+	}
+	uncompressed := archive.Uncompressed
+	for _, layer := range upperLayers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		diff, err := store.Diff("", layer.ID, &storage.DiffOptions{Compression: &uncompressed})
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading layer %s", layer.ID)
+		}
+		newLayer, _, err := store.PutLayer("", parent, nil, "", false, nil, &contextReader{ctx: ctx, ReadCloser: diff})
+		diff.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "copying layer %s", layer.ID)
+		}
+		created = append(created, newLayer.ID)
+		if newLayer.UncompressedDigest != layer.UncompressedDigest {
+			return nil, errors.Errorf("copy of layer %s has digest %s, expected %s", layer.ID, newLayer.UncompressedDigest, layer.UncompressedDigest)
+		}
+		descriptors = append(descriptors, ociv1.Descriptor{MediaType: ociv1.MediaTypeImageLayer, Digest: newLayer.UncompressedDigest, Size: newLayer.UncompressedSize})
+		parent = newLayer.ID
+	}
+
+	configDigest := digest.FromBytes(configBlob)
+	manifestBlob, err := manifest.OCI1FromComponents(ociv1.Descriptor{
+		MediaType: ociv1.MediaTypeImageConfig,
+		Digest:    configDigest,
+		Size:      int64(len(configBlob)),
+	}, descriptors).Serialize()
+	if err != nil {
+		return nil, err
+	}
+	manifestDigest, err := manifest.Digest(manifestBlob)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &storage.ImageOptions{Digest: manifestDigest}
+	if config.Created != nil {
+		options.CreationDate = *config.Created
+	}
+	img, err := store.CreateImage(configDigest.Encoded(), nil, parent, "", options)
+	if err != nil {
+		return nil, err
+	}
+	bigData := []struct {
+		key  string
+		data []byte
+	}{
+		{configDigest.String(), configBlob},
+		{storage.ImageDigestManifestBigDataNamePrefix + "-" + manifestDigest.String(), manifestBlob},
+		{storage.ImageDigestBigDataKey, manifestBlob},
+	}
+	for _, b := range bigData {
+		if err := store.SetImageBigData(img.ID, b.key, b.data, manifest.Digest); err != nil {
+			if _, deleteErr := store.DeleteImage(img.ID, false); deleteErr != nil {
+				logrus.Errorf("Removing image %s of failed rebase: %v", img.ID, deleteErr)
+			}
+			return nil, err
+		}
+	}
+	return store.Image(img.ID)
+}
+
+// base returns the closest image with a name the image is based on, or nil if
+// there is none.
+func (i *Image) base(ctx context.Context) (*Image, error) {
+	tree, err := i.runtime.layerTree()
+	if err != nil {
+		return nil, err
+	}
+	imageOCI, err := tree.toOCI(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := i.layers()
+	if err != nil {
+		return nil, err
+	}
+	for n := len(layers) - 1; n >= 0; n-- {
+		for _, candidate := range tree.node(layers[n].ID).images {
+			if candidate.ID() == i.ID() || candidate.IsDangling() {
+				continue
+			}
+			candidateOCI, err := tree.toOCI(ctx, candidate)
+			if err != nil {
+				return nil, err
+			}
+			if isBasedOn(imageOCI, layers, candidateOCI, layers[:n+1]) {
+				return candidate, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// isBasedOn returns true if the layers, root file system and history of the
+// base are a proper prefix of the ones of the image.
+func isBasedOn(image *ociv1.Image, layers []*storage.Layer, base *ociv1.Image, baseLayers []*storage.Layer) bool {
+	if len(baseLayers) > len(layers) {
+		return false
+	}
+	for n := range baseLayers {
+		if baseLayers[n].ID != layers[n].ID {
+			return false
+		}
+	}
+	if len(base.RootFS.DiffIDs) > len(image.RootFS.DiffIDs) || len(base.RootFS.DiffIDs) != len(baseLayers) {
+		return false
+	}
+	for n := range base.RootFS.DiffIDs {
+		if base.RootFS.DiffIDs[n] != image.RootFS.DiffIDs[n] {
+			return false
+		}
+	}
+	return len(base.History) < len(image.History) && historiesMatch(base.History, image.History) == len(base.History)
+}
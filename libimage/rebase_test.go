@@ -0,0 +1,107 @@
+package libimage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/storage"
+	"github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCreateImage creates an image adding a layer with the files onto base.
+func testCreateImage(t *testing.T, runtime *Runtime, base *Image, name, createdBy, arch string, files ...tarEntry) *Image {
+	store := runtime.store
+	config := ociv1.Image{Architecture: arch, OS: "linux", RootFS: ociv1.RootFS{Type: "layers"}}
+	var descriptors []ociv1.Descriptor
+	parent := ""
+	if base != nil {
+		baseOCI, err := base.toOCI(context.Background())
+		require.NoError(t, err)
+		config.RootFS.DiffIDs = baseOCI.RootFS.DiffIDs
+		config.History = baseOCI.History
+		layers, err := base.layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			descriptors = append(descriptors, ociv1.Descriptor{MediaType: ociv1.MediaTypeImageLayer, Digest: layer.UncompressedDigest, Size: layer.UncompressedSize})
+		}
+		parent = base.TopLayer()
+	}
+
+	layer, _, err := store.PutLayer("", parent, nil, "", false, nil, layerTar(t, files...))
+	require.NoError(t, err)
+	descriptors = append(descriptors, ociv1.Descriptor{MediaType: ociv1.MediaTypeImageLayer, Digest: layer.UncompressedDigest, Size: layer.UncompressedSize})
+	created := time.Now().UTC()
+	config.Created = &created
+	config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, layer.UncompressedDigest)
+	config.History = append(config.History, ociv1.History{Created: &created, CreatedBy: createdBy})
+
+	configBlob, err := json.Marshal(&config)
+	require.NoError(t, err)
+	configDigest := digest.FromBytes(configBlob)
+	manifestBlob, err := manifest.OCI1FromComponents(ociv1.Descriptor{MediaType: ociv1.MediaTypeImageConfig, Digest: configDigest, Size: int64(len(configBlob))}, descriptors).Serialize()
+	require.NoError(t, err)
+	img, err := store.CreateImage(configDigest.Encoded(), []string{name}, layer.ID, "", &storage.ImageOptions{})
+	require.NoError(t, err)
+	require.NoError(t, store.SetImageBigData(img.ID, configDigest.String(), configBlob, manifest.Digest))
+	require.NoError(t, store.SetImageBigData(img.ID, storage.ImageDigestBigDataKey, manifestBlob, manifest.Digest))
+
+	image, _, err := runtime.LookupImage(img.ID, &LookupImageOptions{IgnorePlatform: true})
+	require.NoError(t, err)
+	require.NotNil(t, image)
+	return image
+}
+
+func TestRebase(t *testing.T) {
+	runtime, cleanup := testNewRuntime(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	base1 := testCreateImage(t, runtime, nil, "localhost/base:1", "base 1", "amd64", tarEntry{"etc/os-release", "1"})
+	app := testCreateImage(t, runtime, base1, "localhost/app:latest", "add app", "amd64", tarEntry{"app", "app"})
+	base2 := testCreateImage(t, runtime, nil, "localhost/base:2", "base 2", "amd64", tarEntry{"etc/os-release", "two"})
+	other := testCreateImage(t, runtime, nil, "localhost/other:latest", "other", "arm64", tarEntry{"etc/os-release", "arm"})
+
+	base, err := app.base(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, base)
+	assert.Equal(t, base1.ID(), base.ID())
+
+	_, err = app.Rebase(ctx, other, nil)
+	assert.Error(t, err, "architectures differ")
+	_, err = app.Rebase(ctx, base1, nil)
+	assert.Error(t, err, "already based on base1")
+	_, err = base2.Rebase(ctx, base1, &RebaseOptions{OldBase: app})
+	assert.Error(t, err, "not based on app")
+
+	rebased, err := app.Rebase(ctx, base2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost/app:latest"}, rebased.Names())
+	assert.Empty(t, app.Names())
+
+	rebasedOCI, err := rebased.toOCI(ctx)
+	require.NoError(t, err)
+	base2OCI, err := base2.toOCI(ctx)
+	require.NoError(t, err)
+	appOCI, err := app.toOCI(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []digest.Digest{base2OCI.RootFS.DiffIDs[0], appOCI.RootFS.DiffIDs[1]}, rebasedOCI.RootFS.DiffIDs)
+	require.Len(t, rebasedOCI.History, 2)
+	assert.Equal(t, "base 2", rebasedOCI.History[0].CreatedBy)
+	assert.Equal(t, "add app", rebasedOCI.History[1].CreatedBy)
+
+	files, err := rebased.FindFiles(ctx, []string{"/app", "/etc/os-release"})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, int64(3), files[1].Size)
+
+	// Rebasing again yields the same image.
+	again, err := app.Rebase(ctx, base2, &RebaseOptions{OldBase: base1})
+	require.NoError(t, err)
+	assert.Equal(t, rebased.ID(), again.ID())
+}
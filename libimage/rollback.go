@@ -0,0 +1,118 @@
+package libimage
+
+import (
+	"context"
+
+	libimageTypes "github.com/containers/common/libimage/types"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/storage"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RollbackTagSuffix is appended to the tag of a name to tag the image
+// replaced by Replace.
+const RollbackTagSuffix = "-rollback"
+
+// RollbackName returns the name Replace tags the previous image of the
+// specified name with, e.g. "quay.io/foo/bar:1.0-rollback".
+func RollbackName(name string) (string, error) {
+	named, err := NormalizeName(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "error normalizing name %q", name)
+	}
+	tagged, isTagged := named.(reference.NamedTagged)
+	if !isTagged {
+		return "", errors.Errorf("cannot roll back %q: only tagged names can be replaced", name)
+	}
+	rollback, err := reference.WithTag(reference.TrimNamed(named), tagged.Tag()+RollbackTagSuffix)
+	if err != nil {
+		return "", errors.Wrapf(err, "creating rollback name of %q", name)
+	}
+	return rollback.String(), nil
+}
+
+// ReplaceReport describes the result of Replace.
+type ReplaceReport struct {
+	// Name is the normalized name of the replaced image.
+	Name string
+	// Previous is the local image of Name before the pull, if any.
+	Previous *Image
+	// Current is the pulled image.
+	Current *Image
+	// RollbackName is the name Previous is tagged with if it has been
+	// replaced.
+	RollbackName string
+}
+
+// Replaced returns true if a different image than the previous one was
+// pulled.
+func (r *ReplaceReport) Replaced() bool {
+	return r.Previous != nil && r.Previous.ID() != r.Current.ID()
+}
+
+// Replace pulls a new version of the fully-qualified name.  If a different
+// image than the local one is pulled, the previous image is tagged with the
+// RollbackName of name, replacing an older rollback image, such that
+// Rollback can restore it.
+func (r *Runtime) Replace(ctx context.Context, name string, options *PullOptions) (*ReplaceReport, error) {
+	rollbackName, err := RollbackName(name)
+	if err != nil {
+		return nil, err
+	}
+	named, err := NormalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	report := &ReplaceReport{Name: named.String()}
+
+	previous, _, err := r.LookupImage(report.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	report.Previous = previous
+
+	pulled, err := r.Pull(ctx, report.Name, libimageTypes.PullPolicyAlways, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(pulled) == 0 {
+		return nil, errors.Errorf("internal error: no image pulled for %s", report.Name)
+	}
+	report.Current = pulled[0]
+
+	if report.Replaced() {
+		logrus.Debugf("Tagging previous image %s of %s with %s", previous.ID(), report.Name, rollbackName)
+		if err := previous.Tag(rollbackName); err != nil {
+			return nil, errors.Wrapf(err, "tagging previous image %s for rollback", previous.ID())
+		}
+		report.RollbackName = rollbackName
+	}
+	return report, nil
+}
+
+// Rollback restores the image replaced by the last Replace of name.  The
+// name is moved from the current to the restored image in a single storage
+// operation, such that name always refers to one of them.  The rollback tag
+// is removed from the restored image.
+func (r *Runtime) Rollback(ctx context.Context, name string) (*Image, error) {
+	rollbackName, err := RollbackName(name)
+	if err != nil {
+		return nil, err
+	}
+	image, _, err := r.LookupImage(rollbackName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if image == nil {
+		return nil, errors.Wrapf(storage.ErrImageUnknown, "no rollback image %s", rollbackName)
+	}
+
+	if err := image.Tag(name); err != nil {
+		return nil, errors.Wrapf(err, "restoring image %s as %s", image.ID(), name)
+	}
+	if err := image.Untag(rollbackName); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
@@ -0,0 +1,30 @@
+package libimage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"quay.io/foo/bar:1.0":  "quay.io/foo/bar:1.0-rollback",
+		"quay.io/foo/bar":      "quay.io/foo/bar:latest-rollback",
+		"docker.io/alpine:3.1": "docker.io/library/alpine:3.1-rollback",
+	} {
+		rollback, err := RollbackName(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, rollback, name)
+	}
+
+	for _, name := range []string{
+		"quay.io/foo/bar@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"quay.io/foo/bar:" + strings.Repeat("a", 128),
+		"#",
+	} {
+		_, err := RollbackName(name)
+		assert.Error(t, err, name)
+	}
+}
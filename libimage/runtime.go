@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/containers/image/v5/docker/reference"
 	"github.com/containers/image/v5/pkg/shortnames"
@@ -40,12 +41,20 @@ func setRegistriesConfPath(systemContext *types.SystemContext) {
 
 // Runtime is responsible for image management and storing them in a containers
 // storage.
+//
+// A Runtime is safe for concurrent use by multiple goroutines.  Each image in
+// the local containers storage is represented by a single Image, such that
+// concurrent operations on an image are synchronized by its locks: reading
+// the image is shared while modifying it is exclusive.  Other processes are
+// synchronized by the locks of the containers storage.
 type Runtime struct {
 	// Underlying storage store.
 	store storage.Store
 	// Global system context.  No pointer to simplify copying and modifying
 	// it.
 	systemContext types.SystemContext
+	// imageIDmapLock protects imageIDmap.
+	imageIDmapLock sync.Mutex
 	// maps an image ID to an Image pointer.  Allows for aggressive
 	// caching.
 	imageIDmap map[string]*Image
@@ -101,6 +110,8 @@ func (r *Runtime) Shutdown(force bool) error {
 
 // storageToImage transforms a storage.Image to an Image.
 func (r *Runtime) storageToImage(storageImage *storage.Image, ref types.ImageReference) *Image {
+	r.imageIDmapLock.Lock()
+	defer r.imageIDmapLock.Unlock()
 	image, exists := r.imageIDmap[storageImage.ID]
 	if exists {
 		return image
@@ -114,6 +125,14 @@ func (r *Runtime) storageToImage(storageImage *storage.Image, ref types.ImageRef
 	return image
 }
 
+// forgetImage removes the image from the cache after it has been removed from
+// the local containers storage.
+func (r *Runtime) forgetImage(id string) {
+	r.imageIDmapLock.Lock()
+	defer r.imageIDmapLock.Unlock()
+	delete(r.imageIDmap, id)
+}
+
 // Exists returns true if the specicifed image exists in the local containers
 // storage.
 func (r *Runtime) Exists(name string) (bool, error) {
@@ -0,0 +1,98 @@
+package libimage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/containers/storage"
+	"github.com/containers/storage/pkg/unshare"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNewRuntime(t *testing.T) (*Runtime, func()) {
+	if unshare.IsRootless() {
+		t.Skip("Test can only run as root")
+	}
+	dir, err := ioutil.TempDir("", "libimage")
+	require.NoError(t, err)
+
+	store, err := storage.GetStore(storage.StoreOptions{
+		GraphRoot:       filepath.Join(dir, "root"),
+		RunRoot:         filepath.Join(dir, "runroot"),
+		GraphDriverName: "vfs",
+	})
+	require.NoError(t, err)
+	runtime, err := RuntimeFromStore(store, nil)
+	require.NoError(t, err)
+
+	return runtime, func() {
+		_, _ = store.Shutdown(true)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRuntimeConcurrentTag(t *testing.T) {
+	runtime, cleanup := testNewRuntime(t)
+	defer cleanup()
+
+	_, err := runtime.store.CreateImage("", []string{"localhost/image:latest"}, "", "", &storage.ImageOptions{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			image, _, err := runtime.LookupImage("localhost/image:latest", &LookupImageOptions{IgnorePlatform: true})
+			if assert.NoError(t, err) && assert.NotNil(t, image) {
+				assert.NoError(t, image.Tag(fmt.Sprintf("localhost/image:%d", n)))
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	image, _, err := runtime.LookupImage("localhost/image:latest", &LookupImageOptions{IgnorePlatform: true})
+	require.NoError(t, err)
+	require.NotNil(t, image)
+	// No tag may be lost by concurrent read-modify-write of the names.
+	assert.Len(t, image.Names(), 21)
+}
+
+func TestRuntimeConcurrentLookupAndRemove(t *testing.T) {
+	runtime, cleanup := testNewRuntime(t)
+	defer cleanup()
+
+	for n := 0; n < 10; n++ {
+		_, err := runtime.store.CreateImage("", []string{fmt.Sprintf("localhost/image:%d", n)}, "", "", &storage.ImageOptions{})
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			_, _, err := runtime.RemoveImages(context.Background(), []string{fmt.Sprintf("localhost/image:%d", n)}, nil)
+			assert.NoError(t, err)
+		}(n)
+		go func() {
+			defer wg.Done()
+			_, err := runtime.ListImages(context.Background(), nil, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	images, err := runtime.ListImages(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, images)
+	runtime.imageIDmapLock.Lock()
+	assert.Empty(t, runtime.imageIDmap)
+	runtime.imageIDmapLock.Unlock()
+}
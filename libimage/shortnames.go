@@ -0,0 +1,106 @@
+package libimage
+
+import (
+	"context"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/pkg/shortnames"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/types"
+	"github.com/pkg/errors"
+)
+
+// ErrShortNamePromptUnavailable is returned by a ShortNamePrompter if it
+// cannot ask the user.  The short-name mode then decides whether all
+// candidates are pulled ("permissive") or the pull fails ("enforcing").
+var ErrShortNamePromptUnavailable = errors.New("cannot prompt for a short-name selection")
+
+// ShortNamePrompter lets the user select the image to pull among the
+// candidates of an ambiguous short name.  It replaces the terminal prompt of
+// the short-name resolution, such that, for instance, API servers and GUIs can
+// present the candidates in their own UI.
+type ShortNamePrompter interface {
+	// SelectShortName returns the selected candidate, which must be one of
+	// candidates.  The candidates are fully-qualified references in the
+	// order of the unqualified-search registries.
+	SelectShortName(ctx context.Context, shortName string, candidates []string) (string, error)
+}
+
+// ShortNamePrompterFunc is a function implementing ShortNamePrompter.
+type ShortNamePrompterFunc func(ctx context.Context, shortName string, candidates []string) (string, error)
+
+// SelectShortName calls f.
+func (f ShortNamePrompterFunc) SelectShortName(ctx context.Context, shortName string, candidates []string) (string, error) {
+	return f(ctx, shortName, candidates)
+}
+
+// recordFunc records a pulled candidate as a short-name alias.
+type recordFunc func(*shortnames.PullCandidate) error
+
+// resolveShortName resolves name to its pull candidates.  If prompter is nil,
+// the resolution may prompt on the terminal.  Otherwise, prompter selects
+// among the candidates of an ambiguous short name.  The returned function
+// must be called after a candidate has been pulled successfully.
+func (r *Runtime) resolveShortName(ctx context.Context, name string, prompter ShortNamePrompter) (*shortnames.Resolved, recordFunc, error) {
+	if prompter == nil {
+		resolved, err := shortnames.Resolve(&r.systemContext, name)
+		return resolved, (*shortnames.PullCandidate).Record, err
+	}
+
+	mode, err := sysregistriesv2.GetShortNameMode(&r.systemContext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Resolve without prompting in the disabled mode, which returns all
+	// candidates.  Note that aliases are still used.
+	sys := r.systemContext
+	disabled := types.ShortNameModeDisabled
+	sys.ShortNameMode = &disabled
+	resolved, err := shortnames.Resolve(&sys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	noRecord := func(*shortnames.PullCandidate) error { return nil }
+	if mode == types.ShortNameModeDisabled || len(resolved.PullCandidates) < 2 {
+		return resolved, noRecord, nil
+	}
+
+	candidates := make([]string, 0, len(resolved.PullCandidates))
+	for _, candidate := range resolved.PullCandidates {
+		candidates = append(candidates, candidate.Value.String())
+	}
+	selection, err := prompter.SelectShortName(ctx, name, candidates)
+	if err != nil {
+		if errors.Cause(err) == ErrShortNamePromptUnavailable && mode == types.ShortNameModePermissive {
+			return resolved, noRecord, nil
+		}
+		return nil, nil, errors.Wrapf(err, "selecting image for short name %q", name)
+	}
+
+	var selected *shortnames.PullCandidate
+	for i := range resolved.PullCandidates {
+		if candidates[i] == selection {
+			selected = &resolved.PullCandidates[i]
+			break
+		}
+	}
+	if selected == nil {
+		return nil, nil, errors.Errorf("selection %q is not a candidate for short name %q", selection, name)
+	}
+	resolved.PullCandidates = []shortnames.PullCandidate{*selected}
+
+	// Record the selection as an alias, as done for terminal prompts.
+	record := func(candidate *shortnames.PullCandidate) error {
+		ref, err := reference.Parse(name)
+		if err != nil {
+			return err
+		}
+		named, ok := ref.(reference.Named)
+		if !ok {
+			return errors.Errorf("%q is not a named reference", name)
+		}
+		return shortnames.Add(&r.systemContext, reference.TrimNamed(named).String(), reference.TrimNamed(candidate.Value))
+	}
+	return resolved, record, nil
+}
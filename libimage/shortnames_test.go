@@ -0,0 +1,80 @@
+package libimage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testShortNameRuntime(t *testing.T, mode string) (*Runtime, func()) {
+	dir, err := ioutil.TempDir("", "shortnames")
+	require.NoError(t, err)
+	conf := filepath.Join(dir, "registries.conf")
+	content := "unqualified-search-registries = [\"quay.io\", \"docker.io\"]\nshort-name-mode = \"" + mode + "\"\n"
+	require.NoError(t, ioutil.WriteFile(conf, []byte(content), 0600))
+	return &Runtime{systemContext: types.SystemContext{
+		SystemRegistriesConfPath:    conf,
+		SystemRegistriesConfDirPath: filepath.Join(dir, "registries.conf.d"),
+		UserShortNameAliasConfPath:  filepath.Join(dir, "shortnames.conf"),
+	}}, func() { os.RemoveAll(dir) }
+}
+
+func TestResolveShortNameWithPrompter(t *testing.T) {
+	runtime, cleanup := testShortNameRuntime(t, "enforcing")
+	defer cleanup()
+
+	var prompted []string
+	prompter := ShortNamePrompterFunc(func(_ context.Context, shortName string, candidates []string) (string, error) {
+		assert.Equal(t, "busybox:1.33", shortName)
+		prompted = candidates
+		return candidates[1], nil
+	})
+	resolved, record, err := runtime.resolveShortName(context.Background(), "busybox:1.33", prompter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"quay.io/busybox:1.33", "docker.io/library/busybox:1.33"}, prompted)
+	require.Len(t, resolved.PullCandidates, 1)
+	assert.Equal(t, "docker.io/library/busybox:1.33", resolved.PullCandidates[0].Value.String())
+
+	// The recorded selection is used as an alias without prompting.
+	require.NoError(t, record(&resolved.PullCandidates[0]))
+	prompted = nil
+	resolved, _, err = runtime.resolveShortName(context.Background(), "busybox", prompter)
+	require.NoError(t, err)
+	assert.Nil(t, prompted)
+	require.Len(t, resolved.PullCandidates, 1)
+	assert.Equal(t, "docker.io/library/busybox:latest", resolved.PullCandidates[0].Value.String())
+
+	// Fully-qualified names are not ambiguous.
+	resolved, _, err = runtime.resolveShortName(context.Background(), "quay.io/alpine", prompter)
+	require.NoError(t, err)
+	assert.Nil(t, prompted)
+	assert.Len(t, resolved.PullCandidates, 1)
+
+	_, _, err = runtime.resolveShortName(context.Background(), "alpine", ShortNamePrompterFunc(func(context.Context, string, []string) (string, error) {
+		return "example.com/alpine:latest", nil
+	}))
+	assert.Error(t, err)
+}
+
+func TestResolveShortNamePromptUnavailable(t *testing.T) {
+	unavailable := ShortNamePrompterFunc(func(context.Context, string, []string) (string, error) {
+		return "", ErrShortNamePromptUnavailable
+	})
+
+	runtime, cleanup := testShortNameRuntime(t, "enforcing")
+	defer cleanup()
+	_, _, err := runtime.resolveShortName(context.Background(), "alpine", unavailable)
+	assert.Error(t, err)
+
+	runtime, cleanup = testShortNameRuntime(t, "permissive")
+	defer cleanup()
+	resolved, _, err := runtime.resolveShortName(context.Background(), "alpine", unavailable)
+	require.NoError(t, err)
+	assert.Len(t, resolved.PullCandidates, 2)
+}
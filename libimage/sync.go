@@ -0,0 +1,434 @@
+package libimage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/manifest"
+	ociTransport "github.com/containers/image/v5/oci/layout"
+	storageTransport "github.com/containers/image/v5/storage"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultSyncConcurrency = 4
+
+// SyncOptions allow for customizing mirroring images with Sync.
+type SyncOptions struct {
+	CopyOptions
+
+	// Tags of each repository to mirror.  All tags are mirrored if no
+	// tags are specified and neither TagRegex nor TagSemver is set.
+	Tags []string
+	// TagRegex selects the tags matching the regular expression.
+	TagRegex string
+	// TagSemver selects the tags which are semantic versions satisfying
+	// all space-separated constraints, e.g. ">=1.2 <2".  Supported
+	// operators are =, <, <=, > and >=.  A leading "v" of tags is ignored.
+	TagSemver string
+	// AllPlatforms mirrors all images of manifest lists instead of the
+	// image matching the platform.
+	AllPlatforms bool
+	// Concurrency is the number of images copied in parallel.  Default 4.
+	Concurrency int
+	// DryRun only reports which images would be copied.
+	DryRun bool
+}
+
+// SyncStatus is the result of mirroring an image.
+type SyncStatus string
+
+const (
+	// SyncStatusCopied is set if the image has been copied.
+	SyncStatusCopied SyncStatus = "copied"
+	// SyncStatusUpToDate is set if the destination already has the image.
+	SyncStatusUpToDate SyncStatus = "up-to-date"
+	// SyncStatusOutdated is set if the image would be copied in a dry run.
+	SyncStatusOutdated SyncStatus = "outdated"
+	// SyncStatusFailed is set if the image could not be mirrored.
+	SyncStatusFailed SyncStatus = "failed"
+)
+
+// SyncItem reports the result of mirroring one image.
+type SyncItem struct {
+	// Source is the source image, e.g. "quay.io/foo/bar:1.0".
+	Source string `json:"source"`
+	// Destination is the destination image with its transport.
+	Destination string `json:"destination"`
+	// Digest of the source image.
+	Digest digest.Digest `json:"digest,omitempty"`
+	Status SyncStatus    `json:"status"`
+	// Error is set if Status is SyncStatusFailed.
+	Error string `json:"error,omitempty"`
+}
+
+// SyncReport reports the results of Sync, ordered by source.
+type SyncReport struct {
+	Items []SyncItem `json:"items"`
+}
+
+// Failed returns the items which could not be mirrored.
+func (r *SyncReport) Failed() []SyncItem {
+	var failed []SyncItem
+	for _, item := range r.Items {
+		if item.Status == SyncStatusFailed {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// syncJob is an image to mirror.
+type syncJob struct {
+	source      reference.NamedTagged
+	destination types.ImageReference
+}
+
+// Sync mirrors the tags of the source repositories, e.g. "quay.io/foo/bar",
+// to the destination.  Sources with a tag only mirror that tag.  The
+// destination is either a registry with an optional repository prefix
+// (e.g., "docker://mirror.local/quay"), an OCI layout directory with one
+// layout per repository (e.g., "oci:/srv/mirror") or the local containers
+// storage ("containers-storage").  Images whose digest is already at the
+// destination are not copied.
+//
+// An error is only returned if the sources or tags cannot be listed; the
+// results of the individual images are in the report.
+func (r *Runtime) Sync(ctx context.Context, sources []string, destination string, options *SyncOptions) (*SyncReport, error) {
+	if options == nil {
+		options = &SyncOptions{}
+	}
+	selectTag, err := options.tagSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []syncJob
+	for _, source := range sources {
+		tagged, err := r.syncSources(ctx, source, selectTag)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range tagged {
+			destRef, err := r.syncDestination(src, destination)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, syncJob{source: src, destination: destRef})
+		}
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSyncConcurrency
+	}
+	report := &SyncReport{Items: make([]SyncItem, len(jobs))}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each worker has its own system context and policy
+			// context as they are modified while copying.
+			sys := r.systemContext
+			for i := range indices {
+				report.Items[i] = r.syncImage(ctx, &sys, jobs[i], options)
+			}
+		}()
+	}
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return report, nil
+}
+
+// syncImage mirrors the image of the job unless the destination has it.
+func (r *Runtime) syncImage(ctx context.Context, sys *types.SystemContext, job syncJob, options *SyncOptions) SyncItem {
+	item := SyncItem{
+		Source:      job.source.String(),
+		Destination: transportName(job.destination),
+	}
+	fail := func(err error) SyncItem {
+		logrus.Debugf("Error mirroring %s to %s: %v", item.Source, item.Destination, err)
+		item.Status = SyncStatusFailed
+		item.Error = err.Error()
+		return item
+	}
+
+	srcRef, err := docker.NewReference(job.source)
+	if err != nil {
+		return fail(err)
+	}
+	digests, err := syncSourceDigests(ctx, sys, srcRef, options.AllPlatforms)
+	if err != nil {
+		return fail(err)
+	}
+	item.Digest = digests[0]
+
+	if destDigest, err := referenceDigest(ctx, sys, job.destination); err == nil {
+		for _, d := range digests {
+			if d == destDigest {
+				item.Status = SyncStatusUpToDate
+				return item
+			}
+		}
+	}
+	if options.DryRun {
+		item.Status = SyncStatusOutdated
+		return item
+	}
+
+	c, err := newCopier(sys, &options.CopyOptions)
+	if err != nil {
+		return fail(err)
+	}
+	defer c.close()
+	if options.AllPlatforms {
+		c.imageCopyOptions.ImageListSelection = copy.CopyAllImages
+	}
+	if _, err := c.copy(ctx, srcRef, job.destination); err != nil {
+		return fail(err)
+	}
+	item.Status = SyncStatusCopied
+	return item
+}
+
+// syncSources returns the selected tags of the source repository.
+func (r *Runtime) syncSources(ctx context.Context, source string, selectTag func(string) bool) ([]reference.NamedTagged, error) {
+	named, err := reference.ParseNormalizedNamed(strings.TrimPrefix(source, "docker://"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid sync source %q", source)
+	}
+	if _, isDigested := named.(reference.Digested); isDigested {
+		return nil, errors.Errorf("invalid sync source %q: digests are not supported", source)
+	}
+	if tagged, isTagged := named.(reference.NamedTagged); isTagged {
+		return []reference.NamedTagged{tagged}, nil
+	}
+
+	ref, err := docker.NewReference(reference.TagNameOnly(named))
+	if err != nil {
+		return nil, err
+	}
+	tags, err := docker.GetRepositoryTags(ctx, &r.systemContext, ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing tags of %s", named.Name())
+	}
+	sort.Strings(tags)
+
+	var selected []reference.NamedTagged
+	for _, tag := range tags {
+		if !selectTag(tag) {
+			continue
+		}
+		tagged, err := reference.WithTag(named, tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error creating tagged reference (name %s, tag %s)", named.String(), tag)
+		}
+		selected = append(selected, tagged)
+	}
+	return selected, nil
+}
+
+// syncDestination returns the reference the source is mirrored to.
+func (r *Runtime) syncDestination(source reference.NamedTagged, destination string) (types.ImageReference, error) {
+	path := reference.Path(source)
+	switch {
+	case destination == storageTransport.Transport.Name() || destination == storageTransport.Transport.Name()+":":
+		return storageTransport.Transport.ParseStoreReference(r.store, source.String())
+	case strings.HasPrefix(destination, ociTransport.Transport.Name()+":"):
+		layout := filepath.Join(strings.TrimPrefix(destination, ociTransport.Transport.Name()+":"), path)
+		// The parent directories must exist to resolve the layout.
+		if err := os.MkdirAll(filepath.Dir(layout), 0755); err != nil {
+			return nil, err
+		}
+		return ociTransport.NewReference(layout, source.Tag())
+	}
+
+	if i := strings.Index(destination, ":"); i > 0 && !strings.HasPrefix(destination, "docker://") && transports.Get(destination[:i]) != nil {
+		return nil, errors.Errorf("invalid sync destination %q: only registries, OCI layouts and the containers storage are supported", destination)
+	}
+	prefix := strings.Trim(strings.TrimPrefix(destination, "docker://"), "/")
+	return alltransports.ParseImageName("docker://" + prefix + "/" + path + ":" + source.Tag())
+}
+
+// tagSelector returns a function selecting the tags to mirror.
+func (options *SyncOptions) tagSelector() (func(string) bool, error) {
+	var tagRegex *regexp.Regexp
+	if options.TagRegex != "" {
+		var err error
+		if tagRegex, err = regexp.Compile(options.TagRegex); err != nil {
+			return nil, errors.Wrapf(err, "invalid tag regex %q", options.TagRegex)
+		}
+	}
+	var constraints []semverConstraint
+	if options.TagSemver != "" {
+		var err error
+		if constraints, err = parseSemverConstraints(options.TagSemver); err != nil {
+			return nil, err
+		}
+	}
+	tags := make(map[string]bool, len(options.Tags))
+	for _, tag := range options.Tags {
+		tags[tag] = true
+	}
+
+	return func(tag string) bool {
+		if len(tags) > 0 && !tags[tag] {
+			return false
+		}
+		if tagRegex != nil && !tagRegex.MatchString(tag) {
+			return false
+		}
+		if constraints != nil {
+			version, ok := parseSemver(tag)
+			if !ok {
+				return false
+			}
+			for _, c := range constraints {
+				if !c.matches(version) {
+					return false
+				}
+			}
+		}
+		return true
+	}, nil
+}
+
+// syncSourceDigests returns the digest of the source followed by the
+// digest of the instance matching the platform if the source is a manifest
+// list not mirrored with all platforms.
+func syncSourceDigests(ctx context.Context, sys *types.SystemContext, ref types.ImageReference, allPlatforms bool) ([]digest.Digest, error) {
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	manifestBytes, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	manifestDigest, err := manifest.Digest(manifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	digests := []digest.Digest{manifestDigest}
+	if !allPlatforms && manifest.MIMETypeIsMultiImage(mimeType) {
+		list, err := manifest.ListFromBlob(manifestBytes, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		instance, err := list.ChooseInstance(sys)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, instance)
+	}
+	return digests, nil
+}
+
+// referenceDigest returns the digest of the manifest of the reference.
+func referenceDigest(ctx context.Context, sys *types.SystemContext, ref types.ImageReference) (digest.Digest, error) {
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	manifestBytes, _, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	return manifest.Digest(manifestBytes)
+}
+
+func transportName(ref types.ImageReference) string {
+	return ref.Transport().Name() + ":" + ref.StringWithinTransport()
+}
+
+// semverConstraint is a comparison with a version, e.g. ">=1.2".
+type semverConstraint struct {
+	operator string
+	version  [3]int
+}
+
+func parseSemverConstraints(constraints string) ([]semverConstraint, error) {
+	var parsed []semverConstraint
+	for _, field := range strings.Fields(constraints) {
+		c := semverConstraint{operator: "="}
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(field, op) {
+				c.operator = op
+				field = strings.TrimPrefix(field, op)
+				break
+			}
+		}
+		version, ok := parseSemver(field)
+		if !ok {
+			return nil, errors.Errorf("invalid semantic version constraint %q", constraints)
+		}
+		c.version = version
+		parsed = append(parsed, c)
+	}
+	if len(parsed) == 0 {
+		return nil, errors.Errorf("invalid semantic version constraint %q", constraints)
+	}
+	return parsed, nil
+}
+
+func (c semverConstraint) matches(version [3]int) bool {
+	cmp := 0
+	for i := range version {
+		if version[i] != c.version[i] {
+			cmp = 1
+			if version[i] < c.version[i] {
+				cmp = -1
+			}
+			break
+		}
+	}
+	switch c.operator {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+// parseSemver parses versions like "1", "1.2" or "v1.2.3".  Missing minor
+// and patch versions are 0.  Pre-releases are not versions.
+func parseSemver(value string) ([3]int, bool) {
+	var version [3]int
+	parts := strings.Split(strings.TrimPrefix(value, "v"), ".")
+	if len(parts) > 3 {
+		return version, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || strings.HasPrefix(part, "+") {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
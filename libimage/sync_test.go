@@ -0,0 +1,64 @@
+package libimage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncTagSelector(t *testing.T) {
+	tags := []string{"latest", "1.0", "v1.2.3", "1.10", "2.0", "2.0-rc1", "nightly"}
+	for _, test := range []struct {
+		options  SyncOptions
+		expected []string
+	}{
+		{SyncOptions{}, tags},
+		{SyncOptions{Tags: []string{"latest", "nightly"}}, []string{"latest", "nightly"}},
+		{SyncOptions{TagRegex: `^v?[0-9.]+$`}, []string{"1.0", "v1.2.3", "1.10", "2.0"}},
+		{SyncOptions{TagSemver: ">=1.2 <2"}, []string{"v1.2.3", "1.10"}},
+		{SyncOptions{TagSemver: "2"}, []string{"2.0"}},
+		{SyncOptions{TagSemver: ">1", TagRegex: `\.0$`}, []string{"2.0"}},
+	} {
+		selectTag, err := test.options.tagSelector()
+		require.NoError(t, err)
+		var selected []string
+		for _, tag := range tags {
+			if selectTag(tag) {
+				selected = append(selected, tag)
+			}
+		}
+		assert.Equal(t, test.expected, selected, "%+v", test.options)
+	}
+
+	for _, options := range []SyncOptions{{TagRegex: "("}, {TagSemver: ">=x"}, {TagSemver: " "}} {
+		_, err := options.tagSelector()
+		assert.Error(t, err, "%+v", options)
+	}
+}
+
+func TestSyncDestination(t *testing.T) {
+	named, err := reference.ParseNormalizedNamed("quay.io/foo/bar:1.0")
+	require.NoError(t, err)
+	source := named.(reference.NamedTagged)
+	r := &Runtime{}
+	dir, err := ioutil.TempDir("", "sync")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for destination, expected := range map[string]string{
+		"docker://mirror.local/quay/": "docker://mirror.local/quay/foo/bar:1.0",
+		"mirror.local:5000":           "docker://mirror.local:5000/foo/bar:1.0",
+		"oci:" + dir:                  "oci:" + dir + "/foo/bar:1.0",
+	} {
+		ref, err := r.syncDestination(source, destination)
+		require.NoError(t, err, destination)
+		assert.Equal(t, expected, transportName(ref), destination)
+	}
+
+	_, err = r.syncDestination(source, "dir:/srv/mirror")
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package libimage
+
+import (
+	"context"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/pkg/errors"
+)
+
+// UpdatePolicyLabel is the label of images overriding the update policy
+// passed to CheckForUpdates.
+const UpdatePolicyLabel = "io.containers.autoupdate"
+
+// UpdatePolicy determines if and how images are checked for updates.
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyDisabled skips checking the image for updates.
+	UpdatePolicyDisabled UpdatePolicy = "disabled"
+	// UpdatePolicyRegistry compares the digest of the image with the
+	// digest of its name in the registry.
+	UpdatePolicyRegistry UpdatePolicy = "registry"
+)
+
+// Validate returns an error if the policy is unknown.
+func (p UpdatePolicy) Validate() error {
+	switch p {
+	case UpdatePolicyDisabled, UpdatePolicyRegistry:
+		return nil
+	}
+	return errors.Errorf("unsupported update policy %q: must be %q or %q", p, UpdatePolicyDisabled, UpdatePolicyRegistry)
+}
+
+// parseUpdatePolicy parses the value of the UpdatePolicyLabel.  "image" is
+// an alias of "registry" for compatibility with Podman.
+func parseUpdatePolicy(value string) (UpdatePolicy, error) {
+	if value == "image" {
+		return UpdatePolicyRegistry, nil
+	}
+	policy := UpdatePolicy(value)
+	return policy, policy.Validate()
+}
+
+// UpdateCheckResult is the result of checking an image for updates.
+type UpdateCheckResult struct {
+	Image *Image
+	// Name is the name the image was checked with.
+	Name string
+	// Policy is the update policy of the image.
+	Policy UpdatePolicy
+	// Stale is set if the registry has a different image for Name.
+	Stale bool
+	// Error is set if the image could not be checked.
+	Error error
+}
+
+// CheckForUpdates checks which images differ from the images of their names
+// in the registries.  The policy applies to images without the
+// UpdatePolicyLabel.  Images are checked with their first name, images
+// without names cannot be checked.  Errors of individual images are
+// returned in their results.
+func (r *Runtime) CheckForUpdates(ctx context.Context, images []*Image, policy UpdatePolicy) ([]UpdateCheckResult, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	results := make([]UpdateCheckResult, 0, len(images))
+	for _, image := range images {
+		result := UpdateCheckResult{Image: image, Policy: policy}
+		result.Stale, result.Error = r.checkForUpdate(ctx, &result)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// checkForUpdate sets the name and policy of the result and returns true if
+// the image is stale.
+func (r *Runtime) checkForUpdate(ctx context.Context, result *UpdateCheckResult) (bool, error) {
+	labels, err := result.Image.Labels(ctx)
+	if err != nil {
+		return false, err
+	}
+	if value, ok := labels[UpdatePolicyLabel]; ok {
+		if result.Policy, err = parseUpdatePolicy(value); err != nil {
+			return false, errors.Wrapf(err, "image %s", result.Image.ID())
+		}
+	}
+	if result.Policy == UpdatePolicyDisabled {
+		return false, nil
+	}
+
+	names := result.Image.Names()
+	if len(names) == 0 {
+		return false, errors.Errorf("image %s has no name to check for updates", result.Image.ID())
+	}
+	result.Name = names[0]
+	named, err := reference.ParseNormalizedNamed(result.Name)
+	if err != nil {
+		return false, errors.Wrapf(err, "parsing name %q of image %s", result.Name, result.Image.ID())
+	}
+	remoteRef, err := docker.NewReference(reference.TagNameOnly(named))
+	if err != nil {
+		return false, err
+	}
+	return result.Image.HasDifferentDigest(ctx, remoteRef)
+}
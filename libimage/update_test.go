@@ -0,0 +1,29 @@
+package libimage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUpdatePolicy(t *testing.T) {
+	for value, expected := range map[string]UpdatePolicy{
+		"disabled": UpdatePolicyDisabled,
+		"registry": UpdatePolicyRegistry,
+		"image":    UpdatePolicyRegistry,
+	} {
+		policy, err := parseUpdatePolicy(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, policy, value)
+	}
+
+	for _, value := range []string{"", "local", "Registry"} {
+		_, err := parseUpdatePolicy(value)
+		assert.Error(t, err, value)
+	}
+
+	_, err := (&Runtime{}).CheckForUpdates(context.Background(), nil, "always")
+	assert.Error(t, err)
+}
@@ -11,7 +11,6 @@ import (
 	"path"
 	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/containers/common/pkg/apparmor/internal/supported"
 	"github.com/containers/storage/pkg/unshare"
@@ -30,23 +29,16 @@ func IsEnabled() bool {
 	return supported.NewAppArmorVerifier().IsSupported() == nil
 }
 
-// profileData holds information about the given profile for generation.
-type profileData struct {
-	// Name is profile name.
-	Name string
-	// Imports defines the apparmor functions to import, before defining the profile.
-	Imports []string
-	// InnerImports defines the apparmor functions to import in the profile.
-	InnerImports []string
-	// Version is the {major, minor, patch} version of apparmor_parser as a single number.
-	Version int
-}
-
 // generateDefault creates an apparmor profile from ProfileData.
 func (p *profileData) generateDefault(apparmorParserPath string, out io.Writer) error {
-	compiled, err := template.New("apparmor_profile").Parse(defaultProfileTemplate)
-	if err != nil {
-		return errors.Wrap(err, "create AppArmor profile from template")
+	return p.generate(apparmorParserPath, nil, out)
+}
+
+// generate creates an apparmor profile from ProfileData and the template, or
+// the built-in template if nil.
+func (p *profileData) generate(apparmorParserPath string, tmpl *ProfileTemplate, out io.Writer) error {
+	if tmpl == nil {
+		tmpl = &ProfileTemplate{Content: defaultProfileTemplate}
 	}
 
 	if macroExists("tunables/global") {
@@ -65,7 +57,7 @@ func (p *profileData) generateDefault(apparmorParserPath string, out io.Writer)
 	}
 	p.Version = ver
 
-	return errors.Wrap(compiled.Execute(out, p), "execute compiled profile")
+	return tmpl.execute(p, out)
 }
 
 // macrosExists checks if the passed macro exists.
@@ -77,14 +69,23 @@ func macroExists(m string) bool {
 // InstallDefault generates a default profile and loads it into the kernel
 // using 'apparmor_parser'.
 func InstallDefault(name string) error {
+	return InstallTemplate(name, nil)
+}
+
+// InstallTemplate generates a profile from the template, or the built-in
+// template of the default profile if nil, and loads it into the kernel using
+// 'apparmor_parser'.
+func InstallTemplate(name string, tmpl *ProfileTemplate) error {
+	return install(&profileData{Name: name}, tmpl)
+}
+
+// install generates the profile from the data and the template, or the
+// built-in template if nil, and loads it into the kernel.
+func install(p *profileData, tmpl *ProfileTemplate) error {
 	if unshare.IsRootless() {
 		return ErrApparmorRootless
 	}
 
-	p := profileData{
-		Name: name,
-	}
-
 	apparmorParserPath, err := supported.NewAppArmorVerifier().FindAppArmorParserBinary()
 	if err != nil {
 		return errors.Wrap(err, "find `apparmor_parser` binary")
@@ -101,14 +102,14 @@ func InstallDefault(name string) error {
 		}
 		return errors.Wrapf(err, "start %s command", apparmorParserPath)
 	}
-	if err := p.generateDefault(apparmorParserPath, pipe); err != nil {
+	if err := p.generate(apparmorParserPath, tmpl, pipe); err != nil {
 		if pipeErr := pipe.Close(); pipeErr != nil {
 			logrus.Errorf("unable to close AppArmor pipe: %q", pipeErr)
 		}
 		if cmdErr := cmd.Wait(); cmdErr != nil {
 			logrus.Errorf("unable to wait for AppArmor command: %q", cmdErr)
 		}
-		return errors.Wrap(err, "generate default profile into pipe")
+		return errors.Wrap(err, "generate profile into pipe")
 	}
 
 	if pipeErr := pipe.Close(); pipeErr != nil {
@@ -143,7 +144,7 @@ func IsLoaded(name string) (bool, error) {
 		return false, errors.Wrapf(ErrApparmorRootless, "cannot load AppArmor profile %q", name)
 	}
 
-	file, err := os.Open("/sys/kernel/security/apparmor/profiles")
+	file, err := os.Open(profilesFile)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
@@ -242,6 +243,15 @@ func parseAAParserVersion(output string) (int, error) {
 // default profile, return DefaultLipodProfilePrefix, otherwise the specified
 // one.
 func CheckProfileAndLoadDefault(name string) (string, error) {
+	return CheckProfileAndLoadTemplate(name, nil)
+}
+
+// CheckProfileAndLoadTemplate is like CheckProfileAndLoadDefault but
+// generates the default profile from the template, if not nil.  The name of
+// the default profile then includes the version of the template, see
+// ProfileTemplate.ProfileName, such that a changed template is loaded on the
+// next invocation.  The returned name must be used for the container.
+func CheckProfileAndLoadTemplate(name string, tmpl *ProfileTemplate) (string, error) {
 	if name == "unconfined" {
 		return name, nil
 	}
@@ -281,6 +291,10 @@ func CheckProfileAndLoadDefault(name string) (string, error) {
 		return name, nil
 	}
 
+	if tmpl != nil {
+		name = tmpl.ProfileName(name)
+	}
+
 	// To avoid expensive redundant loads on each invocation, check
 	// if it's loaded before installing it.
 	isLoaded, err := IsLoaded(name)
@@ -288,7 +302,7 @@ func CheckProfileAndLoadDefault(name string) (string, error) {
 		return "", errors.Wrapf(err, "verify if profile %s is loaded", name)
 	}
 	if !isLoaded {
-		err = InstallDefault(name)
+		err = InstallTemplate(name, tmpl)
 		if err != nil {
 			return "", errors.Wrapf(err, "install profile %s", name)
 		}
@@ -45,5 +45,8 @@ profile {{.Name}} flags=(attach_disconnected,mediate_deleted) {
   # suppress ptrace denials when using using 'ps' inside a container
   ptrace (trace,read) peer={{.Name}},
 {{end}}
+{{range $value := .Rules}}
+  {{$value}}
+{{end}}
 }
 `
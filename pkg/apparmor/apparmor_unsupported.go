@@ -29,3 +29,33 @@ func CheckProfileAndLoadDefault(name string) (string, error) {
 func DefaultContent(name string) ([]byte, error) {
 	return nil, nil
 }
+
+// InstallTemplate dummy.
+func InstallTemplate(name string, tmpl *ProfileTemplate) error {
+	return ErrApparmorUnsupported
+}
+
+// CheckProfileAndLoadTemplate dummy.
+func CheckProfileAndLoadTemplate(name string, tmpl *ProfileTemplate) (string, error) {
+	return CheckProfileAndLoadDefault(name)
+}
+
+// ContainerProfileContent dummy.
+func ContainerProfileContent(containerID string, options *ContainerProfileOptions) ([]byte, error) {
+	return nil, ErrApparmorUnsupported
+}
+
+// InstallContainerProfile dummy.
+func InstallContainerProfile(containerID string, options *ContainerProfileOptions) (string, error) {
+	return "", ErrApparmorUnsupported
+}
+
+// RemoveContainerProfile dummy.
+func RemoveContainerProfile(containerID string) error {
+	return ErrApparmorUnsupported
+}
+
+// GetStatus dummy.
+func GetStatus() (*Status, error) {
+	return &Status{DefaultProfile: Profile, Reason: ErrApparmorUnsupported.Error()}, nil
+}
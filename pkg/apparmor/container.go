@@ -0,0 +1,89 @@
+package apparmor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ContainerProfilePrefix is the prefix of the names of per-container
+// profiles.
+const ContainerProfilePrefix = "containers-container-"
+
+// pathRuleRegexp matches the paths of PathRules: absolute paths or paths
+// starting with a variable like @{PROC}, without whitespace, commas or
+// characters ending the rule.
+var pathRuleRegexp = regexp.MustCompile(`^(/|@\{[A-Za-z_]+\})[^\s,{}#"]*$`)
+
+// permissionsRegexp matches the file permissions of PathRules.
+var permissionsRegexp = regexp.MustCompile(`^[rwaklmix]+$`)
+
+// PathRule is a file rule of a container profile.
+type PathRule struct {
+	// Path of the rule, which may use AppArmor globbing, e.g. "/srv/**".
+	Path string
+	// Permissions of the rule, e.g. "rw".  It defaults to "rw" for
+	// allowed paths and "rwklx" for denied paths.
+	Permissions string
+}
+
+// ContainerProfileOptions are the options of a per-container profile.
+type ContainerProfileOptions struct {
+	// Template of the profile the container profile is derived from, or
+	// nil for the built-in template of the default profile.
+	Template *ProfileTemplate
+	// AllowedPaths are the paths the container may access.
+	AllowedPaths []PathRule
+	// DeniedPaths are the paths the container may not access.  Note
+	// that denied paths take precedence over allowed paths.
+	DeniedPaths []PathRule
+}
+
+// ContainerProfileName returns the name of the per-container profile of the
+// container.
+func ContainerProfileName(containerID string) string {
+	return ContainerProfilePrefix + containerID
+}
+
+// rules returns the AppArmor rules of the options.
+func (o *ContainerProfileOptions) rules() ([]string, error) {
+	var rules []string
+	for _, r := range o.AllowedPaths {
+		rule, err := r.format("", "rw")
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	for _, r := range o.DeniedPaths {
+		rule, err := r.format("deny ", "rwklx")
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r *PathRule) format(qualifier, defaultPermissions string) (string, error) {
+	if !pathRuleRegexp.MatchString(r.Path) {
+		return "", errors.Errorf("invalid AppArmor path %q", r.Path)
+	}
+	permissions := r.Permissions
+	if permissions == "" {
+		permissions = defaultPermissions
+	}
+	if !permissionsRegexp.MatchString(permissions) {
+		return "", errors.Errorf("invalid AppArmor permissions %q of path %q", permissions, r.Path)
+	}
+	return qualifier + r.Path + " " + permissions + ",", nil
+}
+
+// validateContainerID checks that the ID can be used in a profile name.
+func validateContainerID(containerID string) error {
+	if containerID == "" || strings.ContainsAny(containerID, " \t\n/{}") {
+		return errors.Errorf("invalid container ID %q for an AppArmor profile", containerID)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// +build linux,apparmor
+
+package apparmor
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/containers/common/pkg/apparmor/internal/supported"
+	"github.com/containers/storage/pkg/unshare"
+	"github.com/pkg/errors"
+)
+
+// removeFile is written the name of a profile to unload it.
+const removeFile = "/sys/kernel/security/apparmor/.remove"
+
+// ContainerProfileContent returns the content of the per-container profile
+// of the container.
+func ContainerProfileContent(containerID string, options *ContainerProfileOptions) ([]byte, error) {
+	if options == nil {
+		options = &ContainerProfileOptions{}
+	}
+	p, err := containerProfileData(containerID, options)
+	if err != nil {
+		return nil, err
+	}
+	apparmorParserPath, err := supported.NewAppArmorVerifier().FindAppArmorParserBinary()
+	if err != nil {
+		return nil, errors.Wrap(err, "find `apparmor_parser` binary")
+	}
+	buffer := &bytes.Buffer{}
+	if err := p.generate(apparmorParserPath, options.Template, buffer); err != nil {
+		return nil, errors.Wrapf(err, "generate AppArmor profile of container %s", containerID)
+	}
+	return buffer.Bytes(), nil
+}
+
+// InstallContainerProfile generates the per-container profile of the
+// container and loads it into the kernel, replacing a previously loaded
+// one.  It returns the name of the profile, which must be unloaded with
+// RemoveContainerProfile when the container is removed.
+func InstallContainerProfile(containerID string, options *ContainerProfileOptions) (string, error) {
+	if options == nil {
+		options = &ContainerProfileOptions{}
+	}
+	p, err := containerProfileData(containerID, options)
+	if err != nil {
+		return "", err
+	}
+	if err := install(p, options.Template); err != nil {
+		return "", errors.Wrapf(err, "install AppArmor profile of container %s", containerID)
+	}
+	return p.Name, nil
+}
+
+// RemoveContainerProfile unloads the per-container profile of the container.
+// Not loaded profiles are ignored.
+func RemoveContainerProfile(containerID string) error {
+	if unshare.IsRootless() {
+		return ErrApparmorRootless
+	}
+	name := ContainerProfileName(containerID)
+	isLoaded, err := IsLoaded(name)
+	if err != nil {
+		return errors.Wrapf(err, "verify if profile %s is loaded", name)
+	}
+	if !isLoaded {
+		return nil
+	}
+
+	f, err := os.OpenFile(removeFile, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.Wrap(err, "open AppArmor profile removal path")
+	}
+	defer f.Close()
+	if _, err := f.WriteString(name); err != nil {
+		return errors.Wrapf(err, "remove AppArmor profile %s", name)
+	}
+	return nil
+}
+
+func containerProfileData(containerID string, options *ContainerProfileOptions) (*profileData, error) {
+	if err := validateContainerID(containerID); err != nil {
+		return nil, err
+	}
+	rules, err := options.rules()
+	if err != nil {
+		return nil, err
+	}
+	return &profileData{Name: ContainerProfileName(containerID), Rules: rules}, nil
+}
@@ -0,0 +1,57 @@
+package apparmor
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContainerProfileRules(t *testing.T) {
+	options := &ContainerProfileOptions{
+		AllowedPaths: []PathRule{{Path: "/data/**"}, {Path: "@{PROC}/sys/net/**", Permissions: "r"}},
+		DeniedPaths:  []PathRule{{Path: "/data/secret/**"}, {Path: "/etc/shadow", Permissions: "r"}},
+	}
+	rules, err := options.rules()
+	if err != nil {
+		t.Fatalf("Couldn't format rules: %v", err)
+	}
+	expected := []string{
+		"/data/** rw,",
+		"@{PROC}/sys/net/** r,",
+		"deny /data/secret/** rwklx,",
+		"deny /etc/shadow r,",
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected rules %v, got %v", expected, rules)
+	}
+
+	tmpl := &ProfileTemplate{Content: "profile {{.Name}} {\n{{range .Rules}}  {{.}}\n{{end}}}\n"}
+	var out bytes.Buffer
+	if err := tmpl.execute(&profileData{Name: ContainerProfileName("abc"), Rules: rules}, &out); err != nil {
+		t.Fatalf("Couldn't execute template: %v", err)
+	}
+	if !strings.Contains(out.String(), "profile "+ContainerProfilePrefix+"abc {") || !strings.Contains(out.String(), "  deny /etc/shadow r,\n") {
+		t.Fatalf("Unexpected profile: %s", out.String())
+	}
+
+	invalid := []PathRule{
+		{Path: "relative/**"},
+		{Path: "/data/** rw,\n  /etc/**"},
+		{Path: "/data/{a,b}"},
+		{Path: "/data", Permissions: "rwx ux"},
+		{Path: "/data", Permissions: "z"},
+	}
+	for _, r := range invalid {
+		options := &ContainerProfileOptions{DeniedPaths: []PathRule{r}}
+		if _, err := options.rules(); err == nil {
+			t.Fatalf("Expected rule %#v to be invalid", r)
+		}
+	}
+
+	for _, id := range []string{"", "a b", "a/b", "a{"} {
+		if err := validateContainerID(id); err == nil {
+			t.Fatalf("Expected container ID %q to be invalid", id)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package apparmor
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// profilesFile lists the loaded profiles and their modes.
+const profilesFile = "/sys/kernel/security/apparmor/profiles"
+
+// LoadedProfile is a profile loaded into the kernel.
+type LoadedProfile struct {
+	// Name of the profile.
+	Name string
+	// Mode of the profile, e.g. "enforce" or "complain".
+	Mode string
+}
+
+// Status describes the AppArmor support of the host, such that container
+// engines can report precisely why a profile cannot be used.
+type Status struct {
+	// Enabled is set if AppArmor is enabled on the host.
+	Enabled bool
+	// Rootless is set if the process runs in rootless mode, where
+	// profiles can neither be loaded nor applied.
+	Rootless bool
+	// ParserPath is the path of the `apparmor_parser` binary, or empty if
+	// it was not found.
+	ParserPath string
+	// CanLoad is set if profiles can be loaded into the kernel.
+	CanLoad bool
+	// Reason explains why profiles cannot be loaded.
+	Reason string
+	// DefaultProfile is the name of the default profile of this version.
+	DefaultProfile string
+	// DefaultProfileMode is the mode of the loaded DefaultProfile, or
+	// empty if it is not loaded.
+	DefaultProfileMode string
+	// DefaultProfiles are the loaded default profiles of all versions,
+	// including the profiles generated from templates.
+	DefaultProfiles []LoadedProfile
+}
+
+// Version returns the version of the default profile, e.g. "0.38.0" for
+// "containers-default-0.38.0", or an empty string if the profile is not a
+// default profile.
+func (p *LoadedProfile) Version() string {
+	if !strings.HasPrefix(p.Name, ProfilePrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(p.Name, ProfilePrefix)
+}
+
+// parseProfiles parses the loaded profiles in the format of profilesFile,
+// one "name (mode)" per line.
+func parseProfiles(r io.Reader) ([]LoadedProfile, error) {
+	var profiles []LoadedProfile
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		p := LoadedProfile{Name: line}
+		if i := strings.LastIndex(line, " ("); i >= 0 && strings.HasSuffix(line, ")") {
+			p.Name = line[:i]
+			p.Mode = line[i+2 : len(line)-1]
+		}
+		profiles = append(profiles, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading AppArmor profiles")
+	}
+	return profiles, nil
+}
+
+// setProfiles sets the default profiles of the status from the loaded
+// profiles.
+func (s *Status) setProfiles(profiles []LoadedProfile) {
+	for _, p := range profiles {
+		if !strings.HasPrefix(p.Name, ProfilePrefix) {
+			continue
+		}
+		s.DefaultProfiles = append(s.DefaultProfiles, p)
+		if p.Name == s.DefaultProfile {
+			s.DefaultProfileMode = p.Mode
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// +build linux,apparmor
+
+package apparmor
+
+import (
+	"os"
+
+	"github.com/containers/common/pkg/apparmor/internal/supported"
+	"github.com/containers/storage/pkg/unshare"
+	runcaa "github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/pkg/errors"
+)
+
+// GetStatus returns the AppArmor status of the host.  Loaded profiles can
+// only be listed as root, they are not reported in rootless mode.
+func GetStatus() (*Status, error) {
+	s := &Status{
+		Enabled:        runcaa.IsEnabled(),
+		Rootless:       unshare.IsRootless(),
+		DefaultProfile: Profile,
+	}
+	if path, err := supported.NewAppArmorVerifier().FindAppArmorParserBinary(); err == nil {
+		s.ParserPath = path
+	}
+
+	switch {
+	case !s.Enabled:
+		s.Reason = "AppArmor is disabled on the host"
+	case s.Rootless:
+		s.Reason = ErrApparmorRootless.Error()
+	case s.ParserPath == "":
+		s.Reason = "`apparmor_parser` binary neither found in /sbin nor $PATH"
+	default:
+		s.CanLoad = true
+	}
+	if !s.Enabled || s.Rootless {
+		return s, nil
+	}
+
+	file, err := os.Open(profilesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrap(err, "open AppArmor profile path")
+	}
+	defer file.Close()
+	profiles, err := parseProfiles(file)
+	if err != nil {
+		return nil, err
+	}
+	s.setProfiles(profiles)
+	return s, nil
+}
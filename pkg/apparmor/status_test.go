@@ -0,0 +1,47 @@
+package apparmor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProfiles(t *testing.T) {
+	input := `/usr/sbin/cupsd (enforce)
+containers-default-0.37.0 (enforce)
+` + Profile + ` (complain)
+` + Profile + `-0123456789ab (enforce)
+profile with spaces (kill)
+no-mode
+`
+	profiles, err := parseProfiles(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Couldn't parse profiles: %v", err)
+	}
+	expected := []LoadedProfile{
+		{Name: "/usr/sbin/cupsd", Mode: "enforce"},
+		{Name: "containers-default-0.37.0", Mode: "enforce"},
+		{Name: Profile, Mode: "complain"},
+		{Name: Profile + "-0123456789ab", Mode: "enforce"},
+		{Name: "profile with spaces", Mode: "kill"},
+		{Name: "no-mode"},
+	}
+	if !reflect.DeepEqual(profiles, expected) {
+		t.Fatalf("Expected profiles %v, got %v", expected, profiles)
+	}
+
+	s := &Status{DefaultProfile: Profile}
+	s.setProfiles(profiles)
+	if s.DefaultProfileMode != "complain" {
+		t.Fatalf("Expected default profile mode complain, got %q", s.DefaultProfileMode)
+	}
+	if !reflect.DeepEqual(s.DefaultProfiles, expected[1:4]) {
+		t.Fatalf("Expected default profiles %v, got %v", expected[1:4], s.DefaultProfiles)
+	}
+	if v := s.DefaultProfiles[0].Version(); v != "0.37.0" {
+		t.Fatalf("Expected version 0.37.0, got %q", v)
+	}
+	if v := profiles[0].Version(); v != "" {
+		t.Fatalf("Expected no version, got %q", v)
+	}
+}
@@ -0,0 +1,110 @@
+package apparmor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// profileData holds information about the given profile for generation.
+type profileData struct {
+	// Name is profile name.
+	Name string
+	// Imports defines the apparmor functions to import, before defining the profile.
+	Imports []string
+	// InnerImports defines the apparmor functions to import in the profile.
+	InnerImports []string
+	// Version is the {major, minor, patch} version of apparmor_parser as a single number.
+	Version int
+	// Parameters are the site-specific parameters of a ProfileTemplate.
+	Parameters map[string]string
+	// Rules are additional rules of the profile, e.g. of a container
+	// profile.
+	Rules []string
+}
+
+// ProfileTemplate is a site-specific template replacing the built-in
+// template of the default profile.
+type ProfileTemplate struct {
+	// Content is the text/template of the profile.  It is executed with
+	// the .Name, .Imports, .InnerImports, .Version and .Rules of the
+	// built-in template, and the .Parameters.
+	Content string
+	// Parameters are the values of the .Parameters of the template.
+	Parameters map[string]string
+}
+
+// LoadTemplate reads and validates the profile template at path.
+func LoadTemplate(path string, parameters map[string]string) (*ProfileTemplate, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read AppArmor profile template")
+	}
+	t := &ProfileTemplate{Content: string(content), Parameters: parameters}
+	if err := t.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "AppArmor profile template %s", path)
+	}
+	return t, nil
+}
+
+// Validate checks that the template can be executed with its parameters and
+// that it defines a profile named after the .Name.
+func (t *ProfileTemplate) Validate() error {
+	const name = "containers-template-validation"
+	var out bytes.Buffer
+	if err := t.execute(&profileData{Name: name}, &out); err != nil {
+		return err
+	}
+	if !strings.Contains(out.String(), "profile "+name) {
+		return errors.New("template does not define a profile named {{.Name}}")
+	}
+	return nil
+}
+
+// ProfileName returns the name of the profile generated from the template
+// for the profile name.  The name includes a digest of the template and its
+// parameters, such that a changed template is loaded as a new profile.  A
+// name already including the digest is returned unchanged.
+func (t *ProfileTemplate) ProfileName(name string) string {
+	suffix := "-" + t.digest()[:12]
+	if strings.HasSuffix(name, suffix) {
+		return name
+	}
+	return name + suffix
+}
+
+func (t *ProfileTemplate) digest() string {
+	h := sha256.New()
+	// Errors of hash.Hash writes are always nil.
+	_, _ = io.WriteString(h, t.Content)
+	keys := make([]string, 0, len(t.Parameters))
+	for key := range t.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, _ = io.WriteString(h, "\x00"+key+"="+t.Parameters[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// execute writes the profile of the data to out.  Parameters referenced by
+// the template but not defined are an error.
+func (t *ProfileTemplate) execute(p *profileData, out io.Writer) error {
+	compiled, err := template.New("apparmor_profile").Option("missingkey=error").Parse(t.Content)
+	if err != nil {
+		return errors.Wrap(err, "create AppArmor profile from template")
+	}
+	p.Parameters = t.Parameters
+	if p.Parameters == nil {
+		p.Parameters = map[string]string{}
+	}
+	return errors.Wrap(compiled.Execute(out, p), "execute compiled profile")
+}
@@ -0,0 +1,84 @@
+package apparmor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `profile {{.Name}} flags=(attach_disconnected) {
+  file,
+  deny {{.Parameters.denied}} rwklx,
+}
+`
+
+func TestProfileTemplate(t *testing.T) {
+	tmpl := &ProfileTemplate{Content: testTemplate, Parameters: map[string]string{"denied": "/srv/**"}}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("Expected template to be valid: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.execute(&profileData{Name: "test"}, &out); err != nil {
+		t.Fatalf("Couldn't execute template: %v", err)
+	}
+	if !strings.Contains(out.String(), "profile test flags") || !strings.Contains(out.String(), "deny /srv/** rwklx") {
+		t.Fatalf("Unexpected profile: %s", out.String())
+	}
+
+	invalid := []*ProfileTemplate{
+		{Content: testTemplate},
+		{Content: "profile {{.Name} {}"},
+		{Content: "profile fixed-name {}"},
+	}
+	for _, tmpl := range invalid {
+		if err := tmpl.Validate(); err == nil {
+			t.Fatalf("Expected template %q to be invalid", tmpl.Content)
+		}
+	}
+}
+
+func TestProfileTemplateName(t *testing.T) {
+	tmpl := &ProfileTemplate{Content: testTemplate, Parameters: map[string]string{"denied": "/srv/**"}}
+	name := tmpl.ProfileName(Profile)
+	if !strings.HasPrefix(name, Profile+"-") || len(name) != len(Profile)+13 {
+		t.Fatalf("Unexpected profile name %q", name)
+	}
+	if tmpl.ProfileName(name) != name {
+		t.Fatalf("Expected versioned name %q to be unchanged", name)
+	}
+
+	changed := &ProfileTemplate{Content: testTemplate, Parameters: map[string]string{"denied": "/home/**"}}
+	if changed.ProfileName(Profile) == name {
+		t.Fatalf("Expected changed parameters to change the profile name %q", name)
+	}
+	changed = &ProfileTemplate{Content: testTemplate + "\n", Parameters: tmpl.Parameters}
+	if changed.ProfileName(Profile) == name {
+		t.Fatalf("Expected changed template to change the profile name %q", name)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apparmor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "profile.tmpl")
+	if err := ioutil.WriteFile(path, []byte(testTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadTemplate(path, map[string]string{"denied": "/srv/**"}); err != nil {
+		t.Fatalf("Couldn't load template: %v", err)
+	}
+	if _, err := LoadTemplate(path, nil); err == nil {
+		t.Fatal("Expected template with missing parameters to fail")
+	}
+	if _, err := LoadTemplate(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Fatal("Expected missing template to fail")
+	}
+}
@@ -20,9 +20,18 @@ var (
 	// Used internally and populated during init().
 	capsList []capability.Cap
 
+	// Used internally and populated during init(). Contains the
+	// capabilities known to this package, including those not supported
+	// by the running kernel.
+	knownCapabilityList []string
+
 	// ErrUnknownCapability is thrown when an unknown capability is processed.
 	ErrUnknownCapability = errors.New("unknown capability")
 
+	// ErrUnsupportedCapability is thrown when a capability is known but not
+	// supported by the running kernel.
+	ErrUnsupportedCapability = errors.New("capability not supported by the kernel")
+
 	// ContainerImageLabels - label can indicate the required
 	// capabilities required by containers to run the container image.
 	ContainerImageLabels = []string{"io.containers.capabilities"}
@@ -43,6 +52,7 @@ func init() {
 		last = capability.CAP_BLOCK_SUSPEND
 	}
 	for _, cap := range capability.List() {
+		knownCapabilityList = append(knownCapabilityList, getCapName(cap))
 		if cap > last {
 			continue
 		}
@@ -98,14 +108,15 @@ func AllCapabilities() []string {
 	return capabilityList
 }
 
-// NormalizeCapabilities normalizes caps by adding a "CAP_" prefix (if not yet
-// present).
+// NormalizeCapabilities normalizes caps by converting them to upper case and
+// adding a "CAP_" prefix (if not yet present).  Any case of "all" is
+// normalized to All.
 func NormalizeCapabilities(caps []string) ([]string, error) {
 	normalized := make([]string, len(caps))
 	for i, c := range caps {
-		c = strings.ToUpper(c)
+		c = strings.ToUpper(strings.TrimSpace(c))
 		if c == All {
-			normalized = append(normalized, c)
+			normalized[i] = c
 			continue
 		}
 		if !strings.HasPrefix(c, "CAP_") {
@@ -3,6 +3,7 @@ package capabilities
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -88,3 +89,108 @@ func TestValidateCapabilitieBadCapabilities(t *testing.T) {
 	err := ValidateCapabilities(strSlice)
 	assert.Error(t, err)
 }
+
+func TestNormalizeCapabilitiesAll(t *testing.T) {
+	caps, err := NormalizeCapabilities([]string{"all", " net_admin "})
+	require.Nil(t, err)
+	assert.Equal(t, []string{All, "CAP_NET_ADMIN"}, caps)
+}
+
+func TestSetOperations(t *testing.T) {
+	a := []string{"CAP_CHOWN", "CAP_NET_ADMIN", "CAP_CHOWN"}
+	b := []string{"CAP_NET_ADMIN", "CAP_SYS_ADMIN"}
+	assert.Equal(t, []string{"CAP_CHOWN", "CAP_NET_ADMIN", "CAP_SYS_ADMIN"}, Union(a, b))
+	assert.Equal(t, []string{"CAP_CHOWN"}, Subtract(a, b))
+	assert.Equal(t, []string{"CAP_NET_ADMIN"}, Intersect(a, b))
+	assert.Nil(t, Intersect(a, nil))
+}
+
+func TestExpandCapabilities(t *testing.T) {
+	caps, err := ExpandCapabilities([]string{"chown", "ALL"})
+	require.Nil(t, err)
+	assert.Equal(t, AllCapabilities(), caps)
+
+	caps, err = ExpandCapabilities([]string{"chown", "CAP_CHOWN"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"CAP_CHOWN"}, caps)
+}
+
+func TestValidateKernelSupport(t *testing.T) {
+	require.Nil(t, ValidateKernelSupport([]string{"chown", "CAP_NET_ADMIN", "all"}))
+	err := ValidateKernelSupport([]string{"NO_ADMIN"})
+	assert.True(t, errors.Is(err, ErrUnknownCapability))
+}
+
+func TestSpecCapabilities(t *testing.T) {
+	caps, err := SpecCapabilities([]string{"CAP_CHOWN", "CAP_SETUID"}, []string{"net_admin"}, []string{"setuid"})
+	require.Nil(t, err)
+	expected := []string{"CAP_CHOWN", "CAP_NET_ADMIN"}
+	assert.Equal(t, expected, caps.Bounding)
+	assert.Equal(t, expected, caps.Effective)
+	assert.Equal(t, expected, caps.Permitted)
+	assert.Nil(t, caps.Inheritable)
+	assert.Nil(t, caps.Ambient)
+}
+
+func TestComputeCapabilities(t *testing.T) {
+	caps, warnings, err := ComputeCapabilities(&ComputeOptions{
+		Base:    []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"},
+		Add:     []string{"NET_RAW"},
+		Ambient: []string{"net_bind_service", "sys_admin"},
+	})
+	require.Nil(t, err)
+	expected := []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE", "CAP_NET_RAW"}
+	assert.Equal(t, expected, caps.Bounding)
+	assert.Equal(t, expected, caps.Effective)
+	assert.Equal(t, expected, caps.Permitted)
+	assert.Equal(t, []string{"CAP_NET_BIND_SERVICE"}, caps.Inheritable)
+	assert.Equal(t, []string{"CAP_NET_BIND_SERVICE"}, caps.Ambient)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "CAP_SYS_ADMIN")
+
+	caps, warnings, err = ComputeCapabilities(&ComputeOptions{
+		Base:        []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"},
+		Ambient:     []string{"CAP_NET_BIND_SERVICE"},
+		NonRootUser: true,
+	})
+	require.Nil(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"}, caps.Bounding)
+	assert.Equal(t, []string{"CAP_NET_BIND_SERVICE"}, caps.Effective)
+	assert.Equal(t, []string{"CAP_NET_BIND_SERVICE"}, caps.Permitted)
+
+	_, _, err = ComputeCapabilities(&ComputeOptions{Add: []string{"CAP_CHOWN"}, Drop: []string{"chown"}})
+	assert.Error(t, err)
+}
+
+func TestComputeCapabilitiesRootless(t *testing.T) {
+	bounding, err := BoundingSet()
+	require.Nil(t, err)
+	caps, warnings, err := ComputeCapabilities(&ComputeOptions{Base: []string{"ALL"}, Rootless: true})
+	require.Nil(t, err)
+	assert.Equal(t, bounding, caps.Bounding)
+	assert.Len(t, warnings, len(AllCapabilities())-len(bounding))
+}
+
+func TestExpandProfiles(t *testing.T) {
+	profiles := map[string][]string{
+		"net-admin": {"NET_ADMIN", "NET_RAW"},
+		"default":   {"CHOWN"},
+	}
+	caps, err := ExpandProfiles([]string{"profile:default", "SETUID", "profile:net-admin", "profile:none"}, profiles)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"CHOWN", "SETUID", "NET_ADMIN", "NET_RAW"}, caps)
+
+	_, err = ExpandProfiles([]string{"profile:unknown"}, profiles)
+	assert.True(t, errors.Is(err, ErrUnknownProfile))
+
+	_, err = ExpandProfiles([]string{"profile:nested"}, map[string][]string{"nested": {"profile:default"}})
+	assert.Error(t, err)
+}
+
+func TestValidateProfiles(t *testing.T) {
+	require.Nil(t, ValidateProfiles(map[string][]string{"net-admin": {"NET_ADMIN", "cap_net_raw"}}))
+	assert.Error(t, ValidateProfiles(map[string][]string{"none": {}}))
+	assert.Error(t, ValidateProfiles(map[string][]string{"bad": {"NO_ADMIN"}}))
+	assert.Error(t, ValidateProfiles(map[string][]string{"nested": {"profile:net-admin"}}))
+}
@@ -0,0 +1,78 @@
+package capabilities
+
+import (
+	"fmt"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// ComputeOptions are the input of ComputeCapabilities.
+type ComputeOptions struct {
+	// Base are the default capabilities of the container.
+	Base []string
+	// Add and Drop are the requested changes of Base, see
+	// MergeCapabilities.
+	Add  []string
+	Drop []string
+	// Ambient are the capabilities to keep in the ambient set, such that
+	// they are retained across the execve of a non-root process.
+	Ambient []string
+	// NonRootUser is set if the container process runs as a user other
+	// than root, which starts without effective and permitted capabilities
+	// except for the ambient ones.
+	NonRootUser bool
+	// Rootless is set if the container is created by an unprivileged user,
+	// who can only grant capabilities in their own bounding set.
+	Rootless bool
+}
+
+// ComputeCapabilities computes the OCI capability sets of a container from
+// the requested capabilities.  Capabilities which cannot be granted, e.g.
+// because they are not in the bounding set of a rootless user, are removed
+// and reported in the returned warnings.
+func ComputeCapabilities(options *ComputeOptions) (*specs.LinuxCapabilities, []string, error) {
+	merged, err := MergeCapabilities(options.Base, options.Add, options.Drop)
+	if err != nil {
+		return nil, nil, err
+	}
+	caps, err := ExpandCapabilities(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	ambient, err := ExpandCapabilities(options.Ambient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	if options.Rootless {
+		bounding, err := BoundingSet()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range Subtract(caps, bounding) {
+			warnings = append(warnings, fmt.Sprintf("capability %s is not in the bounding set of the current user and cannot be granted", c))
+		}
+		caps = Intersect(caps, bounding)
+	}
+	for _, c := range Subtract(ambient, caps) {
+		warnings = append(warnings, fmt.Sprintf("ambient capability %s is not granted to the container and cannot be raised", c))
+	}
+	ambient = Intersect(ambient, caps)
+
+	// Ambient capabilities must be permitted and inheritable. Other
+	// capabilities are not made inheritable, such that they are not passed
+	// on to executables with file capabilities.
+	spec := &specs.LinuxCapabilities{
+		Bounding:    caps,
+		Effective:   caps,
+		Permitted:   caps,
+		Inheritable: ambient,
+		Ambient:     ambient,
+	}
+	if options.NonRootUser {
+		spec.Effective = ambient
+		spec.Permitted = ambient
+	}
+	return spec, warnings, nil
+}
@@ -0,0 +1,79 @@
+package capabilities
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProfilePrefix marks a reference to a named capability profile in a list
+// of capabilities, e.g. "profile:net-admin".
+const ProfilePrefix = "profile:"
+
+const (
+	// ProfileNone is the built-in profile without any capabilities.
+	ProfileNone = "none"
+	// ProfileDefault is the profile of the default capabilities of the
+	// container engine.
+	ProfileDefault = "default"
+)
+
+// ErrUnknownProfile is thrown when an unknown capability profile is
+// referenced.
+var ErrUnknownProfile = errors.New("unknown capability profile")
+
+// IsProfile returns true if c references a capability profile.
+func IsProfile(c string) bool {
+	return strings.HasPrefix(c, ProfilePrefix)
+}
+
+// ExpandProfiles replaces the profile references in caps by the
+// capabilities of the referenced profiles.  profiles maps the names of
+// profiles to their capabilities which must not reference other profiles;
+// ProfileNone is always defined and empty.
+func ExpandProfiles(caps []string, profiles map[string][]string) ([]string, error) {
+	var expanded []string
+	for _, c := range caps {
+		if !IsProfile(c) {
+			expanded = append(expanded, c)
+			continue
+		}
+		name := strings.TrimPrefix(c, ProfilePrefix)
+		if name == ProfileNone {
+			continue
+		}
+		profile, ok := profiles[name]
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownProfile, "%q", name)
+		}
+		for _, pc := range profile {
+			if IsProfile(pc) {
+				return nil, errors.Errorf("capability profile %q must not reference profile %q", name, pc)
+			}
+		}
+		expanded = append(expanded, profile...)
+	}
+	return expanded, nil
+}
+
+// ValidateProfiles validates that the profiles only contain known
+// capabilities and do not redefine ProfileNone.
+func ValidateProfiles(profiles map[string][]string) error {
+	for name, caps := range profiles {
+		if name == "" {
+			return errors.New("capability profile names must not be empty")
+		}
+		if name == ProfileNone {
+			return errors.Errorf("capability profile %q cannot be redefined", ProfileNone)
+		}
+		for _, c := range caps {
+			if IsProfile(c) {
+				return errors.Errorf("capability profile %q must not reference profile %q", name, c)
+			}
+		}
+		if _, err := NormalizeCapabilities(caps); err != nil {
+			return errors.Wrapf(err, "capability profile %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package capabilities
+
+import (
+	"strings"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// Union returns the capabilities contained in any of the sets, in the order
+// of their first occurrence and without duplicates.
+func Union(sets ...[]string) []string {
+	var union []string
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		for _, c := range set {
+			if !seen[c] {
+				seen[c] = true
+				union = append(union, c)
+			}
+		}
+	}
+	return union
+}
+
+// Subtract returns the capabilities of caps which are not in drops, without
+// duplicates.
+func Subtract(caps, drops []string) []string {
+	var result []string
+	for _, c := range Union(caps) {
+		if !stringInSlice(c, drops) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Intersect returns the capabilities of a which are also in b, without
+// duplicates.
+func Intersect(a, b []string) []string {
+	var result []string
+	for _, c := range Union(a) {
+		if stringInSlice(c, b) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// ExpandCapabilities normalizes caps and replaces All by all capabilities
+// supported by the kernel.  The result contains no duplicates.
+func ExpandCapabilities(caps []string) ([]string, error) {
+	normalized, err := NormalizeCapabilities(caps)
+	if err != nil {
+		return nil, err
+	}
+	if stringInSlice(All, normalized) {
+		return Union(AllCapabilities()), nil
+	}
+	return Union(normalized), nil
+}
+
+// ValidateKernelSupport validates that caps only contains capabilities
+// supported by the running kernel.  Capabilities that are known but not
+// supported are reported with ErrUnsupportedCapability, those that are not
+// known at all with ErrUnknownCapability.
+func ValidateKernelSupport(caps []string) error {
+	for _, c := range caps {
+		name := strings.ToUpper(strings.TrimSpace(c))
+		if name == All {
+			continue
+		}
+		if !strings.HasPrefix(name, "CAP_") {
+			name = "CAP_" + name
+		}
+		if stringInSlice(name, capabilityList) {
+			continue
+		}
+		if stringInSlice(name, knownCapabilityList) {
+			return errors.Wrapf(ErrUnsupportedCapability, "%q", name)
+		}
+		return errors.Wrapf(ErrUnknownCapability, "%q", name)
+	}
+	return nil
+}
+
+// SpecCapabilities merges adds and drops into base (see MergeCapabilities)
+// and returns the result as OCI capabilities, with the bounding, effective
+// and permitted sets set to the merged capabilities.
+func SpecCapabilities(base, adds, drops []string) (*specs.LinuxCapabilities, error) {
+	caps, err := MergeCapabilities(base, adds, drops)
+	if err != nil {
+		return nil, err
+	}
+	caps, err = ExpandCapabilities(caps)
+	if err != nil {
+		return nil, err
+	}
+	return &specs.LinuxCapabilities{
+		Bounding:  caps,
+		Effective: caps,
+		Permitted: caps,
+	}, nil
+}
@@ -0,0 +1,295 @@
+package cgroups
+
+import (
+	"bufio"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containers/common/pkg/cgroupv2"
+	"github.com/pkg/errors"
+)
+
+var (
+	// cgroupRoot is the mount point of the unified hierarchy.
+	cgroupRoot = "/sys/fs/cgroup"
+	// cgroup2Enabled is overridden in tests.
+	cgroup2Enabled = cgroupv2.Enabled
+)
+
+// ErrCgroupV1 is returned by operations which are only supported on cgroup
+// v2.
+var ErrCgroupV1 = errors.New("this operation requires cgroup v2")
+
+// CgroupControl controls a cgroup of the unified hierarchy.
+type CgroupControl struct {
+	// path of the cgroup relative to the root of the hierarchy.
+	path string
+	// systemd is set if the cgroup is managed by systemd, in which case
+	// limits are set through the unit properties.
+	systemd bool
+}
+
+// Load returns a handle for the existing cgroup at the specified path,
+// relative to the root of the unified hierarchy (e.g.,
+// "/machine.slice/libpod-1234.scope").
+func Load(path string) (*CgroupControl, error) {
+	enabled, err := cgroup2Enabled()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrCgroupV1
+	}
+	c := &CgroupControl{path: filepath.Clean("/" + path)}
+	if _, err := os.Stat(c.fullPath()); err != nil {
+		return nil, errors.Wrapf(err, "loading cgroup %s", path)
+	}
+	return c, nil
+}
+
+// LoadSystemd returns a handle for the existing cgroup at the specified path
+// which is managed by systemd. The last element of the path must be the name
+// of the unit (e.g., "/machine.slice/libpod-1234.scope").
+func LoadSystemd(path string) (*CgroupControl, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	c.systemd = true
+	return c, nil
+}
+
+// Path returns the path of the cgroup relative to the root of the unified
+// hierarchy.
+func (c *CgroupControl) Path() string {
+	return c.path
+}
+
+func (c *CgroupControl) fullPath() string {
+	return filepath.Join(cgroupRoot, c.path)
+}
+
+// Stat returns the metrics of the cgroup. Metrics of controllers which are
+// not enabled for the cgroup are nil.
+func (c *CgroupControl) Stat() (*Metrics, error) {
+	return stat(c.fullPath())
+}
+
+func stat(dir string) (*Metrics, error) {
+	m := &Metrics{}
+	var err error
+	if m.CPU, err = statCPU(dir); err != nil {
+		return nil, err
+	}
+	if m.Memory, err = statMemory(dir); err != nil {
+		return nil, err
+	}
+	if m.Pids, err = statPids(dir); err != nil {
+		return nil, err
+	}
+	if m.IO, err = statIO(dir); err != nil {
+		return nil, err
+	}
+	if m.Misc, err = statMisc(dir); err != nil {
+		return nil, err
+	}
+	if m.RDMA, err = statRDMA(dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func statCPU(dir string) (*CPUMetrics, error) {
+	values, err := readKeyValues(filepath.Join(dir, "cpu.stat"))
+	if err != nil || values == nil {
+		return nil, err
+	}
+	cpu := &CPUMetrics{
+		UsageUsec:     values["usage_usec"],
+		UserUsec:      values["user_usec"],
+		SystemUsec:    values["system_usec"],
+		NrPeriods:     values["nr_periods"],
+		NrThrottled:   values["nr_throttled"],
+		ThrottledUsec: values["throttled_usec"],
+	}
+	if cpu.PSI, err = readPSI(filepath.Join(dir, "cpu.pressure")); err != nil {
+		return nil, err
+	}
+	return cpu, nil
+}
+
+func statMemory(dir string) (*MemoryMetrics, error) {
+	usage, err := readUint(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	mem := &MemoryMetrics{Usage: usage}
+	if mem.Limit, err = readUint(filepath.Join(dir, "memory.max")); err != nil {
+		return nil, err
+	}
+	// The swap files don't exist if swap accounting is disabled.
+	if mem.SwapUsage, err = readUint(filepath.Join(dir, "memory.swap.current")); err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, err
+	}
+	if mem.SwapLimit, err = readUint(filepath.Join(dir, "memory.swap.max")); err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, err
+	}
+	if mem.PSI, err = readPSI(filepath.Join(dir, "memory.pressure")); err != nil {
+		return nil, err
+	}
+	return mem, nil
+}
+
+func statPids(dir string) (*PidsMetrics, error) {
+	current, err := readUint(filepath.Join(dir, "pids.current"))
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	pids := &PidsMetrics{Current: current}
+	if pids.Limit, err = readUint(filepath.Join(dir, "pids.max")); err != nil {
+		return nil, err
+	}
+	return pids, nil
+}
+
+func statIO(dir string) (*IOMetrics, error) {
+	devices, err := readIOStat(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return nil, err
+	}
+	psi, err := readPSI(filepath.Join(dir, "io.pressure"))
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil && psi == nil {
+		return nil, nil
+	}
+	return &IOMetrics{Devices: devices, PSI: psi}, nil
+}
+
+// writeFile writes value to the specified control file of the cgroup.
+func (c *CgroupControl) writeFile(name, value string) error {
+	path := filepath.Join(c.fullPath(), name)
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return errors.Wrapf(err, "writing %q to %s", value, path)
+	}
+	return nil
+}
+
+// readUint reads a single unsigned integer from the specified file. "max"
+// is returned as math.MaxUint64.
+func readUint(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return parseUint(strings.TrimSpace(string(data)), path)
+}
+
+func parseUint(value, path string) (uint64, error) {
+	if value == "max" {
+		return math.MaxUint64, nil
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing %s", path)
+	}
+	return v, nil
+}
+
+// readKeyValues reads a flat keyed file (e.g., cpu.stat). A nil map is
+// returned if the file does not exist.
+func readKeyValues(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := parseUint(fields[1], path)
+		if err != nil {
+			return nil, err
+		}
+		values[fields[0]] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	return values, nil
+}
+
+// readPSI reads a pressure file (e.g., cpu.pressure). nil is returned if
+// the file does not exist which is the case if the kernel has been built
+// without PSI support or if it is disabled.
+func readPSI(path string) (*PSIStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	psi := &PSIStats{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var data *PSIData
+		switch fields[0] {
+		case "some":
+			data = &psi.Some
+		case "full":
+			data = &psi.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("parsing %s: invalid field %q", path, field)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				data.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				data.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				data.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				data.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing %s", path)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	return psi, nil
+}
@@ -0,0 +1,420 @@
+package cgroups
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containers/common/pkg/sysinfo"
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+// setupCgroupRoot creates a fake unified hierarchy and returns the path of
+// the cgroup "/test.slice" inside it.
+func setupCgroupRoot(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "cgroups")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	oldRoot, oldEnabled := cgroupRoot, cgroup2Enabled
+	cgroupRoot = root
+	cgroup2Enabled = func() (bool, error) { return true, nil }
+	t.Cleanup(func() { cgroupRoot, cgroup2Enabled = oldRoot, oldEnabled })
+
+	dir := filepath.Join(root, "test.slice")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	return dir
+}
+
+func TestStat(t *testing.T) {
+	setupCgroupRoot(t, map[string]string{
+		"cpu.stat":        "usage_usec 100\nuser_usec 60\nsystem_usec 40\nnr_periods 5\nnr_throttled 1\nthrottled_usec 20\n",
+		"cpu.pressure":    "some avg10=1.50 avg60=0.25 avg300=0.00 total=12345\n",
+		"memory.current":  "4096\n",
+		"memory.max":      "max\n",
+		"memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=1\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=2\n",
+		"pids.current":    "3\n",
+		"pids.max":        "100\n",
+		"io.pressure":     "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+	})
+
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+	require.Equal(t, "/test.slice", c.Path())
+
+	m, err := c.Stat()
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), m.CPU.UsageUsec)
+	require.Equal(t, uint64(20), m.CPU.ThrottledUsec)
+	require.Equal(t, &PSIStats{Some: PSIData{Avg10: 1.5, Avg60: 0.25, Total: 12345}}, m.CPU.PSI)
+	require.Equal(t, uint64(4096), m.Memory.Usage)
+	require.Equal(t, uint64(math.MaxUint64), m.Memory.Limit)
+	require.Equal(t, uint64(2), m.Memory.PSI.Full.Total)
+	require.Equal(t, &PidsMetrics{Current: 3, Limit: 100}, m.Pids)
+	require.NotNil(t, m.IO.PSI)
+
+	_, err = Load("does-not-exist")
+	require.Error(t, err)
+}
+
+func TestStatWithoutControllers(t *testing.T) {
+	setupCgroupRoot(t, map[string]string{
+		"cpu.stat": "usage_usec 100\n",
+	})
+
+	c, err := Load("/test.slice")
+	require.NoError(t, err)
+	m, err := c.Stat()
+	require.NoError(t, err)
+	require.Nil(t, m.CPU.PSI)
+	require.Nil(t, m.Memory)
+	require.Nil(t, m.Pids)
+	require.Nil(t, m.IO)
+}
+
+func TestIO(t *testing.T) {
+	dir := setupCgroupRoot(t, map[string]string{
+		"io.stat": "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n253:1 rbytes=10 wbytes=20 rios=3 wios=4 dbytes=5 dios=6\n",
+	})
+
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+	m, err := c.Stat()
+	require.NoError(t, err)
+	require.Nil(t, m.IO.PSI)
+	require.Equal(t, []IODeviceMetrics{
+		{Major: 8, Minor: 0, RBytes: 1024, WBytes: 2048, RIOs: 1, WIOs: 2},
+		{Major: 253, Minor: 1, RBytes: 10, WBytes: 20, RIOs: 3, WIOs: 4, DBytes: 5, DIOs: 6},
+	}, m.IO.Devices)
+
+	err = c.SetIO(&IOResources{
+		DeviceLimits: []IODeviceLimit{{IODevice: IODevice{Major: 8, Minor: 0}, ReadBps: 1048576, WriteIOPS: 100}},
+	})
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "io.max"))
+	require.NoError(t, err)
+	require.Equal(t, "8:0 rbps=1048576 wbps=max riops=max wiops=100", string(data))
+
+	require.NoError(t, c.SetIO(&IOResources{Weight: 500}))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "io.weight"))
+	require.NoError(t, err)
+	require.Equal(t, "default 500", string(data))
+
+	require.Error(t, c.SetIO(&IOResources{Weight: 10001}))
+	require.Error(t, c.SetIO(&IOResources{DeviceWeights: []IODeviceWeight{{IODevice: IODevice{Major: 8}}}}))
+}
+
+func TestIOSystemdProperties(t *testing.T) {
+	props, err := ioSystemdProperties(&IOResources{
+		Weight:       100,
+		DeviceLimits: []IODeviceLimit{{IODevice: IODevice{Path: "/dev/sda"}, ReadBps: 1024}},
+	})
+	require.NoError(t, err)
+	names := []string{}
+	for _, p := range props {
+		names = append(names, p.Name)
+	}
+	require.Equal(t, []string{"IOWeight", "IOReadBandwidthMax", "IOWriteBandwidthMax", "IOReadIOPSMax", "IOWriteIOPSMax"}, names)
+
+	_, err = ioSystemdProperties(&IOResources{
+		DeviceLimits: []IODeviceLimit{{IODevice: IODevice{Major: 8}, ReadBps: 1024}},
+	})
+	require.Error(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	dir := setupCgroupRoot(t, nil)
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+
+	shares, quota, period := uint64(1024), int64(50000), uint64(100000)
+	limit, swap, pids := int64(1<<30), int64(2<<30), int64(-1)
+	weight := uint16(500)
+	throttle := spec.LinuxThrottleDevice{Rate: 1024}
+	throttle.Major, throttle.Minor = 8, 0
+	err = c.Update(&spec.LinuxResources{
+		CPU:    &spec.LinuxCPU{Shares: &shares, Quota: &quota, Period: &period},
+		Memory: &spec.LinuxMemory{Limit: &limit, Swap: &swap},
+		Pids:   &spec.LinuxPids{Limit: pids},
+		BlockIO: &spec.LinuxBlockIO{
+			Weight:                &weight,
+			ThrottleReadBpsDevice: []spec.LinuxThrottleDevice{throttle},
+		},
+	})
+	require.NoError(t, err)
+
+	for file, expected := range map[string]string{
+		"cpu.weight":      "39",
+		"cpu.max":         "50000 100000",
+		"memory.max":      "1073741824",
+		"memory.swap.max": "1073741824",
+		"pids.max":        "max",
+		"io.weight":       "default 4950",
+		"io.max":          "8:0 rbps=1024 wbps=max riops=max wiops=max",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data), file)
+	}
+
+	swap = 1 << 20
+	require.Error(t, c.Update(&spec.LinuxResources{Memory: &spec.LinuxMemory{Limit: &limit, Swap: &swap}}))
+}
+
+func TestSystemdResourceProperties(t *testing.T) {
+	quota, limit := int64(50000), int64(1<<30)
+	props, err := systemdResourceProperties(&spec.LinuxResources{
+		CPU:    &spec.LinuxCPU{Quota: &quota},
+		Memory: &spec.LinuxMemory{Limit: &limit},
+		Pids:   &spec.LinuxPids{Limit: 100},
+	})
+	require.NoError(t, err)
+	values := make(map[string]interface{})
+	for _, p := range props {
+		values[p.Name] = p.Value.Value()
+	}
+	require.Equal(t, map[string]interface{}{
+		"CPUQuotaPerSecUSec": uint64(500000),
+		"MemoryMax":          uint64(1 << 30),
+		"TasksMax":           uint64(100),
+	}, values)
+}
+
+func TestWatch(t *testing.T) {
+	dir := setupCgroupRoot(t, map[string]string{
+		"cgroup.events": "populated 1\nfrozen 0\n",
+		"memory.events": "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n",
+	})
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := c.Watch(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "memory.events"), []byte("low 0\nhigh 0\nmax 1\noom 1\noom_kill 1\n"), 0644))
+	e := <-ch
+	require.Equal(t, EventOOM, e.Type)
+	e = <-ch
+	require.Equal(t, EventOOMKill, e.Type)
+	require.Equal(t, uint64(1), e.Count)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 0\nfrozen 1\n"), 0644))
+	e = <-ch
+	require.Equal(t, EventUnpopulated, e.Type)
+	e = <-ch
+	require.Equal(t, EventFrozen, e.Type)
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestStatChildren(t *testing.T) {
+	setupCgroupRoot(t, map[string]string{
+		"memory.current":         "300\n",
+		"memory.max":             "max\n",
+		"a.scope/memory.current": "100\n",
+		"a.scope/memory.max":     "1000\n",
+		"a.scope/pids.current":   "1\n",
+		"a.scope/pids.max":       "max\n",
+		"a.scope/io.stat":        "8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0\n",
+		"b.scope/memory.current": "200\n",
+		"b.scope/memory.max":     "max\n",
+		"b.scope/io.stat":        "8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0\n8:16 rbytes=5 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n",
+	})
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+
+	sub, err := c.StatChildren()
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), sub.Self.Memory.Usage)
+	require.Len(t, sub.Children, 2)
+	require.Equal(t, uint64(1000), sub.Children["a.scope"].Memory.Limit)
+	require.Nil(t, sub.Children["b.scope"].Pids)
+	require.Equal(t, &MemoryMetrics{Usage: 300}, sub.Aggregate.Memory)
+	require.Equal(t, &PidsMetrics{Current: 1}, sub.Aggregate.Pids)
+	require.Equal(t, []IODeviceMetrics{
+		{Major: 8, Minor: 0, RBytes: 2, WBytes: 4, RIOs: 6, WIOs: 8},
+		{Major: 8, Minor: 16, RBytes: 5, RIOs: 1},
+	}, sub.Aggregate.IO.Devices)
+}
+
+func TestFreezer(t *testing.T) {
+	dir := setupCgroupRoot(t, map[string]string{
+		"cgroup.freeze": "0\n",
+		"cgroup.events": "populated 1\nfrozen 0\n",
+	})
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+
+	state, err := c.FreezerState()
+	require.NoError(t, err)
+	require.Equal(t, Thawed, state)
+
+	// Nothing updates cgroup.events, so freezing times out and rolls back.
+	state, err = c.Freeze(20 * time.Millisecond)
+	require.Error(t, err)
+	require.Equal(t, Thawed, state)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.freeze"))
+	require.NoError(t, err)
+	require.Equal(t, "0", string(data))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 1\nfrozen 1\n"), 0644))
+	state, err = c.Freeze(time.Second)
+	require.NoError(t, err)
+	require.Equal(t, Frozen, state)
+
+	state, err = c.Thaw(time.Second)
+	require.NoError(t, err)
+	require.Equal(t, Thawed, state)
+}
+
+func TestCpuset(t *testing.T) {
+	dir := setupCgroupRoot(t, map[string]string{
+		"cpuset.cpus.effective": "0-7\n",
+		"cpuset.mems.effective": "0-1\n",
+		"libpod.scope/.keep":    "",
+	})
+	oldOnline, oldNodes := onlineCPUs, numaNodes
+	onlineCPUs = func() (string, error) { return "0-7", nil }
+	numaNodes = func() ([]sysinfo.NUMANode, error) {
+		return []sysinfo.NUMANode{{ID: 0, CPUs: "0-3"}, {ID: 1, CPUs: "4-7"}}, nil
+	}
+	t.Cleanup(func() { onlineCPUs, numaNodes = oldOnline, oldNodes })
+
+	require.NoError(t, ValidateCpuset("0-2,7", "1"))
+	require.Error(t, ValidateCpuset("8", ""))
+	require.Error(t, ValidateCpuset("", "2"))
+	require.Error(t, ValidateCpuset("a-b", ""))
+
+	cpus, err := NUMANodeCPUs("1")
+	require.NoError(t, err)
+	require.Equal(t, "4-7", cpus)
+	_, err = NUMANodeCPUs("3")
+	require.Error(t, err)
+
+	c, err := Load("test.slice/libpod.scope")
+	require.NoError(t, err)
+	// The controller is not enabled for the scope, the effective sets are
+	// inherited from the parent.
+	cpus, mems, err := c.EffectiveCpuset()
+	require.NoError(t, err)
+	require.Equal(t, "0-7", cpus)
+	require.Equal(t, "0-1", mems)
+
+	require.NoError(t, c.SetCpuset("1-3", "0"))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "libpod.scope", "cpuset.cpus"))
+	require.NoError(t, err)
+	require.Equal(t, "1-3", string(data))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cpuset.cpus.effective"), []byte("0-3\n"), 0644))
+	require.Error(t, c.SetCpuset("4", ""))
+}
+
+func TestCpusetHelpers(t *testing.T) {
+	require.Equal(t, "0-3,5,7-8", formatCpuset(map[int]bool{0: true, 1: true, 2: true, 3: true, 5: true, 7: true, 8: true}))
+	require.Equal(t, "", formatCpuset(map[int]bool{}))
+	require.Equal(t, []byte{0x0f, 0x01}, cpusetBitmask(map[int]bool{0: true, 1: true, 2: true, 3: true, 8: true}))
+}
+
+func TestTransientUnitProperties(t *testing.T) {
+	limit := int64(1024)
+	props, err := transientUnitProperties("/machine.slice/libpod-1234.scope", 42,
+		&spec.LinuxResources{Memory: &spec.LinuxMemory{Limit: &limit}},
+		&SystemdOptions{Properties: []systemdDbus.Property{
+			newProperty("MemoryMax", uint64(2048)),
+			newProperty("CPUQuotaPeriodUSec", uint64(10000)),
+		}})
+	require.NoError(t, err)
+
+	values := make(map[string]interface{})
+	for _, p := range props {
+		values[p.Name] = p.Value.Value()
+	}
+	require.Equal(t, "machine.slice", values["Slice"])
+	require.Equal(t, []uint32{42}, values["PIDs"])
+	require.Equal(t, true, values["Delegate"])
+	require.Equal(t, uint64(2048), values["MemoryMax"])
+	require.Equal(t, uint64(10000), values["CPUQuotaPeriodUSec"])
+
+	props, err = transientUnitProperties("/user.slice/test.slice", 0, nil, &SystemdOptions{Slice: "other.slice"})
+	require.NoError(t, err)
+	for _, p := range props {
+		require.NotEqual(t, "Slice", p.Name)
+	}
+
+	_, err = transientUnitProperties("/machine.slice/libpod.scope", 0, nil, nil)
+	require.Error(t, err)
+	_, err = transientUnitProperties("/machine.slice/libpod", 42, nil, nil)
+	require.Error(t, err)
+}
+
+func TestDetectDelegation(t *testing.T) {
+	root := filepath.Join(setupCgroupRoot(t, nil), "..")
+	user := filepath.Join(root, "user.slice", "user-1000.slice", "user@1000.service")
+	require.NoError(t, os.MkdirAll(filepath.Join(user, "app.slice", "podman.scope"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(user, "cgroup.controllers"), []byte("memory pids\n"), 0644))
+
+	procFile := filepath.Join(root, "proc-cgroup")
+	require.NoError(t, ioutil.WriteFile(procFile, []byte("0::/user.slice/user-1000.slice/user@1000.service/app.slice/podman.scope\n"), 0644))
+	oldProc := procSelfCgroup
+	procSelfCgroup = procFile
+	t.Cleanup(func() { procSelfCgroup = oldProc })
+
+	report, err := DetectDelegation()
+	require.NoError(t, err)
+	require.Equal(t, "/user.slice/user-1000.slice/user@1000.service", report.Root)
+	require.Equal(t, []string{"memory", "pids"}, report.Controllers)
+	require.Equal(t, []string{"cpu", "cpuset", "io"}, report.Missing)
+	require.True(t, report.Delegated("memory"))
+	require.False(t, report.Delegated("cpu"))
+	require.NotEmpty(t, report.Guidance())
+
+	require.Equal(t, "/system.slice/foo.service", delegationRoot("/system.slice/foo.service"))
+}
+
+func TestMiscAndRDMA(t *testing.T) {
+	dir := setupCgroupRoot(t, map[string]string{
+		"misc.current": "sev 1\nsev_es 2\n",
+		"misc.max":     "sev max\nsev_es 4\n",
+		"rdma.current": "mlx4_0 hca_handle=1 hca_object=20\n",
+		"rdma.max":     "mlx4_0 hca_handle=2 hca_object=max\n",
+	})
+	c, err := Load("test.slice")
+	require.NoError(t, err)
+
+	m, err := c.Stat()
+	require.NoError(t, err)
+	require.Equal(t, map[string]MiscMetrics{
+		"sev":    {Current: 1, Limit: math.MaxUint64},
+		"sev_es": {Current: 2, Limit: 4},
+	}, m.Misc)
+	require.Equal(t, map[string]RDMAMetrics{
+		"mlx4_0": {HCAHandles: 1, HCAHandlesLimit: 2, HCAObjects: 20, HCAObjectsLimit: math.MaxUint64},
+	}, m.RDMA)
+
+	require.NoError(t, c.SetMisc(map[string]uint64{"sev_es": 8}))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "misc.max"))
+	require.NoError(t, err)
+	require.Equal(t, "sev_es 8", string(data))
+	require.Error(t, c.SetMisc(map[string]uint64{"sev es": 1}))
+
+	handles := uint32(4)
+	require.NoError(t, c.Update(&spec.LinuxResources{Rdma: map[string]spec.LinuxRdma{"mlx4_0": {HcaHandles: &handles}}}))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "rdma.max"))
+	require.NoError(t, err)
+	require.Equal(t, "mlx4_0 hca_handle=4 hca_object=max", string(data))
+}
@@ -0,0 +1,270 @@
+package cgroups
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containers/common/pkg/sysinfo"
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/pkg/errors"
+)
+
+var (
+	// onlineCPUs and numaNodes are overridden in tests.
+	onlineCPUs = sysinfo.OnlineCPUs
+	numaNodes  = sysinfo.NUMANodes
+)
+
+// ValidateCpuset checks that cpus and mems are valid cpuset lists (e.g.,
+// "0-3,8") of online CPUs and NUMA memory nodes. Empty lists are ignored.
+func ValidateCpuset(cpus, mems string) error {
+	if cpus != "" {
+		set, err := parseCpuset(cpus)
+		if err != nil {
+			return err
+		}
+		online, err := onlineCPUs()
+		if err != nil {
+			return errors.Wrap(err, "reading online CPUs")
+		}
+		if err := checkSubset(set, online, "CPUs", "online CPUs"); err != nil {
+			return err
+		}
+	}
+	if mems != "" {
+		set, err := parseCpuset(mems)
+		if err != nil {
+			return err
+		}
+		nodes, err := numaNodes()
+		if err != nil {
+			return errors.Wrap(err, "reading NUMA nodes")
+		}
+		ids := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			ids = append(ids, strconv.Itoa(node.ID))
+		}
+		if err := checkSubset(set, strings.Join(ids, ","), "memory nodes", "online NUMA nodes"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NUMANodeCPUs returns the CPUs local to the specified memory nodes, which
+// can be used to keep the CPUs and the memory of a container on the same
+// NUMA nodes.
+func NUMANodeCPUs(mems string) (string, error) {
+	set, err := parseCpuset(mems)
+	if err != nil {
+		return "", err
+	}
+	nodes, err := numaNodes()
+	if err != nil {
+		return "", errors.Wrap(err, "reading NUMA nodes")
+	}
+	cpus := make(map[int]bool)
+	for _, node := range nodes {
+		if !set[node.ID] {
+			continue
+		}
+		delete(set, node.ID)
+		nodeCPUs, err := parseCpuset(node.CPUs)
+		if err != nil {
+			return "", err
+		}
+		for cpu := range nodeCPUs {
+			cpus[cpu] = true
+		}
+	}
+	if len(set) > 0 {
+		return "", errors.Errorf("memory nodes %s are not online", formatCpuset(set))
+	}
+	return formatCpuset(cpus), nil
+}
+
+// EffectiveCpuset returns the CPUs and memory nodes the processes of the
+// cgroup may use. If the cpuset controller is not enabled for the cgroup,
+// e.g. because it is not delegated by systemd, the effective sets of the
+// closest ancestor with the controller enabled are returned.
+func (c *CgroupControl) EffectiveCpuset() (string, string, error) {
+	return effectiveCpuset(c.path)
+}
+
+func effectiveCpuset(path string) (string, string, error) {
+	for {
+		dir := filepath.Join(cgroupRoot, path)
+		cpus, err := ioutil.ReadFile(filepath.Join(dir, "cpuset.cpus.effective"))
+		if err == nil {
+			mems, err := ioutil.ReadFile(filepath.Join(dir, "cpuset.mems.effective"))
+			if err != nil {
+				return "", "", errors.Wrapf(err, "reading effective memory nodes of cgroup %s", path)
+			}
+			return strings.TrimSpace(string(cpus)), strings.TrimSpace(string(mems)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", errors.Wrapf(err, "reading effective CPUs of cgroup %s", path)
+		}
+		if path == "/" {
+			// The root cgroup has no cpuset files, all online
+			// resources are available.
+			return rootCpuset()
+		}
+		path = filepath.Dir(path)
+	}
+}
+
+func rootCpuset() (string, string, error) {
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return "", "", errors.Wrap(err, "reading online CPUs")
+	}
+	nodes, err := numaNodes()
+	if err != nil {
+		return "", "", errors.Wrap(err, "reading NUMA nodes")
+	}
+	mems := make(map[int]bool, len(nodes))
+	for _, node := range nodes {
+		mems[node.ID] = true
+	}
+	return cpus, formatCpuset(mems), nil
+}
+
+// SetCpuset restricts the cgroup to the specified CPUs and memory nodes. The
+// sets must be available in the parent cgroup. Empty lists are left
+// unchanged.
+func (c *CgroupControl) SetCpuset(cpus, mems string) error {
+	if cpus == "" && mems == "" {
+		return nil
+	}
+	if err := ValidateCpuset(cpus, mems); err != nil {
+		return err
+	}
+	parentCPUs, parentMems, err := effectiveCpuset(filepath.Dir(c.path))
+	if err != nil {
+		return err
+	}
+	if cpus != "" {
+		set, _ := parseCpuset(cpus)
+		if err := checkSubset(set, parentCPUs, "CPUs", "CPUs of the parent cgroup"); err != nil {
+			return err
+		}
+	}
+	if mems != "" {
+		set, _ := parseCpuset(mems)
+		if err := checkSubset(set, parentMems, "memory nodes", "memory nodes of the parent cgroup"); err != nil {
+			return err
+		}
+	}
+
+	if c.systemd {
+		props, err := cpusetSystemdProperties(cpus, mems)
+		if err != nil {
+			return err
+		}
+		return c.setSystemdProperties(props)
+	}
+	if cpus != "" {
+		if err := c.writeFile("cpuset.cpus", cpus); err != nil {
+			return err
+		}
+	}
+	if mems != "" {
+		if err := c.writeFile("cpuset.mems", mems); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpusetSystemdProperties returns the AllowedCPUs and AllowedMemoryNodes
+// properties for the cpuset lists.
+func cpusetSystemdProperties(cpus, mems string) ([]systemdDbus.Property, error) {
+	var props []systemdDbus.Property
+	if cpus != "" {
+		set, err := parseCpuset(cpus)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, newProperty("AllowedCPUs", cpusetBitmask(set)))
+	}
+	if mems != "" {
+		set, err := parseCpuset(mems)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, newProperty("AllowedMemoryNodes", cpusetBitmask(set)))
+	}
+	return props, nil
+}
+
+func parseCpuset(list string) (map[int]bool, error) {
+	set, err := parsers.ParseUintList(list)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing cpuset %q", list)
+	}
+	return set, nil
+}
+
+// checkSubset returns an error if set contains elements not in available.
+func checkSubset(set map[int]bool, available, what, where string) error {
+	availableSet, err := parseCpuset(available)
+	if err != nil {
+		return err
+	}
+	missing := make(map[int]bool)
+	for i := range set {
+		if !availableSet[i] {
+			missing[i] = true
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("%s %s are not available: %s are %q", what, formatCpuset(missing), where, available)
+	}
+	return nil
+}
+
+// formatCpuset formats the set as a cpuset list with ranges (e.g., "0-3,8").
+func formatCpuset(set map[int]bool) string {
+	ids := make([]int, 0, len(set))
+	for i := range set {
+		ids = append(ids, i)
+	}
+	sort.Ints(ids)
+
+	var ranges []string
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(ids[i]))
+		} else {
+			ranges = append(ranges, strconv.Itoa(ids[i])+"-"+strconv.Itoa(ids[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// cpusetBitmask converts the set into the byte array used by systemd where
+// bit n of byte n/8 is set for element n.
+func cpusetBitmask(set map[int]bool) []byte {
+	max := -1
+	for i := range set {
+		if i > max {
+			max = i
+		}
+	}
+	mask := make([]byte, max/8+1)
+	for i := range set {
+		mask[i/8] |= 1 << uint(i%8)
+	}
+	return mask
+}
@@ -0,0 +1,151 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containers/storage/pkg/unshare"
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/pkg/errors"
+)
+
+// procSelfCgroup is overridden in tests.
+var procSelfCgroup = "/proc/self/cgroup"
+
+// RequiredControllers are the controllers needed to apply all resource
+// limits to containers.
+var RequiredControllers = []string{"cpu", "cpuset", "io", "memory", "pids"}
+
+// DelegationReport describes which controllers can be used by the current
+// user.
+type DelegationReport struct {
+	// Rootless is set if the report is about an unprivileged user.
+	Rootless bool
+	// Root is the highest cgroup the user can manage, e.g.
+	// "/user.slice/user-1000.slice/user@1000.service" for the systemd
+	// user instance.
+	Root string
+	// Controllers are the controllers available in Root.
+	Controllers []string
+	// Missing are the RequiredControllers not available in Root.
+	Missing []string
+}
+
+// Delegated returns true if the controller is available to the user.
+func (r *DelegationReport) Delegated(controller string) bool {
+	for _, c := range r.Controllers {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// Guidance returns instructions for the administrator on how to delegate the
+// missing controllers, or an empty string if nothing is missing.
+func (r *DelegationReport) Guidance() string {
+	if len(r.Missing) == 0 {
+		return ""
+	}
+	if !r.Rootless {
+		return fmt.Sprintf("enable the %s controllers in cgroup.subtree_control of the parent cgroups of %s", strings.Join(r.Missing, ", "), r.Root)
+	}
+	return fmt.Sprintf(`the %s controllers are not delegated to the user, limits using them are ignored.
+To delegate them to all users, create /etc/systemd/system/user@.service.d/delegate.conf with:
+
+[Service]
+Delegate=%s
+
+and run "systemctl daemon-reload" before logging in again.`, strings.Join(r.Missing, ", "), strings.Join(RequiredControllers, " "))
+}
+
+// DetectDelegation reports which controllers are delegated to the cgroup
+// subtree of the current process, i.e. the systemd user instance for
+// rootless users.
+func DetectDelegation() (*DelegationReport, error) {
+	enabled, err := cgroup2Enabled()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrCgroupV1
+	}
+	path, err := ownCgroup()
+	if err != nil {
+		return nil, err
+	}
+	report := &DelegationReport{
+		Rootless: unshare.IsRootless(),
+		Root:     delegationRoot(path),
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(cgroupRoot, report.Root, "cgroup.controllers"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading controllers of cgroup %s", report.Root)
+	}
+	report.Controllers = strings.Fields(string(data))
+	sort.Strings(report.Controllers)
+	for _, c := range RequiredControllers {
+		if !report.Delegated(c) {
+			report.Missing = append(report.Missing, c)
+		}
+	}
+	return report, nil
+}
+
+// RequestDelegation asks systemd to delegate the controllers to the user
+// instance of the current user until the next reboot. This requires the
+// privileges to change system units, otherwise the Guidance of the
+// DelegationReport must be followed by the administrator. The controllers
+// are only available in newly created cgroups.
+func RequestDelegation(controllers []string) error {
+	conn, err := systemdDbus.NewSystemConnection()
+	if err != nil {
+		return errors.Wrap(err, "connecting to systemd")
+	}
+	defer conn.Close()
+
+	unit := fmt.Sprintf("user@%d.service", unshare.GetRootlessUID())
+	if err := conn.SetUnitProperties(unit, true, newProperty("DelegateControllers", controllers)); err != nil {
+		return errors.Wrapf(err, "delegating controllers %s to unit %s", strings.Join(controllers, ", "), unit)
+	}
+	return nil
+}
+
+// ownCgroup returns the cgroup of the current process on the unified
+// hierarchy.
+func ownCgroup() (string, error) {
+	f, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrapf(err, "reading %s", procSelfCgroup)
+	}
+	return "", errors.Errorf("no cgroup v2 entry in %s", procSelfCgroup)
+}
+
+// delegationRoot returns the user@UID.service ancestor of the cgroup, or
+// the cgroup itself if it is not managed by a systemd user instance.
+func delegationRoot(path string) string {
+	elements := strings.Split(filepath.Clean(path), "/")
+	for i, e := range elements {
+		if strings.HasPrefix(e, "user@") && strings.HasSuffix(e, ".service") {
+			return "/" + filepath.Join(elements[:i+1]...)
+		}
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,158 @@
+package cgroups
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// EventType is the type of a cgroup event.
+type EventType string
+
+const (
+	// EventOOM is reported when the memory limit of the cgroup has been
+	// reached and the OOM killer has been invoked.
+	EventOOM EventType = "oom"
+	// EventOOMKill is reported when a process of the cgroup has been
+	// killed by the OOM killer.
+	EventOOMKill EventType = "oom_kill"
+	// EventPopulated is reported when the first process enters the cgroup
+	// or one of its descendants.
+	EventPopulated EventType = "populated"
+	// EventUnpopulated is reported when the last process of the cgroup and
+	// its descendants exits.
+	EventUnpopulated EventType = "unpopulated"
+	// EventFrozen is reported when the cgroup has been frozen.
+	EventFrozen EventType = "frozen"
+	// EventThawed is reported when the cgroup has been thawed.
+	EventThawed EventType = "thawed"
+)
+
+// Event is a cgroup event reported by Watch.
+type Event struct {
+	Type EventType
+	// Count is the total number of OOM or OOM kill events of the cgroup
+	// so far. It is only set for EventOOM and EventOOMKill.
+	Count uint64
+	// Time the event has been noticed.
+	Time time.Time
+}
+
+// eventState are the values of memory.events and cgroup.events.
+type eventState struct {
+	oom       uint64
+	oomKill   uint64
+	populated uint64
+	frozen    uint64
+}
+
+func (c *CgroupControl) readEventState() (eventState, error) {
+	var state eventState
+	memory, err := readKeyValues(filepath.Join(c.fullPath(), "memory.events"))
+	if err != nil {
+		return state, err
+	}
+	state.oom, state.oomKill = memory["oom"], memory["oom_kill"]
+	events, err := readKeyValues(filepath.Join(c.fullPath(), "cgroup.events"))
+	if err != nil {
+		return state, err
+	}
+	state.populated, state.frozen = events["populated"], events["frozen"]
+	return state, nil
+}
+
+// diffEvents returns the events between the old and the new state.
+func diffEvents(old, new eventState, now time.Time) []Event {
+	var events []Event
+	if new.oom > old.oom {
+		events = append(events, Event{Type: EventOOM, Count: new.oom, Time: now})
+	}
+	if new.oomKill > old.oomKill {
+		events = append(events, Event{Type: EventOOMKill, Count: new.oomKill, Time: now})
+	}
+	if new.populated != old.populated {
+		if new.populated != 0 {
+			events = append(events, Event{Type: EventPopulated, Time: now})
+		} else {
+			events = append(events, Event{Type: EventUnpopulated, Time: now})
+		}
+	}
+	if new.frozen != old.frozen {
+		if new.frozen != 0 {
+			events = append(events, Event{Type: EventFrozen, Time: now})
+		} else {
+			events = append(events, Event{Type: EventThawed, Time: now})
+		}
+	}
+	return events
+}
+
+// Watch watches memory.events and cgroup.events of the cgroup and reports
+// OOM (kill) events and populated and frozen state transitions on the
+// returned channel without polling. The channel is closed when the context
+// is cancelled or when the cgroup is removed.
+func (c *CgroupControl) Watch(ctx context.Context) (<-chan Event, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating inotify watcher")
+	}
+	files := []string{"cgroup.events"}
+	// memory.events does not exist if the memory controller is disabled.
+	if memory, err := readKeyValues(filepath.Join(c.fullPath(), "memory.events")); err == nil && memory != nil {
+		files = append(files, "memory.events")
+	}
+	for _, name := range files {
+		if err := watcher.Add(filepath.Join(c.fullPath(), name)); err != nil {
+			watcher.Close()
+			return nil, errors.Wrapf(err, "watching %s", name)
+		}
+	}
+
+	state, err := c.readEventState()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logrus.Warnf("Error watching events of cgroup %s: %v", c.path, err)
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&fsnotify.Remove != 0 {
+					return
+				}
+				newState, err := c.readEventState()
+				if err != nil {
+					logrus.Warnf("Error reading events of cgroup %s: %v", c.path, err)
+					continue
+				}
+				for _, e := range diffEvents(state, newState, time.Now()) {
+					select {
+					case ch <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+				state = newState
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,101 @@
+package cgroups
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// FreezerState is the state of the freezer of a cgroup.
+type FreezerState string
+
+const (
+	// Thawed means that the processes of the cgroup are running.
+	Thawed FreezerState = "THAWED"
+	// Freezing means that the cgroup has been requested to freeze but not
+	// all of its processes are stopped yet.
+	Freezing FreezerState = "FREEZING"
+	// Frozen means that all processes of the cgroup are stopped.
+	Frozen FreezerState = "FROZEN"
+)
+
+// freezerPollInterval is the interval in which the state of the cgroup is
+// checked while waiting for it to settle.
+const freezerPollInterval = 10 * time.Millisecond
+
+// FreezerState returns the current freezer state of the cgroup.
+func (c *CgroupControl) FreezerState() (FreezerState, error) {
+	data, err := ioutil.ReadFile(filepath.Join(c.fullPath(), "cgroup.freeze"))
+	if err != nil {
+		return "", errors.Wrapf(err, "reading freezer state of cgroup %s", c.path)
+	}
+	events, err := readKeyValues(filepath.Join(c.fullPath(), "cgroup.events"))
+	if err != nil {
+		return "", err
+	}
+	requested := strings.TrimSpace(string(data)) == "1"
+	frozen := events["frozen"] == 1
+	switch {
+	case requested && frozen:
+		return Frozen, nil
+	case requested:
+		return Freezing, nil
+	default:
+		// The kernel thaws the processes immediately.
+		return Thawed, nil
+	}
+}
+
+// Freeze freezes all processes of the cgroup and its descendants and waits up
+// to timeout for them to be stopped. If the cgroup does not settle in time it
+// is thawed again and an error is returned along with the final state.
+func (c *CgroupControl) Freeze(timeout time.Duration) (FreezerState, error) {
+	return c.setFreezer(Frozen, timeout)
+}
+
+// Thaw resumes all processes of the cgroup and its descendants and waits up
+// to timeout for the cgroup to be reported as thawed.
+func (c *CgroupControl) Thaw(timeout time.Duration) (FreezerState, error) {
+	return c.setFreezer(Thawed, timeout)
+}
+
+func (c *CgroupControl) setFreezer(target FreezerState, timeout time.Duration) (FreezerState, error) {
+	value := "0"
+	if target == Frozen {
+		value = "1"
+	}
+	if err := c.writeFile("cgroup.freeze", value); err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := c.FreezerState()
+		if err != nil {
+			return "", err
+		}
+		if state == target {
+			return state, nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(freezerPollInterval)
+	}
+
+	if target == Frozen {
+		// Do not leave the cgroup half frozen.
+		if err := c.writeFile("cgroup.freeze", "0"); err != nil {
+			logrus.Errorf("Unable to thaw cgroup %s after failing to freeze it: %v", c.path, err)
+		}
+	}
+	state, err := c.FreezerState()
+	if err != nil {
+		return "", err
+	}
+	return state, errors.Errorf("timed out after %s waiting for cgroup %s to become %s", timeout, c.path, strings.ToLower(string(target)))
+}
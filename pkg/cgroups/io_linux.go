@@ -0,0 +1,242 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// IOResources are the limits of the io controller.
+type IOResources struct {
+	// Weight is the default weight of the cgroup (1-10000). It is left
+	// unchanged if 0.
+	Weight uint16
+	// DeviceWeights override the weight for specific devices.
+	DeviceWeights []IODeviceWeight
+	// DeviceLimits are the bandwidth and IOPS limits of specific devices.
+	DeviceLimits []IODeviceLimit
+}
+
+// IODevice identifies a block device either by its path or its major and
+// minor numbers. The path is required for systemd-managed cgroups, the
+// numbers are looked up from the path if unset.
+type IODevice struct {
+	Path  string
+	Major int64
+	Minor int64
+}
+
+// IODeviceWeight is the weight (1-10000) of a specific device.
+type IODeviceWeight struct {
+	IODevice
+	Weight uint16
+}
+
+// IODeviceLimit are the limits of a specific device. A limit of 0 removes
+// the respective limit.
+type IODeviceLimit struct {
+	IODevice
+	ReadBps   uint64
+	WriteBps  uint64
+	ReadIOPS  uint64
+	WriteIOPS uint64
+}
+
+// SetIO applies the specified io controller limits to the cgroup, either by
+// writing io.weight and io.max or through the properties of the systemd
+// unit.
+func (c *CgroupControl) SetIO(resources *IOResources) error {
+	if err := validateIOResources(resources); err != nil {
+		return err
+	}
+	if c.systemd {
+		props, err := ioSystemdProperties(resources)
+		if err != nil {
+			return err
+		}
+		return c.setSystemdProperties(props)
+	}
+
+	if resources.Weight != 0 {
+		if err := c.writeFile("io.weight", fmt.Sprintf("default %d", resources.Weight)); err != nil {
+			return err
+		}
+	}
+	for _, w := range resources.DeviceWeights {
+		major, minor, err := w.numbers()
+		if err != nil {
+			return err
+		}
+		if err := c.writeFile("io.weight", fmt.Sprintf("%d:%d %d", major, minor, w.Weight)); err != nil {
+			return err
+		}
+	}
+	for _, l := range resources.DeviceLimits {
+		major, minor, err := l.numbers()
+		if err != nil {
+			return err
+		}
+		value := fmt.Sprintf("%d:%d rbps=%s wbps=%s riops=%s wiops=%s", major, minor,
+			ioLimit(l.ReadBps), ioLimit(l.WriteBps), ioLimit(l.ReadIOPS), ioLimit(l.WriteIOPS))
+		if err := c.writeFile("io.max", value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateIOResources(resources *IOResources) error {
+	if resources == nil {
+		return errors.New("no io resources specified")
+	}
+	if resources.Weight > 10000 {
+		return errors.Errorf("invalid io weight %d: must be between 1 and 10000", resources.Weight)
+	}
+	for _, w := range resources.DeviceWeights {
+		if w.Weight < 1 || w.Weight > 10000 {
+			return errors.Errorf("invalid io weight %d for device %s: must be between 1 and 10000", w.Weight, w.String())
+		}
+	}
+	return nil
+}
+
+// ioSystemdProperties converts the resources into the IO properties of a
+// systemd unit.
+func ioSystemdProperties(resources *IOResources) ([]systemdDbus.Property, error) {
+	type deviceValue struct {
+		Path  string
+		Value uint64
+	}
+	var props []systemdDbus.Property
+	if resources.Weight != 0 {
+		props = append(props, newProperty("IOWeight", uint64(resources.Weight)))
+	}
+
+	var weights []deviceValue
+	for _, w := range resources.DeviceWeights {
+		if w.Path == "" {
+			return nil, errors.Errorf("device %s: the device path is required for systemd-managed cgroups", w.String())
+		}
+		weights = append(weights, deviceValue{w.Path, uint64(w.Weight)})
+	}
+	if len(weights) > 0 {
+		props = append(props, newProperty("IODeviceWeight", weights))
+	}
+
+	var rbps, wbps, riops, wiops []deviceValue
+	for _, l := range resources.DeviceLimits {
+		if l.Path == "" {
+			return nil, errors.Errorf("device %s: the device path is required for systemd-managed cgroups", l.String())
+		}
+		// systemd removes a limit if it is set to the maximum value.
+		rbps = append(rbps, deviceValue{l.Path, ioSystemdLimit(l.ReadBps)})
+		wbps = append(wbps, deviceValue{l.Path, ioSystemdLimit(l.WriteBps)})
+		riops = append(riops, deviceValue{l.Path, ioSystemdLimit(l.ReadIOPS)})
+		wiops = append(wiops, deviceValue{l.Path, ioSystemdLimit(l.WriteIOPS)})
+	}
+	if len(resources.DeviceLimits) > 0 {
+		props = append(props,
+			newProperty("IOReadBandwidthMax", rbps),
+			newProperty("IOWriteBandwidthMax", wbps),
+			newProperty("IOReadIOPSMax", riops),
+			newProperty("IOWriteIOPSMax", wiops),
+		)
+	}
+	return props, nil
+}
+
+func ioLimit(limit uint64) string {
+	if limit == 0 {
+		return "max"
+	}
+	return strconv.FormatUint(limit, 10)
+}
+
+func ioSystemdLimit(limit uint64) uint64 {
+	if limit == 0 {
+		return unlimited
+	}
+	return limit
+}
+
+func (d *IODevice) String() string {
+	if d.Path != "" {
+		return d.Path
+	}
+	return fmt.Sprintf("%d:%d", d.Major, d.Minor)
+}
+
+// numbers returns the major and minor numbers of the device.
+func (d *IODevice) numbers() (int64, int64, error) {
+	if d.Major != 0 || d.Minor != 0 || d.Path == "" {
+		return d.Major, d.Minor, nil
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(d.Path, &st); err != nil {
+		return 0, 0, errors.Wrapf(err, "stat device %s", d.Path)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return 0, 0, errors.Errorf("%s is not a block device", d.Path)
+	}
+	return int64(unix.Major(uint64(st.Rdev))), int64(unix.Minor(uint64(st.Rdev))), nil //nolint:unconvert
+}
+
+// readIOStat parses io.stat. nil is returned if the file does not exist.
+func readIOStat(path string) ([]IODeviceMetrics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := []IODeviceMetrics{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var dev IODeviceMetrics
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &dev.Major, &dev.Minor); err != nil {
+			return nil, errors.Wrapf(err, "parsing %s: invalid device %q", path, fields[0])
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing %s", path)
+			}
+			switch kv[0] {
+			case "rbytes":
+				dev.RBytes = v
+			case "wbytes":
+				dev.WBytes = v
+			case "rios":
+				dev.RIOs = v
+			case "wios":
+				dev.WIOs = v
+			case "dbytes":
+				dev.DBytes = v
+			case "dios":
+				dev.DIOs = v
+			}
+		}
+		devices = append(devices, dev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	return devices, nil
+}
@@ -0,0 +1,98 @@
+package cgroups
+
+// Metrics are the metrics of a cgroup. The fields of controllers which are
+// not enabled for the cgroup are nil.
+type Metrics struct {
+	CPU    *CPUMetrics    `json:"cpu,omitempty"`
+	Memory *MemoryMetrics `json:"memory,omitempty"`
+	Pids   *PidsMetrics   `json:"pids,omitempty"`
+	IO     *IOMetrics     `json:"io,omitempty"`
+	// Misc maps the resources of the misc controller (e.g., "sev_es") to
+	// their metrics.
+	Misc map[string]MiscMetrics `json:"misc,omitempty"`
+	// RDMA maps the RDMA devices to their metrics.
+	RDMA map[string]RDMAMetrics `json:"rdma,omitempty"`
+}
+
+// CPUMetrics are the metrics of the cpu controller (cpu.stat).
+type CPUMetrics struct {
+	UsageUsec     uint64 `json:"usageUsec"`
+	UserUsec      uint64 `json:"userUsec"`
+	SystemUsec    uint64 `json:"systemUsec"`
+	NrPeriods     uint64 `json:"nrPeriods"`
+	NrThrottled   uint64 `json:"nrThrottled"`
+	ThrottledUsec uint64 `json:"throttledUsec"`
+	// PSI is the CPU pressure (cpu.pressure), nil if not supported.
+	PSI *PSIStats `json:"psi,omitempty"`
+}
+
+// MemoryMetrics are the metrics of the memory controller. Limits set to
+// "max" are reported as math.MaxUint64.
+type MemoryMetrics struct {
+	Usage     uint64 `json:"usage"`
+	Limit     uint64 `json:"limit"`
+	SwapUsage uint64 `json:"swapUsage"`
+	SwapLimit uint64 `json:"swapLimit"`
+	// PSI is the memory pressure (memory.pressure), nil if not supported.
+	PSI *PSIStats `json:"psi,omitempty"`
+}
+
+// PidsMetrics are the metrics of the pids controller. A limit set to "max"
+// is reported as math.MaxUint64.
+type PidsMetrics struct {
+	Current uint64 `json:"current"`
+	Limit   uint64 `json:"limit"`
+}
+
+// IOMetrics are the metrics of the io controller.
+type IOMetrics struct {
+	// Devices are the per-device statistics (io.stat).
+	Devices []IODeviceMetrics `json:"devices,omitempty"`
+	// PSI is the IO pressure (io.pressure), nil if not supported.
+	PSI *PSIStats `json:"psi,omitempty"`
+}
+
+// IODeviceMetrics are the IO statistics of a single block device.
+type IODeviceMetrics struct {
+	Major  int64  `json:"major"`
+	Minor  int64  `json:"minor"`
+	RBytes uint64 `json:"rbytes"`
+	WBytes uint64 `json:"wbytes"`
+	RIOs   uint64 `json:"rios"`
+	WIOs   uint64 `json:"wios"`
+	DBytes uint64 `json:"dbytes"`
+	DIOs   uint64 `json:"dios"`
+}
+
+// MiscMetrics are the usage and the limit of a resource of the misc
+// controller. A limit set to "max" is reported as math.MaxUint64.
+type MiscMetrics struct {
+	Current uint64 `json:"current"`
+	Limit   uint64 `json:"limit"`
+}
+
+// RDMAMetrics are the usage and the limits of the HCA handles and objects of
+// an RDMA device. Limits set to "max" are reported as math.MaxUint64.
+type RDMAMetrics struct {
+	HCAHandles      uint64 `json:"hcaHandles"`
+	HCAHandlesLimit uint64 `json:"hcaHandlesLimit"`
+	HCAObjects      uint64 `json:"hcaObjects"`
+	HCAObjectsLimit uint64 `json:"hcaObjectsLimit"`
+}
+
+// PSIStats are the pressure stall information of a resource. Full is not
+// reported for the CPU by kernels older than 5.13.
+type PSIStats struct {
+	Some PSIData `json:"some"`
+	Full PSIData `json:"full"`
+}
+
+// PSIData are the share of time (in percent) some or all tasks were stalled
+// on a resource during the last 10, 60 and 300 seconds and the total stall
+// time in microseconds.
+type PSIData struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
@@ -0,0 +1,160 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// SetMisc sets the limits of resources of the misc controller, e.g. the
+// number of SEV-ES ASIDs ("sev_es"). A limit of math.MaxUint64 removes the
+// limit. The misc controller is not managed by systemd, so the limits are
+// always written to misc.max.
+func (c *CgroupControl) SetMisc(limits map[string]uint64) error {
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return errors.Errorf("invalid misc resource name %q", name)
+		}
+		if err := c.writeFile("misc.max", name+" "+formatLimit(limits[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRDMA sets the limits of HCA handles and objects of the RDMA devices.
+// Unset limits are removed. The rdma controller is not managed by systemd,
+// so the limits are always written to rdma.max.
+func (c *CgroupControl) SetRDMA(limits map[string]spec.LinuxRdma) error {
+	devices := make([]string, 0, len(limits))
+	for device := range limits {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		if device == "" || strings.ContainsAny(device, " \t\n") {
+			return errors.Errorf("invalid RDMA device name %q", device)
+		}
+		limit := limits[device]
+		handles, objects := "max", "max"
+		if limit.HcaHandles != nil {
+			handles = strconv.FormatUint(uint64(*limit.HcaHandles), 10)
+		}
+		if limit.HcaObjects != nil {
+			objects = strconv.FormatUint(uint64(*limit.HcaObjects), 10)
+		}
+		if err := c.writeFile("rdma.max", fmt.Sprintf("%s hca_handle=%s hca_object=%s", device, handles, objects)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func statMisc(dir string) (map[string]MiscMetrics, error) {
+	current, err := readKeyValues(filepath.Join(dir, "misc.current"))
+	if err != nil || current == nil {
+		return nil, err
+	}
+	limits, err := readKeyValues(filepath.Join(dir, "misc.max"))
+	if err != nil {
+		return nil, err
+	}
+	misc := make(map[string]MiscMetrics, len(current))
+	for name, value := range current {
+		limit, ok := limits[name]
+		if !ok {
+			limit = unlimited
+		}
+		misc[name] = MiscMetrics{Current: value, Limit: limit}
+	}
+	return misc, nil
+}
+
+func statRDMA(dir string) (map[string]RDMAMetrics, error) {
+	current, err := readRDMAFile(filepath.Join(dir, "rdma.current"))
+	if err != nil || current == nil {
+		return nil, err
+	}
+	limits, err := readRDMAFile(filepath.Join(dir, "rdma.max"))
+	if err != nil {
+		return nil, err
+	}
+	rdma := make(map[string]RDMAMetrics, len(current))
+	for device, values := range current {
+		m := RDMAMetrics{
+			HCAHandles:      values["hca_handle"],
+			HCAObjects:      values["hca_object"],
+			HCAHandlesLimit: unlimited,
+			HCAObjectsLimit: unlimited,
+		}
+		if limit, ok := limits[device]; ok {
+			if v, ok := limit["hca_handle"]; ok {
+				m.HCAHandlesLimit = v
+			}
+			if v, ok := limit["hca_object"]; ok {
+				m.HCAObjectsLimit = v
+			}
+		}
+		rdma[device] = m
+	}
+	return rdma, nil
+}
+
+// readRDMAFile parses rdma.current or rdma.max with lines of the form
+// "mlx4_0 hca_handle=2 hca_object=max". nil is returned if the file does not
+// exist.
+func readRDMAFile(path string) (map[string]map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := make(map[string]map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		values := make(map[string]uint64)
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("parsing %s: invalid field %q", path, field)
+			}
+			v, err := parseUint(kv[1], path)
+			if err != nil {
+				return nil, err
+			}
+			values[kv[0]] = v
+		}
+		devices[fields[0]] = values
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	return devices, nil
+}
+
+func formatLimit(limit uint64) string {
+	if limit == unlimited {
+		return "max"
+	}
+	return strconv.FormatUint(limit, 10)
+}
@@ -0,0 +1,121 @@
+package cgroups
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// SubtreeMetrics are the metrics of a cgroup and its children.
+type SubtreeMetrics struct {
+	// Self are the metrics of the cgroup itself. On cgroup v2 the usage
+	// of a cgroup includes the usage of all its descendants.
+	Self *Metrics
+	// Children maps the names of the child cgroups to their metrics.
+	Children map[string]*Metrics
+	// Aggregate is the sum of the usage counters of all children. Limits
+	// and pressure stall information cannot be summed up and are not set.
+	Aggregate *Metrics
+}
+
+// StatChildren returns the metrics of the cgroup and all its immediate
+// children (e.g., the containers of a pod slice) along with the sum of the
+// children's usage, reading each cgroup only once.
+func (c *CgroupControl) StatChildren() (*SubtreeMetrics, error) {
+	self, err := c.Stat()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(c.fullPath())
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading cgroup %s", c.path)
+	}
+
+	sub := &SubtreeMetrics{
+		Self:      self,
+		Children:  make(map[string]*Metrics),
+		Aggregate: &Metrics{},
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		m, err := stat(filepath.Join(c.fullPath(), entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "stat child cgroup %s", entry.Name())
+		}
+		sub.Children[entry.Name()] = m
+		addMetrics(sub.Aggregate, m)
+	}
+	return sub, nil
+}
+
+// addMetrics adds the usage counters of m to sum.
+func addMetrics(sum, m *Metrics) {
+	if m.CPU != nil {
+		if sum.CPU == nil {
+			sum.CPU = &CPUMetrics{}
+		}
+		sum.CPU.UsageUsec += m.CPU.UsageUsec
+		sum.CPU.UserUsec += m.CPU.UserUsec
+		sum.CPU.SystemUsec += m.CPU.SystemUsec
+		sum.CPU.NrPeriods += m.CPU.NrPeriods
+		sum.CPU.NrThrottled += m.CPU.NrThrottled
+		sum.CPU.ThrottledUsec += m.CPU.ThrottledUsec
+	}
+	if m.Memory != nil {
+		if sum.Memory == nil {
+			sum.Memory = &MemoryMetrics{}
+		}
+		sum.Memory.Usage += m.Memory.Usage
+		sum.Memory.SwapUsage += m.Memory.SwapUsage
+	}
+	if m.Pids != nil {
+		if sum.Pids == nil {
+			sum.Pids = &PidsMetrics{}
+		}
+		sum.Pids.Current += m.Pids.Current
+	}
+	for name, misc := range m.Misc {
+		if sum.Misc == nil {
+			sum.Misc = make(map[string]MiscMetrics)
+		}
+		total := sum.Misc[name]
+		total.Current += misc.Current
+		sum.Misc[name] = total
+	}
+	for device, rdma := range m.RDMA {
+		if sum.RDMA == nil {
+			sum.RDMA = make(map[string]RDMAMetrics)
+		}
+		total := sum.RDMA[device]
+		total.HCAHandles += rdma.HCAHandles
+		total.HCAObjects += rdma.HCAObjects
+		sum.RDMA[device] = total
+	}
+	if m.IO != nil && len(m.IO.Devices) > 0 {
+		if sum.IO == nil {
+			sum.IO = &IOMetrics{}
+		}
+		for _, dev := range m.IO.Devices {
+			found := false
+			for i := range sum.IO.Devices {
+				d := &sum.IO.Devices[i]
+				if d.Major == dev.Major && d.Minor == dev.Minor {
+					d.RBytes += dev.RBytes
+					d.WBytes += dev.WBytes
+					d.RIOs += dev.RIOs
+					d.WIOs += dev.WIOs
+					d.DBytes += dev.DBytes
+					d.DIOs += dev.DIOs
+					found = true
+					break
+				}
+			}
+			if !found {
+				sum.IO.Devices = append(sum.IO.Devices, dev)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package cgroups
+
+import (
+	"path/filepath"
+
+	"github.com/containers/storage/pkg/unshare"
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/godbus/dbus/v5"
+	"github.com/pkg/errors"
+)
+
+// newSystemdConnection connects to the systemd instance managing the cgroups
+// of the current user.
+func newSystemdConnection() (*systemdDbus.Conn, error) {
+	if unshare.IsRootless() {
+		return systemdDbus.NewUserConnection()
+	}
+	return systemdDbus.NewSystemConnection()
+}
+
+func newProperty(name string, value interface{}) systemdDbus.Property {
+	return systemdDbus.Property{
+		Name:  name,
+		Value: dbus.MakeVariant(value),
+	}
+}
+
+// unitName returns the name of the systemd unit of the cgroup.
+func (c *CgroupControl) unitName() string {
+	return filepath.Base(c.path)
+}
+
+// setSystemdProperties sets the specified runtime properties of the unit of
+// the cgroup.
+func (c *CgroupControl) setSystemdProperties(props []systemdDbus.Property) error {
+	if len(props) == 0 {
+		return nil
+	}
+	conn, err := newSystemdConnection()
+	if err != nil {
+		return errors.Wrap(err, "connecting to systemd")
+	}
+	defer conn.Close()
+
+	if err := conn.SetUnitProperties(c.unitName(), true, props...); err != nil {
+		return errors.Wrapf(err, "setting properties of unit %s", c.unitName())
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package cgroups
+
+import (
+	"path/filepath"
+	"strings"
+
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// SystemdOptions are the options for creating a cgroup through systemd.
+type SystemdOptions struct {
+	// Description of the unit.
+	Description string
+	// Slice the scope is placed in. It defaults to the parent directory of
+	// the path if it is a slice (e.g., "machine.slice"). It is ignored for
+	// slices as their parent is implied by their name.
+	Slice string
+	// Properties are additional properties of the transient unit (e.g.,
+	// CPUQuotaPeriodUSec or IOReadBandwidthMax). They override the
+	// properties set by default or derived from the resources.
+	Properties []systemdDbus.Property
+}
+
+// NewSystemd creates the cgroup at the specified path as a transient systemd
+// unit, named after the last element of the path which must end with
+// ".scope" or ".slice". If pid is set, the process is moved into the scope.
+func NewSystemd(path string, pid int, resources *spec.LinuxResources, options *SystemdOptions) (*CgroupControl, error) {
+	enabled, err := cgroup2Enabled()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrCgroupV1
+	}
+	c := &CgroupControl{path: filepath.Clean("/" + path), systemd: true}
+	props, err := transientUnitProperties(c.path, pid, resources, options)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := newSystemdConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to systemd")
+	}
+	defer conn.Close()
+
+	ch := make(chan string)
+	if _, err := conn.StartTransientUnit(c.unitName(), "replace", props, ch); err != nil {
+		return nil, errors.Wrapf(err, "starting unit %s", c.unitName())
+	}
+	if result := <-ch; result != "done" {
+		return nil, errors.Errorf("starting unit %s: job %s", c.unitName(), result)
+	}
+	return c, nil
+}
+
+// transientUnitProperties returns the properties of the transient unit for
+// the cgroup at path.
+func transientUnitProperties(path string, pid int, resources *spec.LinuxResources, options *SystemdOptions) ([]systemdDbus.Property, error) {
+	if options == nil {
+		options = &SystemdOptions{}
+	}
+	unit := filepath.Base(path)
+	isScope := strings.HasSuffix(unit, ".scope")
+	if !isScope && !strings.HasSuffix(unit, ".slice") {
+		return nil, errors.Errorf("invalid unit name %q: must end with .scope or .slice", unit)
+	}
+	if isScope && pid <= 0 {
+		return nil, errors.Errorf("a process is required to create scope %s", unit)
+	}
+
+	description := options.Description
+	if description == "" {
+		description = "cgroup " + path
+	}
+	props := []systemdDbus.Property{
+		systemdDbus.PropDescription(description),
+		newProperty("DefaultDependencies", false),
+		newProperty("Delegate", true),
+	}
+	if isScope {
+		slice := options.Slice
+		if slice == "" && strings.HasSuffix(filepath.Dir(path), ".slice") {
+			slice = filepath.Base(filepath.Dir(path))
+		}
+		if slice != "" {
+			props = append(props, systemdDbus.PropSlice(slice))
+		}
+	}
+	if pid > 0 {
+		props = append(props, systemdDbus.PropPids(uint32(pid)))
+	}
+	if resources != nil {
+		resourceProps, err := systemdResourceProperties(resources)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, resourceProps...)
+	}
+	return overrideProperties(props, options.Properties), nil
+}
+
+// overrideProperties replaces the properties with the same name as one of
+// the extra properties and appends the remaining ones.
+func overrideProperties(props, extra []systemdDbus.Property) []systemdDbus.Property {
+	for _, p := range extra {
+		found := false
+		for i := range props {
+			if props[i].Name == p.Name {
+				props[i] = p
+				found = true
+			}
+		}
+		if !found {
+			props = append(props, p)
+		}
+	}
+	return props
+}
@@ -0,0 +1,295 @@
+package cgroups
+
+import (
+	"fmt"
+	"strconv"
+
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// defaultCPUPeriod is the default CFS period in microseconds.
+const defaultCPUPeriod = 100000
+
+// unlimited is the value used by systemd for "infinity".
+const unlimited = ^uint64(0)
+
+// Update changes the cpu, cpuset, memory, pids, rdma and io limits of the
+// cgroup to the specified resources. Unset fields are left unchanged. In
+// systemd mode the limits are applied through the properties of the unit,
+// such that they persist when systemd reapplies the unit configuration.
+func (c *CgroupControl) Update(resources *spec.LinuxResources) error {
+	if resources == nil {
+		return nil
+	}
+	if c.systemd {
+		props, err := systemdResourceProperties(resources)
+		if err != nil {
+			return err
+		}
+		if err := c.setSystemdProperties(props); err != nil {
+			return err
+		}
+		// systemd does not manage the rdma controller.
+		if len(resources.Rdma) > 0 {
+			return c.SetRDMA(resources.Rdma)
+		}
+		return nil
+	}
+
+	if cpu := resources.CPU; cpu != nil {
+		if cpu.Shares != nil && *cpu.Shares != 0 {
+			if err := c.writeFile("cpu.weight", strconv.FormatUint(cpuSharesToWeight(*cpu.Shares), 10)); err != nil {
+				return err
+			}
+		}
+		if cpu.Quota != nil || cpu.Period != nil {
+			quota := "max"
+			if cpu.Quota != nil && *cpu.Quota > 0 {
+				quota = strconv.FormatInt(*cpu.Quota, 10)
+			}
+			period := uint64(defaultCPUPeriod)
+			if cpu.Period != nil && *cpu.Period != 0 {
+				period = *cpu.Period
+			}
+			if err := c.writeFile("cpu.max", fmt.Sprintf("%s %d", quota, period)); err != nil {
+				return err
+			}
+		}
+		if err := c.SetCpuset(cpu.Cpus, cpu.Mems); err != nil {
+			return err
+		}
+	}
+
+	if mem := resources.Memory; mem != nil {
+		// The swap limit must be set after the memory limit as the OCI swap
+		// limit includes the memory.
+		if mem.Limit != nil {
+			if err := c.writeFile("memory.max", limitValue(*mem.Limit)); err != nil {
+				return err
+			}
+		}
+		if mem.Reservation != nil {
+			if err := c.writeFile("memory.low", reservationValue(*mem.Reservation)); err != nil {
+				return err
+			}
+		}
+		if mem.Swap != nil {
+			swap, err := swapLimit(mem)
+			if err != nil {
+				return err
+			}
+			if err := c.writeFile("memory.swap.max", swap); err != nil {
+				return err
+			}
+		}
+	}
+
+	if resources.Pids != nil {
+		if err := c.writeFile("pids.max", limitValue(resources.Pids.Limit)); err != nil {
+			return err
+		}
+	}
+
+	if len(resources.Rdma) > 0 {
+		if err := c.SetRDMA(resources.Rdma); err != nil {
+			return err
+		}
+	}
+
+	if io := blockIOResources(resources.BlockIO); io != nil {
+		return c.SetIO(io)
+	}
+	return nil
+}
+
+// systemdResourceProperties converts the resources into the properties of a
+// systemd unit.
+func systemdResourceProperties(resources *spec.LinuxResources) ([]systemdDbus.Property, error) {
+	var props []systemdDbus.Property
+
+	if cpu := resources.CPU; cpu != nil {
+		if cpu.Shares != nil && *cpu.Shares != 0 {
+			props = append(props, newProperty("CPUWeight", cpuSharesToWeight(*cpu.Shares)))
+		}
+		if cpu.Period != nil && *cpu.Period != 0 {
+			props = append(props, newProperty("CPUQuotaPeriodUSec", *cpu.Period))
+		}
+		if cpu.Quota != nil {
+			period := uint64(defaultCPUPeriod)
+			if cpu.Period != nil && *cpu.Period != 0 {
+				period = *cpu.Period
+			}
+			quota := unlimited
+			if *cpu.Quota > 0 {
+				// systemd expects the quota per second.
+				quota = uint64(*cpu.Quota) * 1000000 / period
+			}
+			props = append(props, newProperty("CPUQuotaPerSecUSec", quota))
+		}
+		cpusetProps, err := cpusetSystemdProperties(cpu.Cpus, cpu.Mems)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, cpusetProps...)
+	}
+
+	if mem := resources.Memory; mem != nil {
+		if mem.Limit != nil {
+			props = append(props, newProperty("MemoryMax", systemdLimit(*mem.Limit)))
+		}
+		if mem.Reservation != nil {
+			low := unlimited
+			if *mem.Reservation >= 0 {
+				low = uint64(*mem.Reservation)
+			}
+			props = append(props, newProperty("MemoryLow", low))
+		}
+		if mem.Swap != nil {
+			swap, err := swapLimit(mem)
+			if err != nil {
+				return nil, err
+			}
+			value := unlimited
+			if swap != "max" {
+				if value, err = strconv.ParseUint(swap, 10, 64); err != nil {
+					return nil, err
+				}
+			}
+			props = append(props, newProperty("MemorySwapMax", value))
+		}
+	}
+
+	if resources.Pids != nil {
+		props = append(props, newProperty("TasksMax", systemdLimit(resources.Pids.Limit)))
+	}
+
+	if io := blockIOResources(resources.BlockIO); io != nil {
+		if err := validateIOResources(io); err != nil {
+			return nil, err
+		}
+		ioProps, err := ioSystemdProperties(io)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, ioProps...)
+	}
+	return props, nil
+}
+
+// blockIOResources converts the OCI block IO resources to the io controller.
+// Devices are referred to by their /dev/block path which is required by
+// systemd.
+func blockIOResources(blkio *spec.LinuxBlockIO) *IOResources {
+	if blkio == nil {
+		return nil
+	}
+	device := func(major, minor int64) IODevice {
+		return IODevice{Path: fmt.Sprintf("/dev/block/%d:%d", major, minor), Major: major, Minor: minor}
+	}
+
+	io := &IOResources{}
+	if blkio.Weight != nil && *blkio.Weight != 0 {
+		io.Weight = blkioWeightToIOWeight(*blkio.Weight)
+	}
+	for _, w := range blkio.WeightDevice {
+		if w.Weight != nil && *w.Weight != 0 {
+			io.DeviceWeights = append(io.DeviceWeights, IODeviceWeight{
+				IODevice: device(w.Major, w.Minor),
+				Weight:   blkioWeightToIOWeight(*w.Weight),
+			})
+		}
+	}
+
+	limits := make(map[[2]int64]*IODeviceLimit)
+	var order [][2]int64
+	add := func(devices []spec.LinuxThrottleDevice, set func(l *IODeviceLimit, rate uint64)) {
+		for _, d := range devices {
+			key := [2]int64{d.Major, d.Minor}
+			l, ok := limits[key]
+			if !ok {
+				l = &IODeviceLimit{IODevice: device(d.Major, d.Minor)}
+				limits[key] = l
+				order = append(order, key)
+			}
+			set(l, d.Rate)
+		}
+	}
+	add(blkio.ThrottleReadBpsDevice, func(l *IODeviceLimit, rate uint64) { l.ReadBps = rate })
+	add(blkio.ThrottleWriteBpsDevice, func(l *IODeviceLimit, rate uint64) { l.WriteBps = rate })
+	add(blkio.ThrottleReadIOPSDevice, func(l *IODeviceLimit, rate uint64) { l.ReadIOPS = rate })
+	add(blkio.ThrottleWriteIOPSDevice, func(l *IODeviceLimit, rate uint64) { l.WriteIOPS = rate })
+	for _, key := range order {
+		io.DeviceLimits = append(io.DeviceLimits, *limits[key])
+	}
+
+	if io.Weight == 0 && len(io.DeviceWeights) == 0 && len(io.DeviceLimits) == 0 {
+		return nil
+	}
+	return io
+}
+
+// cpuSharesToWeight converts the cgroup v1 cpu.shares (2-262144) into the
+// cgroup v2 cpu.weight (1-10000).
+func cpuSharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	}
+	if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
+// blkioWeightToIOWeight converts the cgroup v1 blkio.weight (10-1000) into
+// the cgroup v2 io.weight (1-10000).
+func blkioWeightToIOWeight(weight uint16) uint16 {
+	if weight < 10 {
+		weight = 10
+	}
+	if weight > 1000 {
+		weight = 1000
+	}
+	return uint16(1 + (uint32(weight)-10)*9999/990)
+}
+
+// swapLimit returns the memory.swap.max value for the OCI swap limit which
+// includes the memory limit.
+func swapLimit(mem *spec.LinuxMemory) (string, error) {
+	if *mem.Swap < 0 {
+		return "max", nil
+	}
+	if mem.Limit == nil || *mem.Limit < 0 {
+		return "", errors.New("a memory limit is required to set a swap limit")
+	}
+	if *mem.Swap < *mem.Limit {
+		return "", errors.Errorf("swap limit %d must be greater than or equal to the memory limit %d", *mem.Swap, *mem.Limit)
+	}
+	return strconv.FormatInt(*mem.Swap-*mem.Limit, 10), nil
+}
+
+// reservationValue returns the memory.low value for the OCI reservation, a
+// negative reservation protects all memory of the cgroup.
+func reservationValue(reservation int64) string {
+	if reservation < 0 {
+		return "max"
+	}
+	return strconv.FormatInt(reservation, 10)
+}
+
+// limitValue returns the control file value of the OCI limit, where 0 and
+// negative values mean unlimited.
+func limitValue(limit int64) string {
+	if limit <= 0 {
+		return "max"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+func systemdLimit(limit int64) uint64 {
+	if limit <= 0 {
+		return unlimited
+	}
+	return uint64(limit)
+}
@@ -3,6 +3,7 @@ package chown
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"syscall"
 	"testing"
@@ -134,3 +135,49 @@ func TestChangeHostPathOwnership(t *testing.T) {
 		}
 	}
 }
+
+func TestChangeHostPathOwnershipWithOptions(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Current paths are supported only by Linux")
+	}
+
+	td, err := ioutil.TempDir("/tmp", "validDir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	for _, dir := range []string{"a", "a/b", "c"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(td, dir), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(td, dir, "file"), nil, 0644))
+	}
+
+	var progress []int64
+	options := &Options{
+		Recursive: true,
+		Workers:   2,
+		Progress:  func(files int64) { progress = append(progress, files) },
+	}
+	method, err := ChangeHostPathOwnershipWithOptions(td, os.Getuid(), os.Getgid(), options)
+	assert.NoError(t, err)
+	assert.Equal(t, MethodNone, method)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7}, progress)
+
+	if os.Geteuid() != 0 {
+		t.Skip("Changing the ownership requires root")
+	}
+	progress = nil
+	method, err = ChangeHostPathOwnershipWithOptions(td, 1000, 1000, options)
+	assert.NoError(t, err)
+	assert.Equal(t, MethodChown, method)
+	assert.Len(t, progress, 7)
+	err = filepath.Walk(td, func(path string, f os.FileInfo, err error) error {
+		assert.NoError(t, err)
+		assert.True(t, ownedBy(f, 1000, 1000), path)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	method, err = ChangeHostPathOwnershipWithOptions(td, 1000, 1000, &Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, MethodNone, method)
+}
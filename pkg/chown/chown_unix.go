@@ -5,6 +5,8 @@ package chown
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 	"syscall"
 
 	"github.com/pkg/errors"
@@ -13,54 +15,129 @@ import (
 // ChangeHostPathOwnership changes the uid and gid ownership of a directory or file within the host.
 // This is used by the volume U flag to change source volumes ownership
 func ChangeHostPathOwnership(path string, recursive bool, uid, gid int) error {
+	_, err := ChangeHostPathOwnershipWithOptions(path, uid, gid, &Options{Recursive: recursive})
+	return err
+}
+
+// ChangeHostPathOwnershipWithOptions changes the uid and gid ownership of a
+// directory or file within the host, preferring idmapped mounts over
+// recursive changes if requested.  It returns the method used.
+func ChangeHostPathOwnershipWithOptions(path string, uid, gid int, options *Options) (Method, error) {
+	if options == nil {
+		options = &Options{}
+	}
 	// Validate if host path can be chowned
 	isDangerous, err := DangerousHostPath(path)
 	if err != nil {
-		return errors.Wrap(err, "failed to validate if host path is dangerous")
+		return MethodNone, errors.Wrap(err, "failed to validate if host path is dangerous")
 	}
 
 	if isDangerous {
-		return errors.Errorf("chowning host path %q is not allowed. You can manually `chown -R %d:%d %s`", path, uid, gid, path)
+		return MethodNone, errors.Errorf("chowning host path %q is not allowed. You can manually `chown -R %d:%d %s`", path, uid, gid, path)
 	}
 
-	// Chown host path
-	if recursive {
-		err := filepath.Walk(path, func(filePath string, f os.FileInfo, err error) error {
-			if err != nil {
-				return err
+	if !options.Recursive {
+		// Get host path info
+		f, err := os.Lstat(path)
+		if err != nil {
+			return MethodNone, errors.Wrap(err, "failed to get host path information")
+		}
+		if !ownedBy(f, uid, gid) {
+			if err := os.Lchown(path, uid, gid); err != nil {
+				return MethodNone, errors.Wrap(err, "failed to chown host path")
 			}
+			return MethodChown, nil
+		}
+		return MethodNone, nil
+	}
 
-			// Get current ownership
-			currentUID := int(f.Sys().(*syscall.Stat_t).Uid)
-			currentGID := int(f.Sys().(*syscall.Stat_t).Gid)
+	if options.PreferIDMap && IDMappedMountsSupported(path) {
+		return MethodIDMap, nil
+	}
+	changed, err := chownRecursive(path, uid, gid, options)
+	if err != nil {
+		return MethodNone, errors.Wrap(err, "failed to chown recursively host path")
+	}
+	if changed {
+		return MethodChown, nil
+	}
+	return MethodNone, nil
+}
 
-			if uid != currentUID || gid != currentGID {
-				return os.Lchown(filePath, uid, gid)
-			}
+// errStopped stops the walk after a worker failed.
+var errStopped = errors.New("stopped")
 
-			return nil
-		})
+// chownRecursive walks the path and chowns the files not owned by the user
+// with parallel workers.  It returns true if a file was chowned.
+func chownRecursive(path string, uid, gid int, options *Options) (bool, error) {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
 
-		if err != nil {
-			return errors.Wrap(err, "failed to chown recursively host path")
+	var (
+		mutex     sync.Mutex
+		firstErr  error
+		processed int64
+		changed   bool
+		wg        sync.WaitGroup
+	)
+	// done records the result of a file and returns false after the first
+	// error to stop the walk.
+	done := func(chowned bool, err error) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
-	} else {
-		// Get host path info
-		f, err := os.Lstat(path)
-		if err != nil {
-			return errors.Wrap(err, "failed to get host path information")
+		changed = changed || chowned
+		processed++
+		if options.Progress != nil {
+			options.Progress(processed)
 		}
+		return firstErr == nil
+	}
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return firstErr != nil
+	}
 
-		// Get current ownership
-		currentUID := int(f.Sys().(*syscall.Stat_t).Uid)
-		currentGID := int(f.Sys().(*syscall.Stat_t).Gid)
-
-		if uid != currentUID || gid != currentGID {
-			if err := os.Lchown(path, uid, gid); err != nil {
-				return errors.Wrap(err, "failed to chown host path")
+	paths := make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				err := os.Lchown(p, uid, gid)
+				done(err == nil, err)
 			}
+		}()
+	}
+
+	walkErr := filepath.Walk(path, func(filePath string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
+		if failed() {
+			return errStopped
+		}
+		if ownedBy(f, uid, gid) {
+			done(false, nil)
+			return nil
+		}
+		paths <- filePath
+		return nil
+	})
+	close(paths)
+	wg.Wait()
+	if walkErr != nil && walkErr != errStopped {
+		return changed, walkErr
 	}
+	return changed, firstErr
+}
 
-	return nil
+func ownedBy(f os.FileInfo, uid, gid int) bool {
+	st := f.Sys().(*syscall.Stat_t)
+	return int(st.Uid) == uid && int(st.Gid) == gid
 }
@@ -9,3 +9,9 @@ import (
 func ChangeHostPathOwnership(path string, recursive bool, uid, gid int) error {
 	return errors.New("windows not supported")
 }
+
+// ChangeHostPathOwnershipWithOptions changes the uid and gid ownership of a
+// directory or file within the host.
+func ChangeHostPathOwnershipWithOptions(path string, uid, gid int, options *Options) (Method, error) {
+	return MethodNone, errors.New("windows not supported")
+}
@@ -0,0 +1,47 @@
+package chown
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// sysMountSetattr is the number of the mount_setattr system call, which is
+// the same on all architectures.
+const sysMountSetattr = 442
+
+var (
+	mountSetattrOnce      sync.Once
+	mountSetattrSupported bool
+)
+
+// idmapFilesystems are the file systems supporting idmapped mounts since
+// mount_setattr was added in Linux 5.12.
+var idmapFilesystems = map[int64]bool{
+	unix.EXT4_SUPER_MAGIC: true,
+	unix.XFS_SUPER_MAGIC:  true,
+}
+
+// IDMappedMountsSupported returns true if the path can be mounted with an
+// idmapping by the current user.
+func IDMappedMountsSupported(path string) bool {
+	// Idmapped mounts require CAP_SYS_ADMIN in the initial user namespace.
+	if os.Geteuid() != 0 {
+		return false
+	}
+	mountSetattrOnce.Do(func() {
+		// An invalid file descriptor fails with EBADF if the system
+		// call exists.
+		_, _, errno := unix.Syscall6(sysMountSetattr, ^uintptr(0), 0, 0, 0, 0, 0)
+		mountSetattrSupported = errno != unix.ENOSYS
+	})
+	if !mountSetattrSupported {
+		return false
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return false
+	}
+	return idmapFilesystems[int64(st.Type)]
+}
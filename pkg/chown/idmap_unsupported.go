@@ -0,0 +1,9 @@
+// +build !linux
+
+package chown
+
+// IDMappedMountsSupported returns false as idmapped mounts are only
+// supported on Linux.
+func IDMappedMountsSupported(path string) bool {
+	return false
+}
@@ -0,0 +1,30 @@
+package chown
+
+// Method is the way the ownership of a host path is changed.
+type Method int
+
+const (
+	// MethodNone is returned if the path is already owned by the user.
+	MethodNone Method = iota
+	// MethodChown is returned if the files were chowned.
+	MethodChown
+	// MethodIDMap is returned if the files were left unchanged because
+	// the path supports idmapped mounts.  The caller must mount the path
+	// with an idmapping to the user instead.
+	MethodIDMap
+)
+
+// Options are the options of ChangeHostPathOwnershipWithOptions.
+type Options struct {
+	// Recursive changes the ownership of all files below the path.
+	Recursive bool
+	// PreferIDMap skips changing the ownership if the path supports
+	// idmapped mounts.
+	PreferIDMap bool
+	// Workers is the number of files chowned in parallel by recursive
+	// changes, the number of CPUs if not set.
+	Workers int
+	// Progress is called with the number of files processed by
+	// recursive changes.  It is not called concurrently.
+	Progress func(files int64)
+}
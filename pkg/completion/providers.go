@@ -0,0 +1,85 @@
+package completion
+
+import (
+	"context"
+	"strings"
+
+	"github.com/containers/common/libimage"
+	"github.com/containers/common/pkg/secrets"
+	"github.com/spf13/cobra"
+)
+
+// Provider lists the names of objects, e.g. images or secrets, to complete.
+type Provider interface {
+	Names(ctx context.Context) ([]string, error)
+}
+
+// ProviderFunc adapts a function to a Provider, e.g. to list the networks
+// of an engine.
+type ProviderFunc func(ctx context.Context) ([]string, error)
+
+// Names calls the function.
+func (f ProviderFunc) Names(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// ImageProvider lists the names and short IDs of the local images.
+func ImageProvider(runtime *libimage.Runtime) Provider {
+	return ProviderFunc(func(ctx context.Context) ([]string, error) {
+		images, err := runtime.ListImages(ctx, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, image := range images {
+			names = append(names, image.Names()...)
+			names = append(names, image.ID()[:12])
+		}
+		return names, nil
+	})
+}
+
+// SecretProvider lists the names of the secrets.
+func SecretProvider(manager *secrets.SecretsManager) Provider {
+	return ProviderFunc(func(ctx context.Context) ([]string, error) {
+		list, err := manager.List()
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(list))
+		for _, secret := range list {
+			names = append(names, secret.Name)
+		}
+		return names, nil
+	})
+}
+
+// AutocompleteProvider - Autocomplete the names listed by the provider which
+// start with the input and are not already arguments of the command.
+func AutocompleteProvider(provider Provider) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		ctx := context.Background()
+		if cmd != nil && cmd.Context() != nil {
+			ctx = cmd.Context()
+		}
+		names, err := provider.Names(ctx)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		used := make(map[string]bool, len(args))
+		for _, arg := range args {
+			used[arg] = true
+		}
+		var completions []string
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			if strings.HasPrefix(name, toComplete) && !used[name] && !seen[name] {
+				seen[name] = true
+				completions = append(completions, name)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
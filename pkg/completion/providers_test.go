@@ -0,0 +1,26 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutocompleteProvider(t *testing.T) {
+	provider := ProviderFunc(func(ctx context.Context) ([]string, error) {
+		return []string{"podman", "podman1", "kind", "podman1"}, nil
+	})
+	completions, directive := AutocompleteProvider(provider)(&cobra.Command{}, []string{"podman"}, "pod")
+	assert.Equal(t, []string{"podman1"}, completions)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	provider = ProviderFunc(func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("no networks")
+	})
+	completions, directive = AutocompleteProvider(provider)(nil, nil, "")
+	assert.Empty(t, completions)
+	assert.Equal(t, cobra.ShellCompDirectiveError, directive)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,7 +10,9 @@ import (
 	"sync"
 
 	"github.com/BurntSushi/toml"
+	"github.com/containers/common/pkg/apparmor"
 	"github.com/containers/common/pkg/capabilities"
+	"github.com/containers/common/pkg/umask"
 	"github.com/containers/storage/pkg/unshare"
 	units "github.com/docker/go-units"
 	selinux "github.com/opencontainers/selinux/go-selinux"
@@ -83,6 +86,14 @@ type ContainersConfig struct {
 	// default for the runtime.
 	ApparmorProfile string `toml:"apparmor_profile,omitempty"`
 
+	// ApparmorProfileTemplate is the path of a site-specific template of
+	// the default apparmor profile replacing the built-in one.
+	ApparmorProfileTemplate string `toml:"apparmor_profile_template,omitempty"`
+
+	// ApparmorProfileParameters are the parameters of the
+	// ApparmorProfileTemplate.
+	ApparmorProfileParameters map[string]string `toml:"apparmor_profile_parameters,omitempty"`
+
 	// Annotation to add to all containers
 	Annotations []string `toml:"annotations,omitempty"`
 
@@ -95,6 +106,10 @@ type ContainersConfig struct {
 	// Capabilities to add to all containers.
 	DefaultCapabilities []string `toml:"default_capabilities,omitempty"`
 
+	// CapabilityProfiles are named lists of capabilities which can be
+	// referenced as "profile:NAME" in capability lists.
+	CapabilityProfiles map[string][]string `toml:"capability_profiles,omitempty"`
+
 	// Sysctls to add to all containers.
 	DefaultSysctls []string `toml:"default_sysctls,omitempty"`
 
@@ -573,6 +588,9 @@ func (c *Config) addCAPPrefix() {
 		return cap
 	}
 	for i, cap := range c.Containers.DefaultCapabilities {
+		if capabilities.IsProfile(cap) {
+			continue
+		}
 		c.Containers.DefaultCapabilities[i] = toCAPPrefixed(cap)
 	}
 }
@@ -653,6 +671,14 @@ func (c *ContainersConfig) Validate() error {
 		return err
 	}
 
+	if err := capabilities.ValidateProfiles(c.CapabilityProfiles); err != nil {
+		return err
+	}
+
+	if _, err := c.ApparmorTemplate(); err != nil {
+		return err
+	}
+
 	if c.LogSizeMax >= 0 && c.LogSizeMax < OCIBufSize {
 		return errors.Errorf("log size max should be negative or >= %d", OCIBufSize)
 	}
@@ -783,8 +809,18 @@ func (c *Config) GetDefaultEnvEx(envHost, httpProxy bool) []string {
 	return append(env, c.Containers.Env...)
 }
 
+// ApparmorTemplate returns the validated template of the default apparmor
+// profile, or nil if the built-in template is used.
+func (c *ContainersConfig) ApparmorTemplate() (*apparmor.ProfileTemplate, error) {
+	if c.ApparmorProfileTemplate == "" {
+		return nil, nil
+	}
+	return apparmor.LoadTemplate(c.ApparmorProfileTemplate, c.ApparmorProfileParameters)
+}
+
 // Capabilities returns the capabilities parses the Add and Drop capability
-// list from the default capabiltiies for the container
+// list from the default capabiltiies for the container.  The lists may
+// reference capability profiles as "profile:NAME".
 func (c *Config) Capabilities(user string, addCapabilities, dropCapabilities []string) ([]string, error) {
 
 	userNotRoot := func(user string) bool {
@@ -794,14 +830,42 @@ func (c *Config) Capabilities(user string, addCapabilities, dropCapabilities []s
 		return true
 	}
 
-	defaultCapabilities := c.Containers.DefaultCapabilities
+	profiles, err := c.capabilityProfiles()
+	if err != nil {
+		return nil, err
+	}
+	defaultCapabilities := profiles[capabilities.ProfileDefault]
 	if userNotRoot(user) {
 		defaultCapabilities = []string{}
 	}
+	if addCapabilities, err = capabilities.ExpandProfiles(addCapabilities, profiles); err != nil {
+		return nil, err
+	}
+	if dropCapabilities, err = capabilities.ExpandProfiles(dropCapabilities, profiles); err != nil {
+		return nil, err
+	}
 
 	return capabilities.MergeCapabilities(defaultCapabilities, addCapabilities, dropCapabilities)
 }
 
+// capabilityProfiles returns the capability profiles of the configuration
+// including the "default" profile of the expanded default capabilities,
+// unless it is redefined.
+func (c *Config) capabilityProfiles() (map[string][]string, error) {
+	profiles := make(map[string][]string, len(c.Containers.CapabilityProfiles)+1)
+	for name, caps := range c.Containers.CapabilityProfiles {
+		profiles[name] = caps
+	}
+	defaults, err := capabilities.ExpandProfiles(c.Containers.DefaultCapabilities, c.Containers.CapabilityProfiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "default capabilities")
+	}
+	if _, ok := profiles[capabilities.ProfileDefault]; !ok {
+		profiles[capabilities.ProfileDefault] = defaults
+	}
+	return profiles, nil
+}
+
 // Device parses device mapping string to a src, dest & permissions string
 // Valid values for device looklike:
 //    '/dev/sdc"
@@ -1001,16 +1065,12 @@ func (c *Config) Write() error {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	configFile, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
-	if err != nil {
-		return err
-	}
-	defer configFile.Close()
-	enc := toml.NewEncoder(configFile)
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
 	if err := enc.Encode(c); err != nil {
 		return err
 	}
-	return nil
+	return umask.AtomicWriteFile(path, buf.Bytes(), 0600)
 }
 
 // Reload clean the cached config and reloads the configuration from containers.conf files
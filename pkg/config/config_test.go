@@ -381,6 +381,69 @@ var _ = Describe("Config", func() {
 			gomega.Expect(caps).To(gomega.BeEquivalentTo(expectedCaps))
 		})
 
+		It("should validate the apparmor profile template", func() {
+			// Given
+			config, err := NewConfig("")
+			gomega.Expect(err).To(gomega.BeNil())
+			template, err := ioutil.TempFile("", "apparmor-template")
+			gomega.Expect(err).To(gomega.BeNil())
+			defer os.Remove(template.Name())
+			_, err = template.WriteString("profile {{.Name}} {\n  deny {{.Parameters.denied}} rwklx,\n}\n")
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(template.Close()).To(gomega.BeNil())
+
+			// When
+			config.Containers.ApparmorProfileTemplate = template.Name()
+			config.Containers.ApparmorProfileParameters = map[string]string{"denied": "/srv/**"}
+			tmpl, err := config.Containers.ApparmorTemplate()
+			// Then
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(tmpl.Parameters).To(gomega.Equal(config.Containers.ApparmorProfileParameters))
+			gomega.Expect(config.Containers.Validate()).To(gomega.BeNil())
+
+			// Missing parameter
+			config.Containers.ApparmorProfileParameters = nil
+			gomega.Expect(config.Containers.Validate()).ToNot(gomega.BeNil())
+
+			// No template
+			config.Containers.ApparmorProfileTemplate = ""
+			tmpl, err = config.Containers.ApparmorTemplate()
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(tmpl).To(gomega.BeNil())
+		})
+
+		It("Test Capabilities call with profiles", func() {
+			// Given
+			config, err := NewConfig("")
+			gomega.Expect(err).To(gomega.BeNil())
+			config.Containers.DefaultCapabilities = []string{"CAP_CHOWN", "profile:net"}
+			config.Containers.CapabilityProfiles = map[string][]string{
+				"net":   {"NET_ADMIN", "NET_RAW"},
+				"debug": {"SYS_PTRACE"},
+			}
+			gomega.Expect(config.Containers.Validate()).To(gomega.BeNil())
+
+			// When
+			caps, err := config.Capabilities("", []string{"profile:debug"}, []string{"NET_RAW"})
+			// Then
+			gomega.Expect(err).To(gomega.BeNil())
+			sort.Strings(caps)
+			gomega.Expect(caps).To(gomega.BeEquivalentTo([]string{"CAP_CHOWN", "CAP_NET_ADMIN", "CAP_SYS_PTRACE"}))
+
+			// Drop the default capabilities
+			caps, err = config.Capabilities("", []string{"profile:debug"}, []string{"profile:default"})
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(caps).To(gomega.BeEquivalentTo([]string{"CAP_SYS_PTRACE"}))
+
+			// Unknown profile
+			_, err = config.Capabilities("", []string{"profile:unknown"}, nil)
+			gomega.Expect(err).ToNot(gomega.BeNil())
+
+			// Nested profiles are rejected
+			config.Containers.CapabilityProfiles["nested"] = []string{"profile:net"}
+			gomega.Expect(config.Containers.Validate()).ToNot(gomega.BeNil())
+		})
+
 		It("should succeed with default pull_policy", func() {
 			err := sut.Engine.Validate()
 			gomega.Expect(err).To(gomega.BeNil())
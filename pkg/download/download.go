@@ -0,0 +1,209 @@
+// Package download fetches remote files, verifying their digest while they
+// are streamed and resuming interrupted downloads.
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/containers/common/pkg/retry"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// PartialSuffix is appended to the destination path while downloading.
+const PartialSuffix = ".part"
+
+// Options are the options of FromURL.
+type Options struct {
+	// Digest is the expected digest of the content, if set.  The
+	// destination is only created if the content matches it.
+	Digest digest.Digest
+	// MaxRetry is the number of times a failed download is retried.
+	// Retries resume where the previous attempt stopped if the server
+	// supports range requests.
+	MaxRetry int
+	// RetryOptions override the default backoff of the retries, if set.
+	RetryOptions *retry.RetryOptions
+	// KeepPartial keeps the partial download on failure, such that the
+	// next download to the same path resumes it.
+	KeepPartial bool
+	// Progress is called after each chunk with the number of bytes
+	// downloaded and the total size, which is -1 if unknown.
+	Progress func(downloaded, total int64)
+	// Client is used for the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// StatusError is returned if the server responds with an unexpected status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("downloading %s: unexpected status %d (%s)", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// ErrDigestMismatch is returned if the downloaded content does not match
+// the expected digest.
+var ErrDigestMismatch = errors.New("downloaded content does not match the expected digest")
+
+// FromURL downloads the content of url to path.
+func FromURL(ctx context.Context, url, path string, options *Options) error {
+	if options == nil {
+		options = &Options{}
+	}
+	if options.Digest != "" {
+		if err := options.Digest.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid digest %q", options.Digest)
+		}
+	}
+	partial := path + PartialSuffix
+	retryOptions := retry.RetryOptions{MaxRetry: options.MaxRetry}
+	if options.RetryOptions != nil {
+		retryOptions = *options.RetryOptions
+		retryOptions.MaxRetry = options.MaxRetry
+	}
+	if retryOptions.IsErrorRetryable == nil {
+		retryOptions.IsErrorRetryable = isRetryable
+	}
+
+	err := retry.RetryIfNecessary(ctx, func() error {
+		return download(ctx, url, partial, options)
+	}, &retryOptions)
+	if err != nil {
+		if !options.KeepPartial || errors.Cause(err) == ErrDigestMismatch {
+			os.Remove(partial)
+		}
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// download appends the missing content of url to partial and verifies the
+// digest of the complete content.
+func download(ctx context.Context, url, partial string, options *Options) error {
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var digester digest.Digester
+	hash := io.Writer(ioutil.Discard)
+	if options.Digest != "" {
+		digester = options.Digest.Algorithm().Digester()
+		hash = digester.Hash()
+	}
+	// Hash the content of a previous attempt, which also seeks to its end.
+	offset, err := io.Copy(hash, f)
+	if err != nil {
+		return errors.Wrapf(err, "reading partial download %s", partial)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// The server sends the complete content.
+		if offset > 0 {
+			if err := restart(f, &digester, options.Digest); err != nil {
+				return err
+			}
+			offset = 0
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial download is stale; start over on the next attempt.
+		if err := restart(f, &digester, options.Digest); err != nil {
+			return err
+		}
+		return &StatusError{URL: url, StatusCode: resp.StatusCode}
+	default:
+		return &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+	if digester != nil {
+		hash = digester.Hash()
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	w := &progressWriter{downloaded: offset, total: total, progress: options.Progress}
+	if _, err := io.Copy(io.MultiWriter(f, hash, w), resp.Body); err != nil {
+		return errors.Wrapf(err, "downloading %s", url)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if digester != nil && digester.Digest() != options.Digest {
+		return errors.Wrapf(ErrDigestMismatch, "downloading %s: got %s, expected %s", url, digester.Digest(), options.Digest)
+	}
+	return nil
+}
+
+// restart truncates the partial download and resets the digester.
+func restart(f *os.File, digester *digest.Digester, expected digest.Digest) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if *digester != nil {
+		*digester = expected.Algorithm().Digester()
+	}
+	return nil
+}
+
+// isRetryable retries server errors and interrupted transfers in addition
+// to the errors retried by the retry package.
+func isRetryable(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *StatusError:
+		return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestedRangeNotSatisfiable
+	}
+	switch errors.Cause(err) {
+	case ErrDigestMismatch:
+		return false
+	case io.ErrUnexpectedEOF:
+		return true
+	}
+	return retry.IsErrorRetryable(err)
+}
+
+type progressWriter struct {
+	downloaded int64
+	total      int64
+	progress   func(downloaded, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.downloaded += int64(len(p))
+	if w.progress != nil {
+		w.progress(w.downloaded, w.total)
+	}
+	return len(p), nil
+}
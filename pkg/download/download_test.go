@@ -0,0 +1,131 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containers/common/pkg/retry"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var content = bytes.Repeat([]byte("0123456789"), 1000)
+
+// interruptingServer serves content, aborting the first transfer halfway.
+func interruptingServer(t *testing.T, ranges *[]string) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		if requests == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, err := w.Write(content[:len(content)/2])
+			require.NoError(t, err)
+			return
+		}
+		http.ServeContent(w, r, "content", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestFromURLResume(t *testing.T) {
+	var ranges []string
+	server := interruptingServer(t, &ranges)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+
+	var downloaded, total int64
+	err = FromURL(context.Background(), server.URL, path, &Options{
+		Digest:       digest.FromBytes(content),
+		MaxRetry:     1,
+		RetryOptions: &retry.RetryOptions{Delay: time.Millisecond},
+		Progress: func(d, t int64) {
+			downloaded, total = d, t
+		},
+	})
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.Equal(t, []string{"", "bytes=" + strconv.Itoa(len(content)/2) + "-"}, ranges)
+	assert.Equal(t, int64(len(content)), downloaded)
+	assert.Equal(t, int64(len(content)), total)
+	_, err = os.Stat(path + PartialSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFromURLKeepPartial(t *testing.T) {
+	var ranges []string
+	server := interruptingServer(t, &ranges)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+
+	err = FromURL(context.Background(), server.URL, path, &Options{KeepPartial: true})
+	require.Error(t, err)
+	info, err := os.Stat(path + PartialSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)/2), info.Size())
+
+	require.NoError(t, FromURL(context.Background(), server.URL, path, nil))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestFromURLDigestMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "content", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+
+	err = FromURL(context.Background(), server.URL, path, &Options{Digest: digest.FromString("other"), MaxRetry: 3, KeepPartial: true})
+	assert.Equal(t, ErrDigestMismatch, errors.Cause(err))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + PartialSuffix)
+	assert.True(t, os.IsNotExist(err))
+
+	err = FromURL(context.Background(), server.URL, path, &Options{Digest: "sha256:invalid"})
+	assert.Error(t, err)
+}
+
+func TestFromURLStatus(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = FromURL(context.Background(), server.URL, filepath.Join(dir, "file"), &Options{MaxRetry: 3})
+	require.Error(t, err)
+	statusErr, ok := errors.Cause(err).(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, 1, requests)
+
+	assert.True(t, isRetryable(&StatusError{StatusCode: http.StatusServiceUnavailable}))
+	assert.True(t, isRetryable(&StatusError{StatusCode: http.StatusTooManyRequests}))
+}
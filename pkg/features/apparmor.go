@@ -0,0 +1,7 @@
+// +build linux,apparmor
+
+package features
+
+func init() {
+	supported[AppArmor] = true
+}
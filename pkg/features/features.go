@@ -0,0 +1,95 @@
+// Package features reports the optional features of this build of the
+// library, such that engines and remote clients can negotiate capabilities
+// instead of parsing version strings.
+package features
+
+import (
+	"sort"
+
+	"github.com/containers/common/version"
+)
+
+// Feature is an optional feature of the library.
+type Feature string
+
+const (
+	// AppArmor is set if the library is built with AppArmor support.
+	AppArmor Feature = "apparmor"
+	// Seccomp is set if the library is built with libseccomp.
+	Seccomp Feature = "seccomp"
+	// GPGME is set if signatures are verified with GPGME instead of the
+	// pure Go OpenPGP implementation.
+	GPGME Feature = "gpgme"
+	// Zstd is set if images can be compressed with zstd.
+	Zstd Feature = "zstd"
+	// ZstdChunked is set if zstd:chunked images can be pulled partially.
+	ZstdChunked Feature = "zstd:chunked"
+	// Sigstore is set if sigstore signatures can be created and verified.
+	Sigstore Feature = "sigstore"
+	// Netavark is set if networks can be managed with netavark.
+	Netavark Feature = "netavark"
+	// SQLiteSecrets is set if secrets can be stored in SQLite.
+	SQLiteSecrets Feature = "sqlite-secrets"
+)
+
+// supported are the features of this build; the build tag dependent ones
+// are added by init functions.
+var supported = map[Feature]bool{
+	Zstd: true,
+}
+
+// Report describes the features of a build of the library, e.g. to be sent
+// to remote clients.
+type Report struct {
+	// Version is the version of the library.
+	Version string `json:"version"`
+	// Features are the supported features, sorted.
+	Features []Feature `json:"features"`
+}
+
+// Supported returns true if the feature is supported by this build.
+func Supported(feature Feature) bool {
+	return supported[feature]
+}
+
+// List returns the features supported by this build, sorted.
+func List() []Feature {
+	list := make([]Feature, 0, len(supported))
+	for feature, ok := range supported {
+		if ok {
+			list = append(list, feature)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// Local returns the report of this build.
+func Local() *Report {
+	return &Report{Version: version.Version, Features: List()}
+}
+
+// Negotiate returns the features supported by both this build and the peer,
+// sorted.  Features unknown to this build are ignored.
+func Negotiate(peer []Feature) []Feature {
+	common := []Feature{}
+	seen := make(map[Feature]bool, len(peer))
+	for _, feature := range peer {
+		if supported[feature] && !seen[feature] {
+			seen[feature] = true
+			common = append(common, feature)
+		}
+	}
+	sort.Slice(common, func(i, j int) bool { return common[i] < common[j] })
+	return common
+}
+
+// Supported returns true if the feature is in the report.
+func (r *Report) Supported(feature Feature) bool {
+	for _, f := range r.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
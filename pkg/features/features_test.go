@@ -0,0 +1,28 @@
+package features
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/containers/common/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures(t *testing.T) {
+	assert.True(t, Supported(Zstd))
+	assert.False(t, Supported(ZstdChunked))
+	assert.False(t, Supported("unknown"))
+	assert.Contains(t, List(), Zstd)
+
+	assert.Equal(t, []Feature{Zstd}, Negotiate([]Feature{Zstd, ZstdChunked, Zstd, "unknown"}))
+	assert.Equal(t, []Feature{}, Negotiate(nil))
+
+	data, err := json.Marshal(Local())
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, version.Version, report.Version)
+	assert.True(t, report.Supported(Zstd))
+	assert.False(t, report.Supported(Netavark))
+}
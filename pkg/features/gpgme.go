@@ -0,0 +1,7 @@
+// +build !containers_image_openpgp
+
+package features
+
+func init() {
+	supported[GPGME] = true
+}
@@ -0,0 +1,7 @@
+// +build linux,seccomp
+
+package features
+
+func init() {
+	supported[Seccomp] = true
+}
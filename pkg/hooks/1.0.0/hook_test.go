@@ -0,0 +1,55 @@
+package hook
+
+import (
+	"os"
+	"testing"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoodRead(t *testing.T) {
+	hook, err := Read([]byte("{\"version\": \"1.0.0\", \"hook\": {\"path\": \"/a/b/c\"}, \"when\": {\"always\": true}, \"stages\": [\"prestart\"]}"))
+	require.NoError(t, err)
+	always := true
+	require.Equal(t, &Hook{
+		Version: Version,
+		Hook:    rspec.Hook{Path: "/a/b/c"},
+		When:    When{Always: &always},
+		Stages:  []string{"prestart"},
+	}, hook)
+}
+
+func TestInvalidJSON(t *testing.T) {
+	_, err := Read([]byte("{"))
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name string
+		hook *Hook
+		err  string
+	}{
+		{"nil", nil, "nil hook"},
+		{"version", &Hook{Version: "0.1.0"}, "unexpected hook version"},
+		{"path", &Hook{Version: Version}, "missing required property: hook.path"},
+		{"stages", &Hook{Version: Version, Hook: rspec.Hook{Path: executable}}, "missing required property: stages"},
+		{"stage", &Hook{Version: Version, Hook: rspec.Hook{Path: executable}, Stages: []string{"none"}}, "unknown stage"},
+		{"annotation", &Hook{Version: Version, Hook: rspec.Hook{Path: executable}, Stages: []string{"prestart"}, When: When{Annotations: map[string]string{"[": "a"}}}, "invalid annotation key"},
+		{"command", &Hook{Version: Version, Hook: rspec.Hook{Path: executable}, Stages: []string{"prestart"}, When: When{Commands: []string{"["}}}, "invalid command"},
+		{"pattern", &Hook{Version: Version, Hook: rspec.Hook{Path: executable}, Stages: []string{"prestart"}, When: When{Networks: []string{"["}}}, "invalid pattern"},
+		{"label", &Hook{Version: Version, Hook: rspec.Hook{Path: executable}, Stages: []string{"prestart"}, When: When{ImageLabels: map[string]string{"gpu": "["}}}, "invalid image label value"},
+	} {
+		err := test.hook.Validate([]string{})
+		require.Error(t, err, test.name)
+		require.Contains(t, err.Error(), test.err, test.name)
+	}
+
+	hook := &Hook{Version: Version, Hook: rspec.Hook{Path: executable}, Stages: []string{"poststop", "custom"}}
+	require.Error(t, hook.Validate([]string{}))
+	require.NoError(t, hook.Validate([]string{"custom"}))
+}
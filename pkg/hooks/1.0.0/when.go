@@ -0,0 +1,197 @@
+package hook
+
+import (
+	"path"
+	"regexp"
+	"sort"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// When holds hook-injection conditions. All set conditions must match,
+// except for Commands, ImageNames and Networks where a single matching
+// pattern suffices.
+type When struct {
+	Always        *bool             `json:"always,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+	Commands      []string          `json:"commands,omitempty"`
+	HasBindMounts *bool             `json:"hasBindMounts,omitempty"`
+
+	// ImageNames are glob patterns (see path.Match) matched against the
+	// names of the image of the container, e.g. "quay.io/nvidia/*".
+	ImageNames []string `json:"imageNames,omitempty"`
+	// ImageLabels map the labels the image of the container must have to
+	// glob patterns of their values, e.g. {"gpu": "true"}.
+	ImageLabels map[string]string `json:"imageLabels,omitempty"`
+	// Networks are glob patterns matched against the names of the
+	// networks the container is attached to.
+	Networks []string `json:"networks,omitempty"`
+}
+
+// Container holds the properties of a container, besides its OCI
+// configuration, which hooks are matched against.
+type Container struct {
+	Annotations   map[string]string
+	HasBindMounts bool
+	// ImageNames are the names of the image, e.g.
+	// "docker.io/library/fedora:latest".
+	ImageNames  []string
+	ImageLabels map[string]string
+	// Networks are the names of the networks the container is attached
+	// to.
+	Networks []string
+}
+
+// Match returns true if the given conditions match the configuration.
+func (when *When) Match(config *rspec.Spec, annotations map[string]string, hasBindMounts bool) (match bool, err error) {
+	return when.MatchContainer(config, &Container{Annotations: annotations, HasBindMounts: hasBindMounts})
+}
+
+// MatchContainer returns true if the given conditions match the
+// configuration and the properties of the container.
+func (when *When) MatchContainer(config *rspec.Spec, container *Container) (match bool, err error) {
+	criteria, err := when.Explain(config, container)
+	if err != nil {
+		return false, err
+	}
+	for _, criterion := range criteria {
+		if !criterion.Matched {
+			return false, nil
+		}
+	}
+	return len(criteria) > 0, nil
+}
+
+// Criterion is the result of evaluating a single condition.
+type Criterion struct {
+	// Name of the condition, i.e. its JSON property (e.g., "annotations").
+	Name string
+	// Pattern is the pattern of the condition for annotations and image
+	// labels, which are evaluated separately for every entry.
+	Pattern string
+	Matched bool
+	// Value is the property of the container which matched, if any.
+	Value string
+}
+
+// Explain evaluates all set conditions against the configuration and the
+// properties of the container.  The hook matches if there is at least one
+// criterion and all criteria matched.  Commands are ignored if the
+// configuration has no process.
+func (when *When) Explain(config *rspec.Spec, container *Container) ([]Criterion, error) {
+	var criteria []Criterion
+
+	if when.Always != nil {
+		criteria = append(criteria, Criterion{Name: "always", Matched: *when.Always})
+	}
+
+	if when.HasBindMounts != nil {
+		criteria = append(criteria, Criterion{
+			Name:    "hasBindMounts",
+			Matched: *when.HasBindMounts && container.HasBindMounts,
+		})
+	}
+
+	keyPatterns := make([]string, 0, len(when.Annotations))
+	for keyPattern := range when.Annotations {
+		keyPatterns = append(keyPatterns, keyPattern)
+	}
+	sort.Strings(keyPatterns)
+	for _, keyPattern := range keyPatterns {
+		valuePattern := when.Annotations[keyPattern]
+		criterion := Criterion{Name: "annotations", Pattern: keyPattern + "=" + valuePattern}
+		for key, value := range container.Annotations {
+			match, err := regexp.MatchString(keyPattern, key)
+			if err != nil {
+				return nil, errors.Wrap(err, "annotation key")
+			}
+			if !match {
+				continue
+			}
+			match, err = regexp.MatchString(valuePattern, value)
+			if err != nil {
+				return nil, errors.Wrap(err, "annotation value")
+			}
+			if match {
+				criterion.Matched, criterion.Value = true, key+"="+value
+				break
+			}
+		}
+		criteria = append(criteria, criterion)
+	}
+
+	if len(when.ImageNames) > 0 {
+		value, err := matchAny(when.ImageNames, container.ImageNames)
+		if err != nil {
+			return nil, errors.Wrap(err, "image name")
+		}
+		criteria = append(criteria, Criterion{Name: "imageNames", Matched: value != "", Value: value})
+	}
+
+	labels := make([]string, 0, len(when.ImageLabels))
+	for key := range when.ImageLabels {
+		labels = append(labels, key)
+	}
+	sort.Strings(labels)
+	for _, key := range labels {
+		valuePattern := when.ImageLabels[key]
+		criterion := Criterion{Name: "imageLabels", Pattern: key + "=" + valuePattern}
+		if value, ok := container.ImageLabels[key]; ok {
+			match, err := path.Match(valuePattern, value)
+			if err != nil {
+				return nil, errors.Wrap(err, "image label value")
+			}
+			if match {
+				criterion.Matched, criterion.Value = true, key+"="+value
+			}
+		}
+		criteria = append(criteria, criterion)
+	}
+
+	if len(when.Networks) > 0 {
+		value, err := matchAny(when.Networks, container.Networks)
+		if err != nil {
+			return nil, errors.Wrap(err, "network")
+		}
+		criteria = append(criteria, Criterion{Name: "networks", Matched: value != "", Value: value})
+	}
+
+	if config.Process != nil && len(when.Commands) > 0 {
+		if len(config.Process.Args) == 0 {
+			return nil, errors.New("process.args must have at least one entry")
+		}
+		command := config.Process.Args[0]
+		criterion := Criterion{Name: "commands"}
+		for _, cmdPattern := range when.Commands {
+			match, err := regexp.MatchString(cmdPattern, command)
+			if err != nil {
+				return nil, errors.Wrap(err, "command")
+			}
+			if match {
+				criterion.Matched, criterion.Pattern, criterion.Value = true, cmdPattern, command
+				break
+			}
+		}
+		criteria = append(criteria, criterion)
+	}
+
+	return criteria, nil
+}
+
+// matchAny returns the first value matching any of the glob patterns, or
+// an empty string if none matches.
+func matchAny(patterns, values []string) (string, error) {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			match, err := path.Match(pattern, value)
+			if err != nil {
+				return "", err
+			}
+			if match {
+				return value, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,115 @@
+package hook
+
+import (
+	"testing"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoMatch(t *testing.T) {
+	config := &rspec.Spec{}
+	for _, o := range []bool{true, false} {
+		when := When{}
+		match, err := when.Match(config, map[string]string{}, o)
+		require.NoError(t, err)
+		require.False(t, match)
+	}
+}
+
+func TestAlways(t *testing.T) {
+	config := &rspec.Spec{}
+	for _, always := range []bool{true, false} {
+		always := always
+		when := When{Always: &always}
+		match, err := when.Match(config, map[string]string{}, false)
+		require.NoError(t, err)
+		require.Equal(t, always, match)
+	}
+}
+
+func TestHasBindMounts(t *testing.T) {
+	config := &rspec.Spec{}
+	hasBindMounts := true
+	when := When{HasBindMounts: &hasBindMounts}
+	for _, bindMounts := range []bool{true, false} {
+		match, err := when.Match(config, map[string]string{}, bindMounts)
+		require.NoError(t, err)
+		require.Equal(t, bindMounts, match)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	config := &rspec.Spec{}
+	when := When{Annotations: map[string]string{"^a$": "^b$", "^c$": "^d$"}}
+	for _, test := range []struct {
+		annotations map[string]string
+		match       bool
+	}{
+		{map[string]string{"a": "b", "c": "d"}, true},
+		{map[string]string{"a": "b", "c": "d", "e": "f"}, true},
+		{map[string]string{"a": "b"}, false},
+		{map[string]string{"a": "b", "c": "x"}, false},
+	} {
+		match, err := when.Match(config, test.annotations, false)
+		require.NoError(t, err)
+		require.Equal(t, test.match, match, "%v", test.annotations)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	when := When{Commands: []string{"^/bin/sh$"}}
+	match, err := when.Match(&rspec.Spec{Process: &rspec.Process{Args: []string{"/bin/sh"}}}, map[string]string{}, false)
+	require.NoError(t, err)
+	require.True(t, match)
+
+	match, err = when.Match(&rspec.Spec{Process: &rspec.Process{Args: []string{"/bin/bash"}}}, map[string]string{}, false)
+	require.NoError(t, err)
+	require.False(t, match)
+
+	_, err = when.Match(&rspec.Spec{Process: &rspec.Process{}}, map[string]string{}, false)
+	require.Error(t, err)
+}
+
+func TestInvalidRegexp(t *testing.T) {
+	when := When{Annotations: map[string]string{"[": "a"}}
+	_, err := when.Match(&rspec.Spec{}, map[string]string{"a": "b"}, false)
+	require.Error(t, err)
+}
+
+func TestImageAndNetworks(t *testing.T) {
+	config := &rspec.Spec{}
+	when := When{
+		ImageNames:  []string{"quay.io/nvidia/*"},
+		ImageLabels: map[string]string{"gpu": "true"},
+		Networks:    []string{"hpc-*"},
+	}
+	container := &Container{
+		ImageNames:  []string{"localhost/cuda:latest", "quay.io/nvidia/cuda:11"},
+		ImageLabels: map[string]string{"gpu": "true", "vendor": "nvidia"},
+		Networks:    []string{"podman", "hpc-fabric"},
+	}
+	match, err := when.MatchContainer(config, container)
+	require.NoError(t, err)
+	require.True(t, match)
+
+	for _, c := range []*Container{
+		{ImageNames: container.ImageNames, ImageLabels: container.ImageLabels},
+		{ImageNames: container.ImageNames, ImageLabels: map[string]string{"gpu": "false"}, Networks: container.Networks},
+		{ImageNames: []string{"docker.io/library/fedora:latest"}, ImageLabels: container.ImageLabels, Networks: container.Networks},
+	} {
+		match, err := when.MatchContainer(config, c)
+		require.NoError(t, err)
+		require.False(t, match, "%+v", c)
+	}
+
+	// Labels alone are sufficient.
+	when = When{ImageLabels: map[string]string{"gpu": "*"}}
+	match, err = when.MatchContainer(config, container)
+	require.NoError(t, err)
+	require.True(t, match)
+
+	when = When{Networks: []string{"["}}
+	_, err = when.MatchContainer(config, container)
+	require.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	current "github.com/containers/common/pkg/hooks/1.0.0"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/pkg/blobinfocache/none"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage/pkg/ioutils"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ArtifactSuffix is the suffix of hook directory entries referencing
+	// a hook distributed as an OCI artifact.
+	ArtifactSuffix = ".artifact"
+
+	// ConfigMediaType is the media type of the artifact layer holding the
+	// hook configuration.  The path of the hook is replaced by the path of
+	// the fetched executable.
+	ConfigMediaType = "application/vnd.containers.hook.config.v1+json"
+
+	// BinaryMediaType is the media type of the artifact layer holding the
+	// hook executable.
+	BinaryMediaType = "application/vnd.containers.hook.binary.v1"
+)
+
+// ArtifactReference is the content of a hook directory entry referencing a
+// hook distributed as an OCI artifact.
+type ArtifactReference struct {
+	// Reference of the artifact, e.g. "quay.io/fleet/gpu-hook:1.0".
+	// References without a transport default to "docker://".
+	Reference string `json:"reference"`
+	// BinaryDigest pins the digest of the hook executable.  If set, the
+	// fetched executable must match it.
+	BinaryDigest digest.Digest `json:"binaryDigest,omitempty"`
+}
+
+// FetchArtifacts fetches the hooks referenced by the ArtifactSuffix entries
+// of the directories and stores them in cacheDir, which can then be passed
+// as the last directory to New.  The configuration of a hook referenced by
+// "gpu.artifact" is stored as "gpu.json".  Hooks which cannot be fetched
+// are logged and skipped, such that a registry outage does not prevent
+// other hooks from being used; the first error is returned.
+func FetchArtifacts(ctx context.Context, sys *types.SystemContext, directories []string, cacheDir string) error {
+	var res error
+	for _, dir := range directories {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for _, file := range files {
+			if !strings.HasSuffix(file.Name(), ArtifactSuffix) {
+				continue
+			}
+			path := filepath.Join(dir, file.Name())
+			if err := FetchArtifact(ctx, sys, path, cacheDir); err != nil {
+				logrus.Errorf("Failed to fetch hook %s: %v", path, err)
+				if res == nil {
+					res = err
+				}
+			}
+		}
+	}
+	return res
+}
+
+// FetchArtifact fetches the hook referenced by the ArtifactSuffix entry at
+// path and stores its configuration and executable in cacheDir.
+func FetchArtifact(ctx context.Context, sys *types.SystemContext, path, cacheDir string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var artifact ArtifactReference
+	if err := json.Unmarshal(content, &artifact); err != nil {
+		return errors.Wrapf(err, "parsing hook artifact reference %q", path)
+	}
+	if artifact.Reference == "" {
+		return errors.Errorf("hook artifact reference %q: missing required property: reference", path)
+	}
+	if artifact.BinaryDigest != "" {
+		if err := artifact.BinaryDigest.Validate(); err != nil {
+			return errors.Wrapf(err, "hook artifact reference %q: invalid binaryDigest", path)
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ArtifactSuffix)
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	binaryPath := filepath.Join(cacheDir, name+".bin")
+	hook, err := fetchArtifact(ctx, sys, &artifact, binaryPath)
+	if err != nil {
+		return errors.Wrapf(err, "fetching hook %s", artifact.Reference)
+	}
+	hook.Hook.Path = binaryPath
+	// The extension stages are only known to the Manager reading the hook.
+	if err := hook.Validate(hook.Stages); err != nil {
+		return errors.Wrapf(err, "validating hook %s", artifact.Reference)
+	}
+
+	config, err := json.Marshal(hook)
+	if err != nil {
+		return err
+	}
+	return ioutils.AtomicWriteFile(filepath.Join(cacheDir, name+".json"), config, 0644)
+}
+
+// fetchArtifact downloads the hook configuration and writes the executable
+// to binaryPath after verifying its digest.
+func fetchArtifact(ctx context.Context, sys *types.SystemContext, artifact *ArtifactReference, binaryPath string) (*current.Hook, error) {
+	ref, err := alltransports.ParseImageName(artifact.Reference)
+	if err != nil {
+		// No transport, e.g. "quay.io/fleet/gpu-hook:1.0".
+		dockerRef, dockerErr := alltransports.ParseImageName("docker://" + artifact.Reference)
+		if dockerErr != nil {
+			return nil, err
+		}
+		ref = dockerRef
+	}
+
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	manifestBytes, _, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifest.OCI1FromManifest(manifestBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing artifact manifest")
+	}
+
+	var hook *current.Hook
+	haveBinary := false
+	for _, layer := range m.Layers {
+		switch layer.MediaType {
+		case ConfigMediaType:
+			var buf strings.Builder
+			if err := fetchBlob(ctx, src, layer.Digest, layer.Size, &buf); err != nil {
+				return nil, err
+			}
+			if hook, err = current.Read([]byte(buf.String())); err != nil {
+				return nil, errors.Wrap(err, "parsing hook configuration")
+			}
+		case BinaryMediaType:
+			if artifact.BinaryDigest != "" && artifact.BinaryDigest != layer.Digest {
+				return nil, errors.Errorf("hook executable has digest %s, expected %s", layer.Digest, artifact.BinaryDigest)
+			}
+			if err := fetchExecutable(ctx, src, layer.Digest, layer.Size, binaryPath); err != nil {
+				return nil, err
+			}
+			haveBinary = true
+		}
+	}
+	if hook == nil {
+		return nil, errors.Errorf("artifact has no layer of type %s", ConfigMediaType)
+	}
+	if !haveBinary {
+		return nil, errors.Errorf("artifact has no layer of type %s", BinaryMediaType)
+	}
+	return hook, nil
+}
+
+// fetchBlob copies the blob to w and verifies its digest.
+func fetchBlob(ctx context.Context, src types.ImageSource, blobDigest digest.Digest, size int64, w io.Writer) error {
+	if err := blobDigest.Validate(); err != nil {
+		return err
+	}
+	blob, _, err := src.GetBlob(ctx, types.BlobInfo{Digest: blobDigest, Size: size}, none.NoCache)
+	if err != nil {
+		return errors.Wrapf(err, "fetching blob %s", blobDigest)
+	}
+	defer blob.Close()
+
+	verifier := blobDigest.Verifier()
+	if _, err := io.Copy(io.MultiWriter(w, verifier), blob); err != nil {
+		return errors.Wrapf(err, "fetching blob %s", blobDigest)
+	}
+	if !verifier.Verified() {
+		return errors.Errorf("blob %s does not match its digest", blobDigest)
+	}
+	return nil
+}
+
+// fetchExecutable writes the verified blob to path, replacing an existing
+// executable only once the new one has been fetched completely.
+func fetchExecutable(ctx context.Context, src types.ImageSource, blobDigest digest.Digest, size int64, path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := fetchBlob(ctx, src, blobDigest, size, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBlob stores the blob in the OCI layout and returns its descriptor.
+func writeBlob(t *testing.T, layout, mediaType string, content []byte) imgspecv1.Descriptor {
+	d := digest.FromBytes(content)
+	dir := filepath.Join(layout, "blobs", d.Algorithm().String())
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, d.Encoded()), content, 0644))
+	return imgspecv1.Descriptor{MediaType: mediaType, Digest: d, Size: int64(len(content))}
+}
+
+// writeArtifact creates an OCI layout with a hook artifact tagged "latest".
+func writeArtifact(t *testing.T, layout string, binary []byte) digest.Digest {
+	config := []byte(`{"version": "1.0.0", "hook": {"path": "/placeholder", "args": ["gpu-hook"]}, "when": {"always": true}, "stages": ["prestart"]}`)
+	configDesc := writeBlob(t, layout, ConfigMediaType, config)
+	binaryDesc := writeBlob(t, layout, BinaryMediaType, binary)
+	emptyDesc := writeBlob(t, layout, "application/vnd.oci.image.config.v1+json", []byte("{}"))
+
+	m := imgspecv1.Manifest{Config: emptyDesc, Layers: []imgspecv1.Descriptor{configDesc, binaryDesc}}
+	m.SchemaVersion = 2
+	manifestBytes, err := json.Marshal(m)
+	require.NoError(t, err)
+	manifestDesc := writeBlob(t, layout, imgspecv1.MediaTypeImageManifest, manifestBytes)
+	manifestDesc.Annotations = map[string]string{imgspecv1.AnnotationRefName: "latest"}
+
+	index := imgspecv1.Index{Manifests: []imgspecv1.Descriptor{manifestDesc}}
+	index.SchemaVersion = 2
+	indexBytes, err := json.Marshal(index)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layout, "index.json"), indexBytes, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layout, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`), 0644))
+	return binaryDesc.Digest
+}
+
+func TestFetchArtifacts(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	layout := filepath.Join(tmp, "layout")
+	binary := []byte("#!/bin/sh\nexit 0\n")
+	binaryDigest := writeArtifact(t, layout, binary)
+
+	hooksDir := filepath.Join(tmp, "hooks.d")
+	cacheDir := filepath.Join(tmp, "cache")
+	require.NoError(t, os.MkdirAll(hooksDir, 0755))
+	reference := fmt.Sprintf(`{"reference": "oci:%s:latest", "binaryDigest": %q}`, layout, binaryDigest)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hooksDir, "gpu"+ArtifactSuffix), []byte(reference), 0644))
+
+	require.NoError(t, FetchArtifacts(context.Background(), nil, []string{hooksDir, filepath.Join(tmp, "missing")}, cacheDir))
+	content, err := ioutil.ReadFile(filepath.Join(cacheDir, "gpu.bin"))
+	require.NoError(t, err)
+	require.Equal(t, binary, content)
+
+	manager, err := New(context.Background(), []string{hooksDir, cacheDir}, []string{})
+	require.NoError(t, err)
+	effective := manager.Effective()
+	require.Len(t, effective, 1)
+	require.Equal(t, "gpu.json", effective[0].Name)
+	require.Equal(t, filepath.Join(cacheDir, "gpu.bin"), effective[0].Hook.Hook.Path)
+	require.Equal(t, []string{"gpu-hook"}, effective[0].Hook.Hook.Args)
+
+	// A pinned digest which does not match is rejected.
+	reference = fmt.Sprintf(`{"reference": "oci:%s:latest", "binaryDigest": %q}`, layout, digest.FromString("other"))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hooksDir, "gpu"+ArtifactSuffix), []byte(reference), 0644))
+	err = FetchArtifacts(context.Background(), nil, []string{hooksDir}, cacheDir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected "+digest.FromString("other").String())
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hooksDir, "gpu"+ArtifactSuffix), []byte(`{}`), 0644))
+	require.Error(t, FetchArtifacts(context.Background(), nil, []string{hooksDir}, cacheDir))
+}
@@ -0,0 +1,75 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+const sh = "/bin/sh"
+
+func TestRun(t *testing.T) {
+	hook := &rspec.Hook{
+		Path: sh,
+		Args: []string{"sh", "-c", "cat; echo oops >&2"},
+	}
+	var stdout, stderr bytes.Buffer
+	hookErr, err := Run(context.Background(), hook, []byte("{}"), &stdout, &stderr, DefaultPostKillTimeout)
+	require.NoError(t, err)
+	require.NoError(t, hookErr)
+	require.Equal(t, "{}", stdout.String())
+	require.Equal(t, "oops\n", stderr.String())
+}
+
+func TestRunFailure(t *testing.T) {
+	hook := &rspec.Hook{
+		Path: sh,
+		Args: []string{"sh", "-c", "exit 1"},
+	}
+	hookErr, err := Run(context.Background(), hook, []byte("{}"), nil, nil, DefaultPostKillTimeout)
+	require.Error(t, err)
+	require.Error(t, hookErr)
+	require.Contains(t, hookErr.Error(), "exit status 1")
+}
+
+func TestRunTimeout(t *testing.T) {
+	timeout := 1
+	hook := &rspec.Hook{
+		Path:    sh,
+		Args:    []string{"sh", "-c", "exec sleep 10"},
+		Timeout: &timeout,
+	}
+	hookErr, err := Run(context.Background(), hook, []byte("{}"), nil, nil, DefaultPostKillTimeout)
+	require.Equal(t, context.DeadlineExceeded, hookErr)
+	require.Error(t, err)
+}
+
+func TestRunHooks(t *testing.T) {
+	hooks := []Hook{
+		{Name: "ok.json", Hook: rspec.Hook{Path: sh, Args: []string{"sh", "-c", "echo ok"}}},
+		{Name: "best-effort.json", Hook: rspec.Hook{Path: sh, Args: []string{"sh", "-c", "echo failed >&2; exit 1"}}},
+		{Name: "slow.json", Hook: rspec.Hook{Path: sh, Args: []string{"sh", "-c", "exec sleep 10"}}},
+		{Name: "required.json", Required: true, Hook: rspec.Hook{Path: sh, Args: []string{"sh", "-c", "exit 2"}}},
+		{Name: "skipped.json", Hook: rspec.Hook{Path: sh, Args: []string{"sh", "-c", "exit 0"}}},
+	}
+	results, err := RunHooks(context.Background(), hooks, []byte("{}"), &RunOptions{DefaultTimeout: 100 * time.Millisecond})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "required hook required.json failed")
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	require.Equal(t, "ok\n", string(results[0].Stdout))
+	require.Error(t, results[1].Err)
+	require.Equal(t, "failed\n", string(results[1].Stderr))
+	require.Equal(t, context.DeadlineExceeded, results[2].Err)
+	require.Equal(t, "required.json", results[3].Name)
+	require.Error(t, results[3].Err)
+
+	results, err = RunHooks(context.Background(), hooks[:2], []byte("{}"), nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+}
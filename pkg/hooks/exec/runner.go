@@ -0,0 +1,88 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a hook run by RunHooks.
+type Hook struct {
+	rspec.Hook
+	// Name identifies the hook in results and errors, usually the
+	// filename of its configuration.
+	Name string
+	// Required hooks abort the execution of the remaining hooks when they
+	// fail.  Failures of other hooks are logged and ignored.
+	Required bool
+}
+
+// Result is the outcome of running a hook.
+type Result struct {
+	Name   string
+	Stdout []byte
+	Stderr []byte
+	// Err is the error of the hook, nil if it exited successfully.
+	Err      error
+	Duration time.Duration
+}
+
+// RunOptions are the options of RunHooks.
+type RunOptions struct {
+	// DefaultTimeout limits the run time of hooks without a timeout of
+	// their own.  Zero means no limit.
+	DefaultTimeout time.Duration
+	// PostKillTimeout is the time to wait for a hook to exit after it has
+	// been killed.  It defaults to DefaultPostKillTimeout.
+	PostKillTimeout time.Duration
+}
+
+// RunHooks runs the hooks in order, passing them the serialized container
+// state, and returns the results of the executed hooks.  If a required hook
+// fails, the remaining hooks are skipped and an error is returned; for
+// example, an engine should then abort the creation of the container.
+func RunHooks(ctx context.Context, hooks []Hook, state []byte, options *RunOptions) ([]Result, error) {
+	if options == nil {
+		options = &RunOptions{}
+	}
+	postKillTimeout := options.PostKillTimeout
+	if postKillTimeout == 0 {
+		postKillTimeout = DefaultPostKillTimeout
+	}
+
+	results := make([]Result, 0, len(hooks))
+	for i := range hooks {
+		hook := &hooks[i]
+		hookCtx, cancel := ctx, context.CancelFunc(func() {})
+		if hook.Timeout == nil && options.DefaultTimeout > 0 {
+			hookCtx, cancel = context.WithTimeout(ctx, options.DefaultTimeout)
+		}
+
+		var stdout, stderr bytes.Buffer
+		start := time.Now()
+		hookErr, err := Run(hookCtx, &hook.Hook, state, &stdout, &stderr, postKillTimeout)
+		cancel()
+		results = append(results, Result{
+			Name:     hook.Name,
+			Stdout:   stdout.Bytes(),
+			Stderr:   stderr.Bytes(),
+			Err:      hookErr,
+			Duration: time.Since(start),
+		})
+		if err != nil && err != hookErr {
+			logrus.Errorf("Hook %s: %v", hook.Name, err)
+		}
+		if hookErr == nil {
+			continue
+		}
+		if hook.Required {
+			return results, errors.Wrapf(hookErr, "required hook %s failed", hook.Name)
+		}
+		logrus.Warnf("Ignoring failure of hook %s: %v", hook.Name, hookErr)
+	}
+	return results, nil
+}
@@ -0,0 +1,51 @@
+package hooks
+
+import (
+	current "github.com/containers/common/pkg/hooks/1.0.0"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// Explanation describes why a hook does or does not match a container.
+type Explanation struct {
+	// Name of the hook, i.e. the filename of its configuration.
+	Name string
+	// Source is the path of the configuration file of the hook.
+	Source  string
+	Matched bool
+	// Stages of the hook.  Matching hooks are added to the stages in the
+	// order of the explanations.
+	Stages []string
+	// Criteria are the evaluated conditions of the hook.  A hook without
+	// criteria never matches.
+	Criteria []current.Criterion
+}
+
+// Explain returns for all loaded hooks, in the order they would be injected,
+// whether they match the container and which of their conditions matched,
+// without modifying the configuration.
+func (m *Manager) Explain(config *rspec.Spec, container *current.Container) ([]Explanation, error) {
+	hooks := m.namedHooks()
+	explanations := make([]Explanation, 0, len(hooks))
+	for _, hook := range hooks {
+		criteria, err := hook.hook.When.Explain(config, container)
+		if err != nil {
+			return nil, errors.Wrapf(err, "matching hook %q", hook.name)
+		}
+		matched := len(criteria) > 0
+		for _, criterion := range criteria {
+			if !criterion.Matched {
+				matched = false
+				break
+			}
+		}
+		explanations = append(explanations, Explanation{
+			Name:     hook.name,
+			Source:   hook.source,
+			Matched:  matched,
+			Stages:   hook.hook.Stages,
+			Criteria: criteria,
+		})
+	}
+	return explanations, nil
+}
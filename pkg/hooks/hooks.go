@@ -0,0 +1,237 @@
+// Package hooks implements the OCI hook configuration and handling.
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	current "github.com/containers/common/pkg/hooks/1.0.0"
+	"github.com/containers/common/pkg/hooks/exec"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Version is the current hook configuration version.
+const Version = current.Version
+
+const (
+	// DefaultDir is the default directory containing system hook configuration files.
+	DefaultDir = "/usr/share/containers/oci/hooks.d"
+
+	// OverrideDir is the directory for hook configuration files overriding the default entries.
+	OverrideDir = "/etc/containers/oci/hooks.d"
+)
+
+// Manager provides an opaque interface for managing OCI hooks.
+type Manager struct {
+	directories     []string
+	extensionStages []string
+
+	// lock protects hooks and sources which are replaced as a whole on
+	// reload.
+	lock    sync.RWMutex
+	hooks   map[string]*current.Hook
+	sources map[string]string
+}
+
+type namedHook struct {
+	name   string
+	source string
+	hook   *current.Hook
+}
+
+// EffectiveHook is a hook currently loaded by the Manager.
+type EffectiveHook struct {
+	// Name of the hook, i.e. the filename of its configuration.
+	Name string
+	// Source is the path of the configuration file the hook was read
+	// from.
+	Source string
+	Hook   *current.Hook
+}
+
+// New creates a new hook manager.  Directories are ordered by
+// increasing preference (hook configurations in later directories
+// override configurations with the same filename from earlier
+// directories).  Directories which do not exist are skipped.
+//
+// extensionStages allows callers to add additional stages beyond
+// those specified in the OCI Runtime Specification and to control
+// OCI-defined stages instead of delegating to the OCI runtime.  See
+// Hooks() for more information.
+func New(ctx context.Context, directories []string, extensionStages []string) (*Manager, error) {
+	manager := &Manager{
+		directories:     directories,
+		extensionStages: extensionStages,
+	}
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// Reload re-reads all hook directories and replaces the hook set of the
+// manager at once, such that concurrent Hooks() calls see either the old or
+// the new set.  If any hook cannot be read, the previous set is kept and the
+// error is returned.
+func (m *Manager) Reload() error {
+	hooks := make(map[string]*current.Hook)
+	sources := make(map[string]string)
+	for _, dir := range m.directories {
+		dirHooks := make(map[string]*current.Hook)
+		if err := ReadDir(dir, m.extensionStages, dirHooks); err != nil {
+			if os.IsNotExist(err) {
+				if err2, ok := err.(*os.PathError); ok && err2.Path == dir {
+					continue
+				}
+			}
+			return err
+		}
+		for name, hook := range dirHooks {
+			hooks[name] = hook
+			sources[name] = filepath.Join(dir, name)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.hooks = hooks
+	m.sources = sources
+	return nil
+}
+
+// Effective returns the currently loaded hooks, sorted by name.
+func (m *Manager) Effective() []EffectiveHook {
+	hooks := m.namedHooks()
+	effective := make([]EffectiveHook, 0, len(hooks))
+	for _, h := range hooks {
+		effective = append(effective, EffectiveHook{Name: h.name, Source: h.source, Hook: h.hook})
+	}
+	return effective
+}
+
+// namedHooks returns the hook entries sorted by name.
+func (m *Manager) namedHooks() []*namedHook {
+	m.lock.RLock()
+	hooks := make([]*namedHook, 0, len(m.hooks))
+	for name, hook := range m.hooks {
+		hooks = append(hooks, &namedHook{name: name, source: m.sources[name], hook: hook})
+	}
+	m.lock.RUnlock()
+
+	sort.Slice(hooks, func(i, j int) bool {
+		a, b := strings.ToLower(hooks[i].name), strings.ToLower(hooks[j].name)
+		if a != b {
+			return a < b
+		}
+		return hooks[i].name < hooks[j].name
+	})
+	return hooks
+}
+
+// Hooks injects OCI runtime hooks for a given container configuration.
+//
+// If extensionStages was set when initializing the Manager,
+// matching hooks requesting those stages will be returned in
+// extensionStageHooks.  This takes precedence over their inclusion
+// in the OCI configuration.  For example:
+//
+//   manager, err := New(ctx, []string{DefaultDir}, []string{"poststop"})
+//   extensionStageHooks, err := manager.Hooks(config, annotations, hasBindMounts)
+//
+// will have any matching post-stop hooks in extensionStageHooks and
+// will not insert them into config.Hooks.Poststop.
+func (m *Manager) Hooks(config *rspec.Spec, annotations map[string]string, hasBindMounts bool) (extensionStageHooks map[string][]rspec.Hook, err error) {
+	return m.ContainerHooks(config, &current.Container{Annotations: annotations, HasBindMounts: hasBindMounts})
+}
+
+// ContainerHooks is like Hooks but additionally matches the hooks against
+// the image and the networks of the container.
+func (m *Manager) ContainerHooks(config *rspec.Spec, container *current.Container) (extensionStageHooks map[string][]rspec.Hook, err error) {
+	stageHooks, err := m.inject(config, container)
+	for stage, hooks := range stageHooks {
+		if extensionStageHooks == nil {
+			extensionStageHooks = map[string][]rspec.Hook{}
+		}
+		for _, namedHook := range hooks {
+			extensionStageHooks[stage] = append(extensionStageHooks[stage], namedHook.hook.Hook)
+		}
+	}
+	return extensionStageHooks, err
+}
+
+// ExecHooks is like ContainerHooks but returns the extension stage hooks
+// along with their names and required flags, ready to be run with
+// exec.RunHooks.
+func (m *Manager) ExecHooks(config *rspec.Spec, container *current.Container) (map[string][]exec.Hook, error) {
+	stageHooks, err := m.inject(config, container)
+	var extensionStageHooks map[string][]exec.Hook
+	for stage, hooks := range stageHooks {
+		if extensionStageHooks == nil {
+			extensionStageHooks = map[string][]exec.Hook{}
+		}
+		for _, namedHook := range hooks {
+			extensionStageHooks[stage] = append(extensionStageHooks[stage], exec.Hook{
+				Hook:     namedHook.hook.Hook,
+				Name:     namedHook.name,
+				Required: namedHook.hook.Required,
+			})
+		}
+	}
+	return extensionStageHooks, err
+}
+
+// inject adds the matching hooks to the configuration and returns the
+// matching hooks of the extension stages.
+func (m *Manager) inject(config *rspec.Spec, container *current.Container) (extensionStageHooks map[string][]*namedHook, err error) {
+	localStages := map[string]bool{} // stages destined for extensionStageHooks
+	for _, stage := range m.extensionStages {
+		localStages[stage] = true
+	}
+	for _, hook := range m.namedHooks() {
+		match, err := hook.hook.When.MatchContainer(config, container)
+		if err != nil {
+			return extensionStageHooks, errors.Wrapf(err, "matching hook %q", hook.name)
+		}
+		if !match {
+			logrus.Debugf("Hook %s did not match", hook.name)
+			continue
+		}
+		logrus.Debugf("Hook %s matched; adding to stages %v", hook.name, hook.hook.Stages)
+		if config.Hooks == nil {
+			config.Hooks = &rspec.Hooks{}
+		}
+		for _, stage := range hook.hook.Stages {
+			if localStages[stage] {
+				if extensionStageHooks == nil {
+					extensionStageHooks = map[string][]*namedHook{}
+				}
+				extensionStageHooks[stage] = append(extensionStageHooks[stage], hook)
+				continue
+			}
+			switch stage {
+			case "createContainer":
+				config.Hooks.CreateContainer = append(config.Hooks.CreateContainer, hook.hook.Hook)
+			case "createRuntime":
+				config.Hooks.CreateRuntime = append(config.Hooks.CreateRuntime, hook.hook.Hook)
+			case "prestart":
+				config.Hooks.Prestart = append(config.Hooks.Prestart, hook.hook.Hook)
+			case "poststart":
+				config.Hooks.Poststart = append(config.Hooks.Poststart, hook.hook.Hook)
+			case "poststop":
+				config.Hooks.Poststop = append(config.Hooks.Poststop, hook.hook.Hook)
+			case "startContainer":
+				config.Hooks.StartContainer = append(config.Hooks.StartContainer, hook.hook.Hook)
+			default:
+				return extensionStageHooks, errors.Errorf("hook %q: unknown stage %q", hook.name, stage)
+			}
+		}
+	}
+
+	return extensionStageHooks, nil
+}
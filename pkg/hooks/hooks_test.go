@@ -0,0 +1,179 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	current "github.com/containers/common/pkg/hooks/1.0.0"
+	"github.com/containers/common/pkg/hooks/exec"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+// path is the path to an example hook executable.
+var path string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "hooks-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	path = filepath.Join(dir, "hook")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func writeHook(t *testing.T, dir, name, stage string) {
+	content := fmt.Sprintf(`{"version": "1.0.0", "hook": {"path": %q}, "when": {"always": true}, "stages": [%q]}`, path, stage)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestGoodNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for i, name := range []string{"a.json", "b.json", "c.json"} {
+		stage := "prestart"
+		if i == 1 {
+			stage = "poststop"
+		}
+		writeHook(t, dir, name, stage)
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a hook"), 0644))
+
+	manager, err := New(context.Background(), []string{dir, filepath.Join(dir, "missing")}, []string{"poststop"})
+	require.NoError(t, err)
+
+	config := &rspec.Spec{}
+	extensionStageHooks, err := manager.Hooks(config, map[string]string{}, false)
+	require.NoError(t, err)
+
+	hook := rspec.Hook{Path: path}
+	require.Equal(t, &rspec.Hooks{Prestart: []rspec.Hook{hook, hook}}, config.Hooks)
+	require.Equal(t, map[string][]rspec.Hook{"poststop": {hook}}, extensionStageHooks)
+}
+
+func TestBadNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte("{\"version\": \"-1\"}"), 0644))
+
+	_, err = New(context.Background(), []string{dir}, []string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unrecognized hook version")
+}
+
+func TestEffectiveAndReload(t *testing.T) {
+	defaults, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(defaults)
+	overrides, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(overrides)
+
+	writeHook(t, defaults, "a.json", "prestart")
+	writeHook(t, defaults, "b.json", "prestart")
+	writeHook(t, overrides, "b.json", "poststop")
+
+	manager, err := New(context.Background(), []string{defaults, overrides}, []string{})
+	require.NoError(t, err)
+
+	effective := manager.Effective()
+	require.Len(t, effective, 2)
+	require.Equal(t, "a.json", effective[0].Name)
+	require.Equal(t, filepath.Join(defaults, "a.json"), effective[0].Source)
+	require.Equal(t, filepath.Join(overrides, "b.json"), effective[1].Source)
+	require.Equal(t, []string{"poststop"}, effective[1].Hook.Stages)
+
+	// A broken hook keeps the previous set.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(overrides, "c.json"), []byte("{"), 0644))
+	require.Error(t, manager.Reload())
+	require.Len(t, manager.Effective(), 2)
+
+	require.NoError(t, os.Remove(filepath.Join(overrides, "c.json")))
+	require.NoError(t, os.Remove(filepath.Join(defaults, "a.json")))
+	require.NoError(t, manager.Reload())
+	effective = manager.Effective()
+	require.Len(t, effective, 1)
+	require.Equal(t, "b.json", effective[0].Name)
+}
+
+func TestRead(t *testing.T) {
+	_, err := Read("does-not-end-in-json", []string{})
+	require.Equal(t, ErrNoJSONSuffix, err)
+
+	hooks := map[string]*current.Hook{}
+	err = ReadDir(filepath.Join(os.TempDir(), "does-not-exist-hooks"), []string{}, hooks)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestExecHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := fmt.Sprintf(`{"version": "1.0.0", "hook": {"path": %q}, "when": {"always": true}, "stages": ["precreate", "prestart"], "required": true}`, path)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(content), 0644))
+
+	manager, err := New(context.Background(), []string{dir}, []string{"precreate"})
+	require.NoError(t, err)
+
+	config := &rspec.Spec{}
+	hooks, err := manager.ExecHooks(config, &current.Container{})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]exec.Hook{
+		"precreate": {{Name: "a.json", Required: true, Hook: rspec.Hook{Path: path}}},
+	}, hooks)
+	require.Equal(t, []rspec.Hook{{Path: path}}, config.Hooks.Prestart)
+}
+
+func TestExplain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeHook(t, dir, "b.json", "prestart")
+	content := fmt.Sprintf(`{"version": "1.0.0", "hook": {"path": %q}, "when": {"annotations": {"^gpu$": "^true$"}, "networks": ["hpc-*"]}, "stages": ["poststop"]}`, path)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(content), 0644))
+
+	manager, err := New(context.Background(), []string{dir}, []string{})
+	require.NoError(t, err)
+
+	config := &rspec.Spec{}
+	container := &current.Container{Annotations: map[string]string{"gpu": "true"}, Networks: []string{"podman"}}
+	explanations, err := manager.Explain(config, container)
+	require.NoError(t, err)
+	require.Nil(t, config.Hooks)
+	require.Equal(t, []Explanation{
+		{
+			Name:    "a.json",
+			Source:  filepath.Join(dir, "a.json"),
+			Matched: false,
+			Stages:  []string{"poststop"},
+			Criteria: []current.Criterion{
+				{Name: "annotations", Pattern: "^gpu$=^true$", Matched: true, Value: "gpu=true"},
+				{Name: "networks", Matched: false},
+			},
+		},
+		{
+			Name:     "b.json",
+			Source:   filepath.Join(dir, "b.json"),
+			Matched:  true,
+			Stages:   []string{"prestart"},
+			Criteria: []current.Criterion{{Name: "always", Matched: true}},
+		},
+	}, explanations)
+}
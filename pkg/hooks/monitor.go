@@ -0,0 +1,62 @@
+package hooks
+
+import (
+	"context"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// Monitor dynamically monitors hook directories for additions,
+// updates, and removals and reloads the hook set on changes.
+//
+// This function writes two errors to the sync channel: the first is
+// written after the watchers are established and the second when this
+// function exits.  The expected usage is:
+//
+//   ctx, cancel := context.WithCancel(context.Background())
+//   sync := make(chan error, 2)
+//   go m.Monitor(ctx, sync)
+//   err := <-sync // block until writers are established
+//   if err != nil {
+//     return err // failed to establish watchers
+//   }
+//   // do stuff
+//   cancel()
+//   err = <-sync // block until monitor finishes
+func (m *Manager) Monitor(ctx context.Context, sync chan<- error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		sync <- err
+		return
+	}
+	defer watcher.Close()
+
+	for _, dir := range m.directories {
+		if err := watcher.Add(dir); err != nil {
+			logrus.Errorf("Failed to watch %q for hooks", dir)
+			sync <- err
+			return
+		}
+		logrus.Debugf("Monitoring %q for hooks", dir)
+	}
+
+	sync <- nil
+
+	for {
+		select {
+		case event := <-watcher.Events:
+			if err := m.Reload(); err != nil {
+				logrus.Errorf("Failed to reload hooks after change of %s, keeping the previous hooks: %v", event.Name, err)
+			}
+		case err := <-watcher.Errors:
+			logrus.Errorf("Error watching hook directories: %v", err)
+		case <-ctx.Done():
+			err = ctx.Err()
+			logrus.Debugf("Hook monitoring canceled: %v", err)
+			sync <- err
+			close(sync)
+			return
+		}
+	}
+}
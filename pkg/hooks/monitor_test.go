@@ -0,0 +1,36 @@
+package hooks
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	manager, err := New(context.Background(), []string{dir}, []string{})
+	require.NoError(t, err)
+	require.Empty(t, manager.Effective())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sync := make(chan error, 2)
+	go manager.Monitor(ctx, sync)
+	require.NoError(t, <-sync)
+
+	writeHook(t, dir, "a.json", "prestart")
+	require.Eventually(t, func() bool { return len(manager.Effective()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.json")))
+	require.Eventually(t, func() bool { return len(manager.Effective()) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-sync)
+}
@@ -0,0 +1,321 @@
+package parse
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	units "github.com/docker/go-units"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// MountType is the type of a Mount.
+type MountType string
+
+const (
+	// BindMount mounts a host path.
+	BindMount MountType = "bind"
+	// VolumeMount mounts a named or anonymous volume.
+	VolumeMount MountType = "volume"
+	// TmpfsMount mounts a new tmpfs.
+	TmpfsMount MountType = "tmpfs"
+	// ImageMount mounts the root filesystem of an image.
+	ImageMount MountType = "image"
+)
+
+// Mount is a mount parsed from the --mount or --volume syntax.
+type Mount struct {
+	Type MountType
+	// Source is the host path of bind mounts, the name of volumes and
+	// the image of image mounts.  It is empty for anonymous volumes and
+	// tmpfs mounts.
+	Source string
+	// Destination is the absolute path in the container.
+	Destination string
+	// SubPath is the path within the volume or image to mount.
+	SubPath string
+	// ReadOnly mounts are not writable in the container.
+	ReadOnly bool
+	// Propagation is the mount propagation of bind mounts, e.g. "rshared".
+	Propagation string
+	// NonRecursive bind mounts do not include the submounts of the source.
+	NonRecursive bool
+	// Relabel is "z" for shared and "Z" for private SELinux relabeling.
+	Relabel string
+	// Chown changes the owner of the source to the user of the container.
+	Chown bool
+	// Overlay mounts the source as an overlay with a writable upper
+	// directory.
+	Overlay bool
+	// Options are the remaining mount options, e.g. "nosuid" or
+	// "size=64m" for tmpfs mounts.
+	Options []string
+}
+
+var propagationModes = map[string]bool{
+	"private": true, "rprivate": true, "shared": true, "rshared": true,
+	"slave": true, "rslave": true, "unbindable": true, "runbindable": true,
+}
+
+// passThroughOptions are the mount options passed to the runtime.
+var passThroughOptions = map[string]bool{
+	"nosuid": true, "suid": true, "nodev": true, "dev": true, "noexec": true, "exec": true,
+}
+
+// ParseMount parses the value of the --mount flag, e.g.
+// "type=bind,source=/srv,destination=/data,ro,bind-propagation=rslave".
+func ParseMount(value string) (*Mount, error) {
+	m := &Mount{}
+	var tmpfsSize, tmpfsMode string
+	for _, field := range strings.Split(value, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		key, val := kv[0], ""
+		if len(kv) == 2 {
+			val = kv[1]
+		}
+		boolVal := func() (bool, error) {
+			if len(kv) == 1 {
+				return true, nil
+			}
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return false, errors.Errorf("invalid value %q of mount option %q", val, key)
+			}
+			return b, nil
+		}
+		var err error
+		switch key {
+		case "type":
+			m.Type = MountType(val)
+		case "source", "src":
+			m.Source = val
+		case "target", "destination", "dst":
+			m.Destination = val
+		case "ro", "readonly":
+			m.ReadOnly, err = boolVal()
+		case "rw", "readwrite":
+			var rw bool
+			rw, err = boolVal()
+			m.ReadOnly = !rw
+		case "bind-propagation":
+			m.Propagation = val
+		case "bind-nonrecursive":
+			m.NonRecursive, err = boolVal()
+		case "relabel":
+			switch val {
+			case "shared":
+				m.Relabel = "z"
+			case "private":
+				m.Relabel = "Z"
+			default:
+				return nil, errors.Errorf("invalid relabel value %q, must be shared or private", val)
+			}
+		case "U", "chown":
+			m.Chown, err = boolVal()
+		case "subpath", "volume-subpath":
+			m.SubPath = val
+		case "tmpfs-size":
+			tmpfsSize = val
+		case "tmpfs-mode":
+			tmpfsMode = val
+		default:
+			if passThroughOptions[key] && len(kv) == 1 {
+				m.Options = append(m.Options, key)
+				continue
+			}
+			return nil, errors.Errorf("invalid mount option %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.Type == "" {
+		return nil, errors.New("mount type must be specified")
+	}
+	if tmpfsSize != "" || tmpfsMode != "" {
+		if m.Type != TmpfsMount {
+			return nil, errors.Errorf("tmpfs options are not supported by %s mounts", m.Type)
+		}
+		if tmpfsSize != "" {
+			if _, err := units.RAMInBytes(tmpfsSize); err != nil {
+				return nil, errors.Wrapf(err, "invalid tmpfs-size %q", tmpfsSize)
+			}
+			m.Options = append(m.Options, "size="+tmpfsSize)
+		}
+		if tmpfsMode != "" {
+			if _, err := strconv.ParseUint(tmpfsMode, 8, 32); err != nil {
+				return nil, errors.Errorf("invalid tmpfs-mode %q, must be octal", tmpfsMode)
+			}
+			m.Options = append(m.Options, "mode="+tmpfsMode)
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseVolume parses the value of the --volume flag, i.e.
+// "[SOURCE:]DESTINATION[:OPTIONS]".  Absolute sources are bind mounts,
+// other sources are named volumes and volumes without a source are
+// anonymous.
+func ParseVolume(value string) (*Mount, error) {
+	arr := strings.Split(value, ":")
+	m := &Mount{Type: VolumeMount}
+	var options string
+	switch len(arr) {
+	case 1:
+		m.Destination = arr[0]
+	case 2:
+		if err := ValidateVolumeCtrDir(arr[1]); err == nil {
+			m.Source, m.Destination = arr[0], arr[1]
+		} else {
+			m.Destination, options = arr[0], arr[1]
+		}
+	case 3:
+		m.Source, m.Destination, options = arr[0], arr[1], arr[2]
+	default:
+		return nil, errors.Errorf("invalid volume specification %q", value)
+	}
+	if filepath.IsAbs(m.Source) {
+		m.Type = BindMount
+	}
+
+	var opts []string
+	if options != "" {
+		var err error
+		if opts, err = ValidateVolumeOpts(strings.Split(options, ",")); err != nil {
+			return nil, err
+		}
+	}
+	for _, opt := range opts {
+		switch {
+		case opt == "ro":
+			m.ReadOnly = true
+		case opt == "rw":
+		case opt == "z" || opt == "Z":
+			m.Relabel = opt
+		case opt == "O":
+			m.Overlay = true
+		case opt == "U":
+			m.Chown = true
+		case opt == "bind":
+			m.NonRecursive = true
+		case opt == "rbind":
+		case propagationModes[opt]:
+			m.Propagation = opt
+		default:
+			m.Options = append(m.Options, opt)
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks the mount for the host platform.
+func (m *Mount) Validate() error {
+	return m.validate(runtime.GOOS)
+}
+
+func (m *Mount) validate(goos string) error {
+	if err := ValidateVolumeCtrDir(m.Destination); err != nil {
+		return err
+	}
+	switch m.Type {
+	case BindMount:
+		if m.Source == "" {
+			return errors.New("bind mounts require a source")
+		}
+		if !filepath.IsAbs(m.Source) {
+			return errors.Errorf("invalid bind mount source %q, must be an absolute path", m.Source)
+		}
+	case VolumeMount:
+		if m.Source == "" && m.SubPath != "" {
+			return errors.New("anonymous volumes do not support subpath")
+		}
+	case ImageMount:
+		if m.Source == "" {
+			return errors.New("image mounts require a source image")
+		}
+	case TmpfsMount:
+		if m.Source != "" {
+			return errors.New("tmpfs mounts do not support a source")
+		}
+	default:
+		return errors.Errorf("invalid mount type %q", m.Type)
+	}
+
+	if m.SubPath != "" {
+		if m.Type != VolumeMount && m.Type != ImageMount {
+			return errors.Errorf("subpath is not supported by %s mounts", m.Type)
+		}
+		if filepath.IsAbs(m.SubPath) || strings.HasPrefix(filepath.Clean(m.SubPath), "..") {
+			return errors.Errorf("invalid subpath %q, must be a relative path within the %s", m.SubPath, m.Type)
+		}
+	}
+	if m.Propagation != "" || m.NonRecursive {
+		if m.Type != BindMount {
+			return errors.Errorf("bind options are not supported by %s mounts", m.Type)
+		}
+		if m.Propagation != "" && !propagationModes[m.Propagation] {
+			return errors.Errorf("invalid bind-propagation %q", m.Propagation)
+		}
+		if m.Propagation != "" && goos != "linux" {
+			return errors.Errorf("bind-propagation is not supported on %s", goos)
+		}
+	}
+	if m.Relabel != "" && m.Type != BindMount && m.Type != VolumeMount {
+		return errors.Errorf("relabeling is not supported by %s mounts", m.Type)
+	}
+	if m.Overlay && (m.Type == TmpfsMount || m.Type == ImageMount) {
+		return errors.Errorf("overlay is not supported by %s mounts", m.Type)
+	}
+	if m.Type == ImageMount && m.Chown {
+		return errors.New("chown is not supported by image mounts")
+	}
+	return nil
+}
+
+// SpecMount returns the OCI mount of bind and tmpfs mounts.  Volume and
+// image mounts require the engine to resolve their source.
+func (m *Mount) SpecMount() (rspec.Mount, error) {
+	options := []string{"rw"}
+	if m.ReadOnly {
+		options[0] = "ro"
+	}
+	switch m.Type {
+	case BindMount:
+		bind := "rbind"
+		if m.NonRecursive {
+			bind = "bind"
+		}
+		propagation := m.Propagation
+		if propagation == "" {
+			propagation = "rprivate"
+		}
+		options = append(append(options, bind, propagation), m.Options...)
+		return rspec.Mount{Type: "bind", Source: m.Source, Destination: m.Destination, Options: options}, nil
+	case TmpfsMount:
+		options = append(options, m.Options...)
+		for _, defaultOption := range []string{"noexec", "nosuid", "nodev"} {
+			if !hasOption(options, defaultOption) && !hasOption(options, strings.TrimPrefix(defaultOption, "no")) {
+				options = append(options, defaultOption)
+			}
+		}
+		return rspec.Mount{Type: "tmpfs", Source: "tmpfs", Destination: m.Destination, Options: options}, nil
+	}
+	return rspec.Mount{}, errors.Errorf("%s mounts have no OCI mount without resolving their source", m.Type)
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package parse
+
+import (
+	"testing"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMount(t *testing.T) {
+	m, err := ParseMount("type=bind,src=/srv,target=/data,ro,bind-propagation=rslave,relabel=private,nosuid")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{
+		Type:        BindMount,
+		Source:      "/srv",
+		Destination: "/data",
+		ReadOnly:    true,
+		Propagation: "rslave",
+		Relabel:     "Z",
+		Options:     []string{"nosuid"},
+	}, m)
+	spec, err := m.SpecMount()
+	require.NoError(t, err)
+	assert.Equal(t, rspec.Mount{Type: "bind", Source: "/srv", Destination: "/data", Options: []string{"ro", "rbind", "rslave", "nosuid"}}, spec)
+
+	m, err = ParseMount("type=tmpfs,destination=/tmp,tmpfs-size=64m,tmpfs-mode=1777,exec")
+	require.NoError(t, err)
+	spec, err = m.SpecMount()
+	require.NoError(t, err)
+	assert.Equal(t, rspec.Mount{Type: "tmpfs", Source: "tmpfs", Destination: "/tmp", Options: []string{"rw", "exec", "size=64m", "mode=1777", "nosuid", "nodev"}}, spec)
+
+	m, err = ParseMount("type=image,source=fedora,destination=/image,subpath=usr/share,rw=true")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{Type: ImageMount, Source: "fedora", Destination: "/image", SubPath: "usr/share"}, m)
+	_, err = m.SpecMount()
+	assert.Error(t, err)
+
+	m, err = ParseMount("type=volume,source=data,destination=/data,volume-subpath=app,U")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{Type: VolumeMount, Source: "data", Destination: "/data", SubPath: "app", Chown: true}, m)
+
+	for _, value := range []string{
+		"source=/srv,destination=/data",
+		"type=nfs,destination=/data",
+		"type=bind,source=srv,destination=/data",
+		"type=bind,source=/srv,destination=data",
+		"type=bind,source=/srv,destination=/data,bind-propagation=both",
+		"type=bind,source=/srv,destination=/data,subpath=foo",
+		"type=bind,source=/srv,destination=/data,ro=maybe",
+		"type=bind,source=/srv,destination=/data,unknown=1",
+		"type=volume,source=data,destination=/data,bind-propagation=rshared",
+		"type=volume,source=data,destination=/data,subpath=../etc",
+		"type=volume,destination=/data,subpath=app",
+		"type=volume,source=data,destination=/data,tmpfs-size=1g",
+		"type=tmpfs,destination=/tmp,tmpfs-size=lots",
+		"type=tmpfs,destination=/tmp,tmpfs-mode=999",
+		"type=image,destination=/image",
+		"type=image,source=fedora,destination=/image,relabel=shared",
+	} {
+		_, err := ParseMount(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseVolume(t *testing.T) {
+	m, err := ParseVolume("/srv:/data:ro,z,rshared,noexec")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{
+		Type:        BindMount,
+		Source:      "/srv",
+		Destination: "/data",
+		ReadOnly:    true,
+		Relabel:     "z",
+		Propagation: "rshared",
+		Options:     []string{"noexec"},
+	}, m)
+
+	m, err = ParseVolume("data:/data")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{Type: VolumeMount, Source: "data", Destination: "/data"}, m)
+
+	m, err = ParseVolume("/data:O")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{Type: VolumeMount, Destination: "/data", Overlay: true}, m)
+
+	m, err = ParseVolume("/anonymous")
+	require.NoError(t, err)
+	assert.Equal(t, &Mount{Type: VolumeMount, Destination: "/anonymous"}, m)
+
+	for _, value := range []string{"data", "/srv:/data:ro,rw", "/srv:/data:foo", "a:b:c:d", "data:/data:rshared"} {
+		_, err := ParseVolume(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestValidateMountPlatform(t *testing.T) {
+	m := &Mount{Type: BindMount, Source: "/srv", Destination: "/data", Propagation: "rshared"}
+	assert.NoError(t, m.validate("linux"))
+	assert.Error(t, m.validate("windows"))
+	m.Propagation = ""
+	assert.NoError(t, m.validate("windows"))
+}
@@ -32,6 +32,24 @@ or
 	})
 	// t.IsTable() == true
 
+Tables:
+
+report.NewTableWriter() may be used instead of a Writer to align the columns
+to the widest cell of all rows, right-aligning numeric columns.
+
+Structured output:
+
+Formats starting with "csv " or equal to "ndjson" select CSV and
+newline-delimited JSON output of the same data:
+
+	if report.IsStructured(format) {
+		w, err := report.NewStructuredWriter(os.Stdout, format)
+		...
+		w.WriteHeaders(headers)
+		w.Write(data)
+		w.Flush()
+	}
+
 Helpers:
 
 	if report.IsJSON(cmd.Flag("format").Value.String()) {
@@ -41,10 +59,15 @@ Helpers:
 Template Functions:
 
 The following template functions are added to the template when parsed:
+	- humanDuration  duration, or time since, {{ .Created | humanDuration }}
+	- humanSize  size in bytes, {{ .Size | humanSize }}
 	- join  strings.Join, {{join .Field separator}}
+	- json  encoding/json, {{ json .Field }}
 	- lower strings.ToLower {{ .Field | lower }}
+	- pad   pads non-empty fields with spaces, {{pad .Field prefix suffix}}
 	- split strings.Split {{ .Field | split }}
 	- title strings.Title {{ .Field | title }}
+	- truncate  first characters, {{truncate .Field length}}
 	- upper strings.ToUpper {{ .Field | upper }}
 
 report.Funcs() may be used to add additional template functions.
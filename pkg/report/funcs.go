@@ -0,0 +1,65 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// humanSize formats a size in bytes like "1.5GB".  Sizes can be of any
+// integer or float type, negative sizes are formatted as "N/A".
+func humanSize(size interface{}) (string, error) {
+	var f float64
+	switch s := size.(type) {
+	case int:
+		f = float64(s)
+	case int32:
+		f = float64(s)
+	case int64:
+		f = float64(s)
+	case uint:
+		f = float64(s)
+	case uint32:
+		f = float64(s)
+	case uint64:
+		f = float64(s)
+	case float32:
+		f = float64(s)
+	case float64:
+		f = s
+	default:
+		return "", fmt.Errorf("humanSize: unsupported type %T", size)
+	}
+	if f < 0 {
+		return "N/A", nil
+	}
+	return units.HumanSizeWithPrecision(f, 3), nil
+}
+
+// humanDuration formats a duration like "3 hours".  A time is formatted as
+// the duration since then followed by "ago", like "3 hours ago", or as
+// "Never" if it is the zero time.
+func humanDuration(d interface{}) (string, error) {
+	switch v := d.(type) {
+	case time.Duration:
+		return units.HumanDuration(v), nil
+	case *time.Duration:
+		return units.HumanDuration(*v), nil
+	case time.Time:
+		return humanSince(v), nil
+	case *time.Time:
+		return humanSince(*v), nil
+	case int64:
+		// Unix timestamp, as used in many API structs.
+		return humanSince(time.Unix(v, 0)), nil
+	}
+	return "", fmt.Errorf("humanDuration: unsupported type %T", d)
+}
+
+func humanSince(t time.Time) string {
+	if t.IsZero() {
+		return "Never"
+	}
+	return units.HumanDuration(time.Since(t)) + " ago"
+}
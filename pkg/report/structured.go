@@ -0,0 +1,183 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CSVPrefix selects CSV output with the columns of the rest of the
+	// format, e.g. "csv {{.ID}} {{.Size | humanSize}}".
+	CSVPrefix = "csv "
+	// NDJSONFormat selects newline-delimited JSON output, one object per
+	// line.
+	NDJSONFormat = "ndjson"
+)
+
+// fieldRegex matches the first field referenced by a column template.
+var fieldRegex = regexp.MustCompile(`\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// IsCSV returns true if the format selects CSV output.
+func IsCSV(format string) bool {
+	return strings.HasPrefix(format, CSVPrefix)
+}
+
+// IsNDJSON returns true if the format selects newline-delimited JSON output.
+func IsNDJSON(format string) bool {
+	return strings.TrimSpace(format) == NDJSONFormat
+}
+
+// IsStructured returns true if the format selects an output written by a
+// StructuredWriter.
+func IsStructured(format string) bool {
+	return IsCSV(format) || IsNDJSON(format)
+}
+
+// StructuredWriter writes the data passed to Go templates as CSV or
+// newline-delimited JSON, see NewStructuredWriter.
+type StructuredWriter struct {
+	output  io.Writer
+	csv     *csv.Writer
+	columns []*template.Template
+	fields  []string
+}
+
+// NewStructuredWriter returns a writer of the CSV or NDJSON output selected
+// by the format.  CSV columns are separated by spaces or tabs, like the
+// columns of a "table" format, and may use the template functions of
+// DefaultFuncs.
+func NewStructuredWriter(output io.Writer, format string) (*StructuredWriter, error) {
+	w := &StructuredWriter{output: output}
+	switch {
+	case IsNDJSON(format):
+		return w, nil
+	case IsCSV(format):
+	default:
+		return nil, errors.Errorf("format %q is neither %q nor starts with %q", format, NDJSONFormat, CSVPrefix)
+	}
+
+	for _, column := range splitColumns(escapedReplacer.Replace(strings.TrimPrefix(format, CSVPrefix))) {
+		t, err := template.New(column).Funcs(template.FuncMap(DefaultFuncs)).Parse(column)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing CSV column %q", column)
+		}
+		w.columns = append(w.columns, t)
+		field := ""
+		if m := fieldRegex.FindStringSubmatch(column); m != nil {
+			field = m[1]
+		}
+		w.fields = append(w.fields, field)
+	}
+	if len(w.columns) == 0 {
+		return nil, errors.Errorf("format %q has no CSV columns", format)
+	}
+	w.csv = csv.NewWriter(output)
+	return w, nil
+}
+
+// WriteHeaders writes the CSV header row.  The header of a column is the
+// header of the first field it references, as returned by Headers.  It is
+// a no-op for NDJSON output.
+func (w *StructuredWriter) WriteHeaders(headers []map[string]string) error {
+	if w.csv == nil {
+		return nil
+	}
+	var h map[string]string
+	if len(headers) > 0 {
+		h = headers[0]
+	}
+	row := make([]string, len(w.fields))
+	for i, field := range w.fields {
+		if header, ok := h[field]; ok {
+			row[i] = header
+		} else {
+			row[i] = strings.ToUpper(field)
+		}
+	}
+	return w.csv.Write(row)
+}
+
+// Write writes a row or JSON object per element of data if it is a slice or
+// an array, otherwise a single one for data.
+func (w *StructuredWriter) Write(data interface{}) error {
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := 0; i < value.Len(); i++ {
+			if err := w.writeItem(value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return w.writeItem(data)
+}
+
+func (w *StructuredWriter) writeItem(item interface{}) error {
+	if w.csv == nil {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		_, err = w.output.Write(append(line, '\n'))
+		return err
+	}
+	row := make([]string, len(w.columns))
+	for i, t := range w.columns {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, item); err != nil {
+			return err
+		}
+		row[i] = buf.String()
+	}
+	return w.csv.Write(row)
+}
+
+// Flush writes any buffered rows.
+func (w *StructuredWriter) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// splitColumns splits the format at the spaces and tabs outside of template
+// actions.
+func splitColumns(format string) []string {
+	var (
+		columns []string
+		current strings.Builder
+		depth   int
+	)
+	for i := 0; i < len(format); i++ {
+		switch {
+		case strings.HasPrefix(format[i:], "{{"):
+			depth++
+			current.WriteString("{{")
+			i++
+		case strings.HasPrefix(format[i:], "}}") && depth > 0:
+			depth--
+			current.WriteString("}}")
+			i++
+		case depth == 0 && (format[i] == ' ' || format[i] == '\t' || format[i] == '\n'):
+			if current.Len() > 0 {
+				columns = append(columns, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteByte(format[i])
+		}
+	}
+	if current.Len() > 0 {
+		columns = append(columns, current.String())
+	}
+	return columns
+}
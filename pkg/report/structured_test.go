@@ -0,0 +1,63 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type structuredItem struct {
+	ID   string
+	Name string
+	Size int64
+}
+
+var structuredItems = []structuredItem{
+	{ID: "fa85da03b401", Name: "alpine, latest", Size: 5850000},
+	{ID: "2b1dc1374fc9", Name: "busybox", Size: 1410000},
+}
+
+func TestStructuredFormats(t *testing.T) {
+	assert.True(t, IsCSV("csv {{.ID}}"))
+	assert.False(t, IsCSV("table {{.ID}}"))
+	assert.True(t, IsNDJSON("ndjson"))
+	assert.False(t, IsNDJSON("json"))
+	assert.True(t, IsStructured(" ndjson "))
+	assert.False(t, IsStructured("{{.ID}}"))
+
+	_, err := NewStructuredWriter(&bytes.Buffer{}, "table {{.ID}}")
+	assert.Error(t, err)
+	_, err = NewStructuredWriter(&bytes.Buffer{}, "csv ")
+	assert.Error(t, err)
+	_, err = NewStructuredWriter(&bytes.Buffer{}, "csv {{.ID")
+	assert.Error(t, err)
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewStructuredWriter(&buf, `csv {{.ID}}\t{{ .Name | upper }} {{.Size | humanSize}}`)
+	assert.NoError(t, err)
+	assert.NoError(t, w.WriteHeaders(Headers(structuredItem{}, map[string]string{"Name": "repository"})))
+	assert.NoError(t, w.Write(structuredItems))
+	assert.NoError(t, w.Flush())
+	expected := "ID,REPOSITORY,SIZE\n" +
+		"fa85da03b401,\"ALPINE, LATEST\",5.85MB\n" +
+		"2b1dc1374fc9,BUSYBOX,1.41MB\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewStructuredWriter(&buf, "ndjson")
+	assert.NoError(t, err)
+	assert.NoError(t, w.WriteHeaders(Headers(structuredItem{}, nil)))
+	assert.NoError(t, w.Write(structuredItems))
+	assert.NoError(t, w.Write(structuredItems[0]))
+	assert.NoError(t, w.Flush())
+	expected := `{"ID":"fa85da03b401","Name":"alpine, latest","Size":5850000}
+{"ID":"2b1dc1374fc9","Name":"busybox","Size":1410000}
+{"ID":"fa85da03b401","Name":"alpine, latest","Size":5850000}
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// numericRegex matches cells of numeric columns, e.g. "42", "-1.5",
+// "1.5GB", "3.2 kB" or "12%".
+var numericRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?(\s?([kKMGTPEZY]i?)?B|%)?$`)
+
+// TableWriter writes rows of tab-separated cells as a table.  Each column is
+// as wide as its widest cell.  Columns whose cells below the header are all
+// numeric are right-aligned, other columns can be right-aligned with
+// AlignRight.  The table is written on Flush.
+type TableWriter struct {
+	output  io.Writer
+	padding int
+	right   map[int]bool
+	buf     bytes.Buffer
+}
+
+// NewTableWriter initializes a new TableWriter separating columns by two
+// spaces.
+func NewTableWriter(output io.Writer) *TableWriter {
+	return &TableWriter{output: output, padding: 2, right: make(map[int]bool)}
+}
+
+// AlignRight right-aligns the columns, counted from 0.
+func (w *TableWriter) AlignRight(columns ...int) {
+	for _, c := range columns {
+		w.right[c] = true
+	}
+}
+
+// Write buffers the rows, which are written on Flush.
+func (w *TableWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Flush writes the buffered rows as a table.
+func (w *TableWriter) Flush() error {
+	defer w.buf.Reset()
+	text := strings.TrimSuffix(w.buf.String(), "\n")
+	if text == "" {
+		return nil
+	}
+	var rows [][]string
+	for _, line := range strings.Split(text, "\n") {
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+
+	var widths []int
+	var numeric []bool
+	for r, row := range rows {
+		for c, cell := range row {
+			if c >= len(widths) {
+				widths = append(widths, 0)
+				numeric = append(numeric, true)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[c] {
+				widths[c] = n
+			}
+			if r > 0 && cell != "" && !numericRegex.MatchString(cell) {
+				numeric[c] = false
+			}
+		}
+	}
+	for c := range numeric {
+		// Columns without data below the header are not numeric.
+		numeric[c] = numeric[c] && hasData(rows[1:], c)
+	}
+
+	var out bytes.Buffer
+	for _, row := range rows {
+		var line strings.Builder
+		for c, cell := range row {
+			pad := strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell))
+			if w.right[c] || numeric[c] {
+				line.WriteString(pad + cell)
+			} else {
+				line.WriteString(cell + pad)
+			}
+			line.WriteString(strings.Repeat(" ", w.padding))
+		}
+		// No trailing spaces after the last or empty cells.
+		out.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	_, err := w.output.Write(out.Bytes())
+	return err
+}
+
+// hasData returns true if a row has a non-empty cell in the column.
+func hasData(rows [][]string, column int) bool {
+	for _, row := range rows {
+		if column < len(row) && row[column] != "" {
+			return true
+		}
+	}
+	return false
+}
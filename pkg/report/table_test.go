@@ -0,0 +1,37 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTableWriter(&buf)
+	_, err := w.Write([]byte("REPOSITORY\tTAG\tSIZE\tCPU %\n"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("quay.io/libpod/alpine\tlatest\t5.85MB\t1.5%\nbusybox\tmusl\t1.41 MB\t12%\nfedora\t\t190MB\t\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Flush())
+
+	expected := "" +
+		"REPOSITORY             TAG        SIZE  CPU %\n" +
+		"quay.io/libpod/alpine  latest   5.85MB   1.5%\n" +
+		"busybox                musl    1.41 MB    12%\n" +
+		"fedora                           190MB\n"
+	assert.Equal(t, expected, buf.String())
+
+	// Explicitly right-aligned columns, multi-byte characters
+	buf.Reset()
+	w.AlignRight(0)
+	_, err = w.Write([]byte("NAME\tSTATUS\nü\tUp\nlonger\tExited\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "  NAME  STATUS\n     ü  Up\nlonger  Exited\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "", buf.String())
+}
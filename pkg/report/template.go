@@ -42,12 +42,14 @@ var DefaultFuncs = FuncMap{
 		// Remove the trailing new line added by the encoder
 		return strings.TrimSpace(buf.String())
 	},
-	"lower":    strings.ToLower,
-	"pad":      padWithSpace,
-	"split":    strings.Split,
-	"title":    strings.Title,
-	"truncate": truncateWithLength,
-	"upper":    strings.ToUpper,
+	"humanDuration": humanDuration,
+	"humanSize":     humanSize,
+	"lower":         strings.ToLower,
+	"pad":           padWithSpace,
+	"split":         strings.Split,
+	"title":         strings.Title,
+	"truncate":      truncateWithLength,
+	"upper":         strings.ToUpper,
 }
 
 // NormalizeFormat reads given go template format provided by CLI and munges it into what we need
@@ -76,10 +78,12 @@ func padWithSpace(source string, prefix, suffix int) string {
 
 // truncateWithLength truncates the source string up to the length provided by the input
 func truncateWithLength(source string, length int) string {
-	if len(source) < length {
+	// Count characters, not bytes, to not split multi-byte characters.
+	runes := []rune(source)
+	if len(runes) < length {
 		return source
 	}
-	return source[:length]
+	return string(runes[:length])
 }
 
 // Headers queries the interface for field names.
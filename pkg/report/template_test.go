@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -163,3 +164,34 @@ func TestTemplate_EnforceRange(t *testing.T) {
 	assert.Equal(t, testRange, EnforceRange("foobar was here"))
 	assert.NotEqual(t, testRange, EnforceRange("foobar"))
 }
+
+func TestTemplate_HumanFuncs(t *testing.T) {
+	tmpl, e := NewTemplate("TestTemplate").Parse(`{{humanSize .Size}} {{humanSize .Virtual}} {{humanDuration .Duration}} {{humanDuration .Zero}} {{truncate .Name 4}}`)
+	assert.NoError(t, e)
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, struct {
+		Size     int64
+		Virtual  float64
+		Duration time.Duration
+		Zero     time.Time
+		Name     string
+	}{
+		Size:     5850000,
+		Virtual:  -1,
+		Duration: 3 * time.Hour,
+		Name:     "überlong",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "5.85MB N/A 3 hours Never über\n", buf.String())
+
+	created := time.Now().Add(-2 * time.Minute)
+	s, err := humanDuration(created.Unix())
+	assert.NoError(t, err)
+	assert.Equal(t, "2 minutes ago", s)
+
+	_, err = humanSize("1GB")
+	assert.Error(t, err)
+	_, err = humanDuration("1h")
+	assert.Error(t, err)
+}
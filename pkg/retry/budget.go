@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	randLock sync.Mutex
+	// random is seeded per process, such that the jitter of processes
+	// started at the same time differs.
+	random = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func randFloat64() float64 {
+	randLock.Lock()
+	defer randLock.Unlock()
+	return random.Float64()
+}
+
+// Budget limits the retries of the operations sharing it, e.g. all pulls
+// from a registry, to avoid amplifying outages and rate limits.  Each
+// failure of an operation spends one token and each success earns tokens.
+// Retries are only attempted while more than half of the tokens are
+// available, i.e. the budget acts as a circuit breaker once most operations
+// fail.  A Budget is safe for concurrent use.
+type Budget struct {
+	lock      sync.Mutex
+	tokens    float64
+	maxTokens float64
+	ratio     float64
+}
+
+// NewBudget returns a budget of maxTokens tokens, which earns ratio tokens
+// per successful operation, e.g. NewBudget(10, 0.1).
+func NewBudget(maxTokens int, ratio float64) *Budget {
+	return &Budget{tokens: float64(maxTokens), maxTokens: float64(maxTokens), ratio: ratio}
+}
+
+// allow returns true if a retry may be attempted.
+func (b *Budget) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.tokens > b.maxTokens/2
+}
+
+func (b *Budget) onFailure() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.tokens--; b.tokens < 0 {
+		b.tokens = 0
+	}
+}
+
+func (b *Budget) onSuccess() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.tokens += b.ratio; b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
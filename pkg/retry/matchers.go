@@ -0,0 +1,55 @@
+package retry
+
+import "sync"
+
+// Classification is the result of a Matcher.
+type Classification int
+
+const (
+	// Unknown leaves the classification to the other matchers and the
+	// built-in rules.
+	Unknown Classification = iota
+	// Retryable errors are retried.
+	Retryable
+	// NotRetryable errors are not retried.
+	NotRetryable
+)
+
+// Matcher classifies errors, e.g. treating the 502 responses of a specific
+// proxy as Retryable.  It is called with the error passed to
+// RetryIfNecessary and with each error wrapped by it, and must return
+// Unknown for errors it does not handle.
+type Matcher func(err error) Classification
+
+var (
+	matchersLock sync.RWMutex
+	matchers     []Matcher
+)
+
+// RegisterMatcher registers the matcher for all operations retried without
+// a RetryOptions.IsErrorRetryable.  Matchers are consulted in the order of
+// registration before the built-in rules; the first classification other
+// than Unknown is used.
+func RegisterMatcher(m Matcher) {
+	matchersLock.Lock()
+	defer matchersLock.Unlock()
+	matchers = append(matchers, m)
+}
+
+// IsErrorRetryable returns true if RetryIfNecessary retries the error by
+// default, i.e. according to the registered matchers and the built-in rules.
+func IsErrorRetryable(err error) bool {
+	return isRetryable(err)
+}
+
+// match returns the classification of the error by the registered matchers.
+func match(err error) Classification {
+	matchersLock.RLock()
+	defer matchersLock.RUnlock()
+	for _, m := range matchers {
+		if c := m(err); c != Unknown {
+			return c
+		}
+	}
+	return Unknown
+}
@@ -20,15 +20,39 @@ import (
 type RetryOptions struct {
 	MaxRetry int           // The number of times to possibly retry
 	Delay    time.Duration // The delay to use between retries, if set
+	// MaxDelay caps the exponential backoff delay, if set.
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to the fraction of it, e.g. 0.2
+	// for delays between 80% and 120% of the backoff, such that clients
+	// failing at the same time do not retry at the same time.
+	Jitter float64
+	// MaxElapsedTime is the maximum time spent on the operation, if set.
+	// No retry is attempted if it would start after MaxElapsedTime.
+	MaxElapsedTime time.Duration
+	// Budget limits the retries of all operations sharing it, if set.
+	Budget *Budget
+	// IsErrorRetryable decides which errors are retried, if set,
+	// replacing the registered matchers and the built-in classification.
+	IsErrorRetryable func(error) bool
 }
 
 // RetryIfNecessary retries the operation in exponential backoff with the retryOptions
 func RetryIfNecessary(ctx context.Context, operation func() error, retryOptions *RetryOptions) error {
-	err := operation()
-	for attempt := 0; err != nil && isRetryable(err) && attempt < retryOptions.MaxRetry; attempt++ {
-		delay := time.Duration(int(math.Pow(2, float64(attempt)))) * time.Second
-		if retryOptions.Delay != 0 {
-			delay = retryOptions.Delay
+	retryable := isRetryable
+	if retryOptions.IsErrorRetryable != nil {
+		retryable = retryOptions.IsErrorRetryable
+	}
+	start := time.Now()
+	err := run(operation, retryOptions.Budget)
+	for attempt := 0; err != nil && retryable(err) && attempt < retryOptions.MaxRetry; attempt++ {
+		delay := retryOptions.delay(attempt)
+		if retryOptions.MaxElapsedTime > 0 && time.Since(start)+delay > retryOptions.MaxElapsedTime {
+			logrus.Debugf("not retrying, maximum elapsed time of %s exceeded", retryOptions.MaxElapsedTime)
+			return err
+		}
+		if retryOptions.Budget != nil && !retryOptions.Budget.allow() {
+			logrus.Debugf("not retrying, retry budget exhausted")
+			return err
 		}
 		logrus.Warnf("failed, retrying in %s ... (%d/%d). Error: %v", delay, attempt+1, retryOptions.MaxRetry, err)
 		select {
@@ -37,13 +61,50 @@ func RetryIfNecessary(ctx context.Context, operation func() error, retryOptions
 		case <-ctx.Done():
 			return err
 		}
-		err = operation()
+		err = run(operation, retryOptions.Budget)
+	}
+	return err
+}
+
+// run runs the operation and records its result in the budget.
+func run(operation func() error, budget *Budget) error {
+	err := operation()
+	if budget != nil {
+		if err != nil {
+			budget.onFailure()
+		} else {
+			budget.onSuccess()
+		}
 	}
 	return err
 }
 
+// delay returns the delay before the retry after the attempt.
+func (o *RetryOptions) delay(attempt int) time.Duration {
+	delay := time.Duration(int(math.Pow(2, float64(attempt)))) * time.Second
+	if o.Delay != 0 {
+		delay = o.Delay
+	}
+	if o.MaxDelay > 0 && delay > o.MaxDelay {
+		delay = o.MaxDelay
+	}
+	if o.Jitter > 0 {
+		delay += time.Duration(o.Jitter * (2*randFloat64() - 1) * float64(delay))
+	}
+	return delay
+}
+
 func isRetryable(err error) bool {
-	err = errors.Cause(err)
+	switch match(err) {
+	case Retryable:
+		return true
+	case NotRetryable:
+		return false
+	}
+
+	if cause := errors.Cause(err); cause != err {
+		return isRetryable(cause)
+	}
 
 	if err == context.Canceled || err == context.DeadlineExceeded {
 		return false
@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryIfNecessary(t *testing.T) {
+	ctx := context.Background()
+	attempts := 0
+	failTwice := func() error {
+		if attempts++; attempts <= 2 {
+			return syscall.ECONNRESET
+		}
+		return nil
+	}
+	err := RetryIfNecessary(ctx, failTwice, &RetryOptions{MaxRetry: 3, Delay: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// Errors not retryable by default
+	attempts = 0
+	errPermanent := errors.New("permanent")
+	fail := func() error {
+		attempts++
+		return errPermanent
+	}
+	err = RetryIfNecessary(ctx, fail, &RetryOptions{MaxRetry: 3, Delay: time.Millisecond})
+	assert.Equal(t, errPermanent, err)
+	assert.Equal(t, 1, attempts)
+
+	// Custom classification
+	attempts = 0
+	err = RetryIfNecessary(ctx, fail, &RetryOptions{MaxRetry: 3, Delay: time.Millisecond, IsErrorRetryable: func(err error) bool {
+		return err == errPermanent
+	}})
+	assert.Equal(t, errPermanent, err)
+	assert.Equal(t, 4, attempts)
+
+	// Maximum elapsed time
+	attempts = 0
+	err = RetryIfNecessary(ctx, func() error {
+		attempts++
+		return syscall.ECONNRESET
+	}, &RetryOptions{MaxRetry: 10, Delay: 20 * time.Millisecond, MaxElapsedTime: 50 * time.Millisecond})
+	assert.Equal(t, syscall.ECONNRESET, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryDelay(t *testing.T) {
+	options := RetryOptions{}
+	assert.Equal(t, time.Second, options.delay(0))
+	assert.Equal(t, 8*time.Second, options.delay(3))
+
+	options.MaxDelay = 5 * time.Second
+	assert.Equal(t, 5*time.Second, options.delay(3))
+
+	options.Delay = 2 * time.Second
+	assert.Equal(t, 2*time.Second, options.delay(3))
+
+	options.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := options.delay(0)
+		assert.True(t, delay >= time.Second && delay <= 3*time.Second, delay)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	ctx := context.Background()
+	budget := NewBudget(4, 1)
+	attempts := 0
+	fail := func() error {
+		attempts++
+		return syscall.ECONNRESET
+	}
+	// Two failures spend half of the budget, no further retries
+	err := RetryIfNecessary(ctx, fail, &RetryOptions{MaxRetry: 5, Delay: time.Millisecond, Budget: budget})
+	assert.Error(t, err)
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	err = RetryIfNecessary(ctx, fail, &RetryOptions{MaxRetry: 5, Delay: time.Millisecond, Budget: budget})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// Successes earn tokens again
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, RetryIfNecessary(ctx, func() error { return nil }, &RetryOptions{Budget: budget}))
+	}
+	assert.True(t, budget.allow())
+}
+
+type proxyError struct {
+	status int
+}
+
+func (e *proxyError) Error() string {
+	return "proxy error"
+}
+
+func TestRegisterMatcher(t *testing.T) {
+	defer func(saved []Matcher) { matchers = saved }(matchers)
+
+	badGateway := errors.Wrap(&proxyError{status: 502}, "pulling image")
+	assert.False(t, IsErrorRetryable(badGateway))
+	assert.True(t, IsErrorRetryable(syscall.ECONNRESET))
+
+	RegisterMatcher(func(err error) Classification {
+		if e, ok := err.(*proxyError); ok && e.status == 502 {
+			return Retryable
+		}
+		return Unknown
+	})
+	RegisterMatcher(func(err error) Classification {
+		if err == syscall.ECONNRESET {
+			return NotRetryable
+		}
+		return Unknown
+	})
+	assert.True(t, IsErrorRetryable(badGateway))
+	assert.False(t, IsErrorRetryable(&proxyError{status: 500}))
+	assert.False(t, IsErrorRetryable(errors.Wrap(syscall.ECONNRESET, "reading blob")))
+
+	attempts := 0
+	err := RetryIfNecessary(context.Background(), func() error {
+		if attempts++; attempts == 1 {
+			return badGateway
+		}
+		return nil
+	}, &RetryOptions{MaxRetry: 1, Delay: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
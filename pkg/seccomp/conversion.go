@@ -76,6 +76,8 @@ var (
 		specs.ActAllow: ActAllow,
 		specs.ActTrace: ActTrace,
 		specs.ActLog:   ActLog,
+		// Not yet part of the runtime-spec version we're using.
+		specs.LinuxSeccompAction(ActNotify): ActNotify,
 	}
 	specOperatorToSeccompOperatorMap = map[specs.LinuxSeccompOperator]Operator{
 		specs.OpNotEqual:     OpNotEqual,
@@ -115,9 +115,6 @@ func matchSyscall(filter *libseccomp.ScmpFilter, call *Syscall) error {
 			return errors.Wrapf(err, "add seccomp filter rule for syscall %s", call.Name)
 		}
 	} else {
-		// Linux system calls can have at most 6 arguments
-		const syscallMaxArguments int = 6
-
 		// If two or more arguments have the same condition,
 		// Revert to old behavior, adding each condition as a separate rule
 		argCounts := make([]uint, syscallMaxArguments)
@@ -186,6 +183,8 @@ func toAction(act Action, errnoRet *uint) (libseccomp.ScmpAction, error) {
 		return libseccomp.ActTrace.SetReturnCode(int16(unix.EPERM)), nil
 	case ActLog:
 		return libseccomp.ActLog, nil
+	case ActNotify:
+		return libseccomp.ActInvalid, errors.Errorf("action %s is not supported by the libseccomp bindings", act)
 	default:
 		return libseccomp.ActInvalid, errors.Errorf("invalid action %s", act)
 	}
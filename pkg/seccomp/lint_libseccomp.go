@@ -0,0 +1,38 @@
+// +build linux,seccomp
+
+package seccomp
+
+import (
+	libseccomp "github.com/seccomp/libseccomp-golang"
+)
+
+// libseccompArches are the architectures used to resolve syscall names.
+var libseccompArches = []libseccomp.ScmpArch{
+	libseccomp.ArchX86,
+	libseccomp.ArchAMD64,
+	libseccomp.ArchX32,
+	libseccomp.ArchARM,
+	libseccomp.ArchARM64,
+	libseccomp.ArchMIPS,
+	libseccomp.ArchMIPS64,
+	libseccomp.ArchMIPS64N32,
+	libseccomp.ArchMIPSEL,
+	libseccomp.ArchMIPSEL64,
+	libseccomp.ArchMIPSEL64N32,
+	libseccomp.ArchPPC,
+	libseccomp.ArchPPC64,
+	libseccomp.ArchPPC64LE,
+	libseccomp.ArchS390,
+	libseccomp.ArchS390X,
+}
+
+func init() {
+	syscallKnown = func(name string) bool {
+		for _, arch := range libseccompArches {
+			if _, err := libseccomp.GetSyscallFromNameByArch(name, arch); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+}
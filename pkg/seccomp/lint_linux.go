@@ -0,0 +1,265 @@
+package seccomp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// ProblemKind describes the kind of a problem found by Validate.
+type ProblemKind string
+
+const (
+	// ProblemInvalidRule is reported for rules which cannot be loaded.
+	ProblemInvalidRule ProblemKind = "invalid rule"
+	// ProblemUnknownSyscall is reported for syscall names unknown on all
+	// architectures.
+	ProblemUnknownSyscall ProblemKind = "unknown syscall"
+	// ProblemDuplicateRule is reported for rules which are specified more
+	// than once.
+	ProblemDuplicateRule ProblemKind = "duplicate rule"
+	// ProblemConflictingRule is reported for rules with the same conditions
+	// as a previous rule but a different action.
+	ProblemConflictingRule ProblemKind = "conflicting rule"
+	// ProblemUnreachableRule is reported for rules which can never have an
+	// effect.
+	ProblemUnreachableRule ProblemKind = "unreachable rule"
+	// ProblemKernelTooOld is reported for syscalls which are not supported
+	// by the running kernel.
+	ProblemKernelTooOld ProblemKind = "kernel too old"
+)
+
+// Problem is a single problem found by Validate.
+type Problem struct {
+	// Kind of the problem.
+	Kind ProblemKind
+	// Index of the syscall block in the profile or -1 for problems
+	// affecting the whole profile.
+	Rule int
+	// Syscall the problem relates to, if any.
+	Syscall string
+	// Message describing the problem.
+	Message string
+	// Warning is set for problems which do not prevent the profile from
+	// being loaded.
+	Warning bool
+}
+
+func (p Problem) String() string {
+	prefix := "error"
+	if p.Warning {
+		prefix = "warning"
+	}
+	switch {
+	case p.Rule < 0:
+		return fmt.Sprintf("%s: %s: %s", prefix, p.Kind, p.Message)
+	case p.Syscall != "":
+		return fmt.Sprintf("%s: syscalls[%d] %q: %s: %s", prefix, p.Rule, p.Syscall, p.Kind, p.Message)
+	default:
+		return fmt.Sprintf("%s: syscalls[%d]: %s: %s", prefix, p.Rule, p.Kind, p.Message)
+	}
+}
+
+// ValidationReport is returned by Validate.
+type ValidationReport struct {
+	// Problems found in the profile in the order of the rules.
+	Problems []Problem
+}
+
+// Err returns an error listing all problems of the report which are not
+// warnings, or nil if there are none.
+func (r *ValidationReport) Err() error {
+	var msgs []string
+	for _, p := range r.Problems {
+		if !p.Warning {
+			msgs = append(msgs, p.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.Errorf("invalid seccomp profile:\n%s", strings.Join(msgs, "\n"))
+}
+
+// syscallKnown is set by builds with libseccomp support to resolve syscall
+// names.
+var syscallKnown func(name string) bool
+
+// syscallMinKernel maps syscalls added to recent kernels to the kernel
+// version which introduced them.
+var syscallMinKernel = map[string][2]int{
+	"bpf":                          {3, 18},
+	"execveat":                     {3, 19},
+	"userfaultfd":                  {4, 3},
+	"membarrier":                   {4, 3},
+	"mlock2":                       {4, 4},
+	"copy_file_range":              {4, 5},
+	"preadv2":                      {4, 6},
+	"pwritev2":                     {4, 6},
+	"pkey_mprotect":                {4, 9},
+	"pkey_alloc":                   {4, 9},
+	"pkey_free":                    {4, 9},
+	"statx":                        {4, 11},
+	"io_pgetevents":                {4, 18},
+	"rseq":                         {4, 18},
+	"clock_gettime64":              {5, 1},
+	"clock_settime64":              {5, 1},
+	"clock_adjtime64":              {5, 1},
+	"clock_getres_time64":          {5, 1},
+	"clock_nanosleep_time64":       {5, 1},
+	"futex_time64":                 {5, 1},
+	"io_pgetevents_time64":         {5, 1},
+	"mq_timedreceive_time64":       {5, 1},
+	"mq_timedsend_time64":          {5, 1},
+	"ppoll_time64":                 {5, 1},
+	"pselect6_time64":              {5, 1},
+	"recvmmsg_time64":              {5, 1},
+	"rt_sigtimedwait_time64":       {5, 1},
+	"sched_rr_get_interval_time64": {5, 1},
+	"semtimedop_time64":            {5, 1},
+	"timer_gettime64":              {5, 1},
+	"timer_settime64":              {5, 1},
+	"timerfd_gettime64":            {5, 1},
+	"timerfd_settime64":            {5, 1},
+	"utimensat_time64":             {5, 1},
+	"io_uring_setup":               {5, 1},
+	"io_uring_enter":               {5, 1},
+	"io_uring_register":            {5, 1},
+	"pidfd_send_signal":            {5, 1},
+	"open_tree":                    {5, 2},
+	"move_mount":                   {5, 2},
+	"fsopen":                       {5, 2},
+	"fsconfig":                     {5, 2},
+	"fsmount":                      {5, 2},
+	"fspick":                       {5, 2},
+	"pidfd_open":                   {5, 3},
+	"clone3":                       {5, 3},
+	"openat2":                      {5, 6},
+	"pidfd_getfd":                  {5, 6},
+	"faccessat2":                   {5, 8},
+	"close_range":                  {5, 9},
+	"process_madvise":              {5, 10},
+	"epoll_pwait2":                 {5, 11},
+	"mount_setattr":                {5, 12},
+	"landlock_create_ruleset":      {5, 13},
+	"landlock_add_rule":            {5, 13},
+	"landlock_restrict_self":       {5, 13},
+	"memfd_secret":                 {5, 14},
+	"quotactl_fd":                  {5, 14},
+	"process_mrelease":             {5, 15},
+	"futex_waitv":                  {5, 16},
+}
+
+// kernelVersion returns the major and minor version of the running kernel.
+func kernelVersion() ([2]int, error) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return [2]int{}, err
+	}
+	release := unix.ByteSliceToString(uts.Release[:])
+	fields := strings.SplitN(release, ".", 3)
+	if len(fields) < 2 {
+		return [2]int{}, errors.Errorf("parsing kernel version %q", release)
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return [2]int{}, errors.Wrapf(err, "parsing kernel version %q", release)
+	}
+	minor, err := strconv.Atoi(strings.TrimRightFunc(fields[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return [2]int{}, errors.Wrapf(err, "parsing kernel version %q", release)
+	}
+	return [2]int{major, minor}, nil
+}
+
+// Validate lints the specified profile and reports invalid rules, unknown
+// syscall names, duplicate or conflicting rules, rules which can never have
+// an effect and syscalls which are not supported by the running kernel.
+// Use ValidationReport.Err to fail on problems which are not warnings.
+func Validate(profile *Seccomp) *ValidationReport {
+	report := &ValidationReport{}
+	if profile == nil {
+		return report
+	}
+	add := func(kind ProblemKind, rule int, name string, warning bool, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, Problem{
+			Kind:    kind,
+			Rule:    rule,
+			Syscall: name,
+			Message: fmt.Sprintf(format, args...),
+			Warning: warning,
+		})
+	}
+
+	if len(profile.Architectures) != 0 && len(profile.ArchMap) != 0 {
+		add(ProblemInvalidRule, -1, "", false, "'architectures' and 'archMap' were specified, use either 'architectures' or 'archMap'")
+	}
+
+	known := make(map[string]bool)
+	for _, call := range DefaultProfile().Syscalls {
+		for _, name := range call.Names {
+			known[name] = true
+		}
+	}
+	kernel, kernelErr := kernelVersion()
+
+	// first maps a syscall and its conditions to the index of the first
+	// rule and its action.
+	type firstRule struct {
+		index  int
+		action string
+	}
+	first := make(map[string]firstRule)
+	// unconditional maps a syscall to the index of the first rule
+	// without any conditions.
+	unconditional := make(map[string]int)
+
+	for i, call := range profile.Syscalls {
+		if err := ValidateRule(call); err != nil {
+			add(ProblemInvalidRule, i, "", false, "%v", err)
+			continue
+		}
+		rules, err := profileRules(&Seccomp{Syscalls: []*Syscall{call}})
+		if err != nil {
+			add(ProblemInvalidRule, i, "", false, "%v", err)
+			continue
+		}
+		hasConditions := len(call.Args) > 0 || len(call.Includes.Caps) > 0 || len(call.Includes.Arches) > 0 ||
+			len(call.Excludes.Caps) > 0 || len(call.Excludes.Arches) > 0
+		for _, r := range rules {
+			if !known[r.name] && syscallMinKernel[r.name] == [2]int{} && (syscallKnown == nil || !syscallKnown(r.name)) {
+				add(ProblemUnknownSyscall, i, r.name, false, "syscall is unknown on all architectures")
+				continue
+			}
+			if min, ok := syscallMinKernel[r.name]; ok && kernelErr == nil {
+				if kernel[0] < min[0] || (kernel[0] == min[0] && kernel[1] < min[1]) {
+					add(ProblemKernelTooOld, i, r.name, true, "requires kernel %d.%d but running %d.%d, the rule will be ignored", min[0], min[1], kernel[0], kernel[1])
+				}
+			}
+			if call.Action == profile.DefaultAction && call.ErrnoRet == nil {
+				add(ProblemUnreachableRule, i, r.name, true, "action %s matches the default action", call.Action)
+			}
+
+			key := r.name + "\x00" + r.condition
+			if prev, ok := first[key]; ok {
+				if prev.action == r.action {
+					add(ProblemDuplicateRule, i, r.name, true, "same rule as syscalls[%d]", prev.index)
+				} else {
+					add(ProblemConflictingRule, i, r.name, false, "same conditions as syscalls[%d] but different action", prev.index)
+				}
+				continue
+			}
+			first[key] = firstRule{index: i, action: r.action}
+
+			if prev, ok := unconditional[r.name]; ok {
+				add(ProblemUnreachableRule, i, r.name, true, "shadowed by unconditional rule syscalls[%d]", prev)
+			} else if !hasConditions {
+				unconditional[r.name] = i
+			}
+		}
+	}
+	return report
+}
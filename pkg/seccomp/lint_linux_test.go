@@ -0,0 +1,48 @@
+package seccomp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDefaultProfile(t *testing.T) {
+	report := Validate(DefaultProfile())
+	require.Nil(t, report.Err())
+	for _, p := range report.Problems {
+		require.True(t, p.Warning, p.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	profile := &Seccomp{
+		DefaultAction: ActErrno,
+		Syscalls: []*Syscall{
+			{Names: []string{"read", "write"}, Action: ActAllow},
+			{Name: "read", Action: ActAllow},
+			{Name: "write", Action: ActLog},
+			{Name: "notasyscall", Action: ActAllow},
+			{Name: "close", Action: ActErrno},
+			{Name: "read", Action: ActLog, Args: []*Arg{{Index: 0, Value: 1, Op: OpEqualTo}}},
+			{Name: "open", Action: ActAllow, Args: []*Arg{{Index: 7, Op: OpEqualTo}}},
+		},
+	}
+
+	report := Validate(profile)
+	kinds := make(map[ProblemKind][]int)
+	for _, p := range report.Problems {
+		kinds[p.Kind] = append(kinds[p.Kind], p.Rule)
+	}
+	require.Equal(t, []int{1}, kinds[ProblemDuplicateRule])
+	require.Equal(t, []int{2}, kinds[ProblemConflictingRule])
+	require.Equal(t, []int{3}, kinds[ProblemUnknownSyscall])
+	require.Equal(t, []int{4, 5}, kinds[ProblemUnreachableRule])
+	require.Equal(t, []int{6}, kinds[ProblemInvalidRule])
+
+	err := report.Err()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), `syscalls[3] "notasyscall": unknown syscall`)
+	require.NotContains(t, err.Error(), "duplicate rule")
+
+	require.Empty(t, Validate(nil).Problems)
+}
@@ -0,0 +1,360 @@
+package seccomp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MergeMode determines how the syscalls of multiple profiles are merged.
+type MergeMode int
+
+const (
+	// MergeUnion results in a profile containing the rules of all profiles.
+	MergeUnion MergeMode = iota
+	// MergeIntersection results in a profile only containing the rules
+	// which are part of all profiles.
+	MergeIntersection
+)
+
+// MergeConflict describes a syscall for which the merged profiles define
+// rules with identical conditions but different actions.
+type MergeConflict struct {
+	// Name of the syscall.
+	Name string
+	// Actions specified by the profiles for the syscall.
+	Actions []string
+}
+
+// MergeConflictError is returned by MergeProfiles if the profiles cannot be
+// merged due to conflicting rules.
+type MergeConflictError struct {
+	// DefaultActions is set if the profiles use different default actions.
+	DefaultActions []Action
+	// Conflicts lists the syscalls with conflicting rules.
+	Conflicts []MergeConflict
+}
+
+func (e *MergeConflictError) Error() string {
+	var msgs []string
+	if len(e.DefaultActions) > 0 {
+		var actions []string
+		for _, a := range e.DefaultActions {
+			actions = append(actions, string(a))
+		}
+		msgs = append(msgs, "default actions differ: "+strings.Join(actions, ", "))
+	}
+	for _, c := range e.Conflicts {
+		msgs = append(msgs, fmt.Sprintf("syscall %q: conflicting actions %s", c.Name, strings.Join(c.Actions, ", ")))
+	}
+	return "merging seccomp profiles: " + strings.Join(msgs, "; ")
+}
+
+// ProfileDiff describes the differences between two seccomp profiles.
+type ProfileDiff struct {
+	// DefaultAction is set to the default actions of both profiles if
+	// they differ.
+	DefaultAction []Action
+	// Added lists the syscalls which only have rules in the second profile.
+	Added []string
+	// Removed lists the syscalls which only have rules in the first profile.
+	Removed []string
+	// Changed lists the syscalls whose rules differ between both profiles.
+	Changed []string
+	// AddedArches lists the architectures only supported by the second
+	// profile.
+	AddedArches []Arch
+	// RemovedArches lists the architectures only supported by the first
+	// profile.
+	RemovedArches []Arch
+}
+
+// Empty returns true if both profiles are equivalent.
+func (d *ProfileDiff) Empty() bool {
+	return len(d.DefaultAction) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 &&
+		len(d.Changed) == 0 && len(d.AddedArches) == 0 && len(d.RemovedArches) == 0
+}
+
+// rule is a single syscall rule of a profile. Syscall blocks specifying
+// multiple names are split into one rule per name.
+type rule struct {
+	name string
+	// condition identifies the arguments and filters of the rule.
+	condition string
+	// action identifies the action and errno of the rule.
+	action string
+	call   *Syscall
+}
+
+type ruleCondition struct {
+	Args     []*Arg `json:"args,omitempty"`
+	Includes Filter `json:"includes"`
+	Excludes Filter `json:"excludes"`
+}
+
+type ruleAction struct {
+	Action   Action `json:"action"`
+	ErrnoRet *uint  `json:"errnoRet,omitempty"`
+}
+
+// profileRules returns the rules of the profile in the order of appearance.
+func profileRules(profile *Seccomp) ([]rule, error) {
+	var rules []rule
+	for _, call := range profile.Syscalls {
+		if call.Name != "" && len(call.Names) != 0 {
+			return nil, errors.New("'name' and 'names' were specified in the seccomp profile, use either 'name' or 'names'")
+		}
+		names := call.Names
+		if call.Name != "" {
+			names = []string{call.Name}
+		}
+		condition, err := json.Marshal(ruleCondition{Args: call.Args, Includes: call.Includes, Excludes: call.Excludes})
+		if err != nil {
+			return nil, err
+		}
+		action, err := json.Marshal(ruleAction{Action: call.Action, ErrnoRet: call.ErrnoRet})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			rules = append(rules, rule{name: name, condition: string(condition), action: string(action), call: call})
+		}
+	}
+	return rules, nil
+}
+
+// profileArches returns all architectures supported by the profile.
+func profileArches(profile *Seccomp) map[Arch]bool {
+	arches := make(map[Arch]bool)
+	for _, a := range profile.Architectures {
+		arches[a] = true
+	}
+	for _, a := range profile.ArchMap {
+		arches[a.Arch] = true
+	}
+	return arches
+}
+
+// MergeProfiles merges the specified profiles into a new one according to
+// the specified mode. Rules are considered equal if they apply to the same
+// syscall with the same arguments and filters. A MergeConflictError is
+// returned if the profiles use different default actions or if equal rules
+// specify different actions.
+func MergeProfiles(mode MergeMode, profiles ...*Seccomp) (*Seccomp, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no seccomp profiles to merge")
+	}
+
+	conflictErr := &MergeConflictError{}
+	defaultAction := profiles[0].DefaultAction
+	for _, p := range profiles[1:] {
+		if p.DefaultAction != defaultAction {
+			conflictErr.DefaultActions = []Action{defaultAction, p.DefaultAction}
+			break
+		}
+	}
+
+	type mergedRule struct {
+		rule
+		actions []string
+		count   int
+	}
+	var order []string
+	merged := make(map[string]*mergedRule)
+	for _, p := range profiles {
+		rules, err := profileRules(p)
+		if err != nil {
+			return nil, err
+		}
+		// A profile may list the same rule more than once.
+		seen := make(map[string]bool)
+		for _, r := range rules {
+			key := r.name + "\x00" + r.condition
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			m, ok := merged[key]
+			if !ok {
+				m = &mergedRule{rule: r}
+				merged[key] = m
+				order = append(order, key)
+			}
+			m.count++
+			found := false
+			for _, a := range m.actions {
+				if a == r.action {
+					found = true
+					break
+				}
+			}
+			if !found {
+				m.actions = append(m.actions, r.action)
+			}
+		}
+	}
+
+	// Group the resulting rules with the same conditions and actions into
+	// a single syscall block.
+	result := &Seccomp{DefaultAction: defaultAction, Syscalls: []*Syscall{}}
+	blocks := make(map[string]*Syscall)
+	for _, key := range order {
+		m := merged[key]
+		if mode == MergeIntersection && m.count != len(profiles) {
+			continue
+		}
+		if len(m.actions) > 1 {
+			var actions []string
+			for _, a := range m.actions {
+				var ra ruleAction
+				if err := json.Unmarshal([]byte(a), &ra); err != nil {
+					return nil, err
+				}
+				actions = append(actions, string(ra.Action))
+			}
+			conflictErr.Conflicts = append(conflictErr.Conflicts, MergeConflict{Name: m.name, Actions: actions})
+			continue
+		}
+		blockKey := m.condition + "\x00" + m.action
+		if block, ok := blocks[blockKey]; ok {
+			block.Names = append(block.Names, m.name)
+			continue
+		}
+		block := &Syscall{
+			Names:    []string{m.name},
+			Action:   m.call.Action,
+			Args:     m.call.Args,
+			Comment:  m.call.Comment,
+			Includes: m.call.Includes,
+			Excludes: m.call.Excludes,
+			ErrnoRet: m.call.ErrnoRet,
+		}
+		blocks[blockKey] = block
+		result.Syscalls = append(result.Syscalls, block)
+	}
+
+	if len(conflictErr.DefaultActions) > 0 || len(conflictErr.Conflicts) > 0 {
+		return nil, conflictErr
+	}
+
+	mergeArches(mode, result, profiles)
+	return result, nil
+}
+
+// mergeArches sets the architectures of the merged profile.
+func mergeArches(mode MergeMode, result *Seccomp, profiles []*Seccomp) {
+	keep := func(arch Arch) bool {
+		if mode == MergeUnion {
+			return true
+		}
+		for _, p := range profiles {
+			if !profileArches(p)[arch] {
+				return false
+			}
+		}
+		return true
+	}
+
+	seenArchMap := make(map[Arch]bool)
+	seenArches := make(map[Arch]bool)
+	for _, p := range profiles {
+		for _, a := range p.ArchMap {
+			if !seenArchMap[a.Arch] && keep(a.Arch) {
+				seenArchMap[a.Arch] = true
+				result.ArchMap = append(result.ArchMap, a)
+			}
+		}
+	}
+	for _, p := range profiles {
+		for _, a := range p.Architectures {
+			if !seenArches[a] && !seenArchMap[a] && keep(a) {
+				seenArches[a] = true
+				result.Architectures = append(result.Architectures, a)
+			}
+		}
+	}
+	// A profile may only use either the architectures or the archMap.
+	if len(result.ArchMap) > 0 {
+		for _, a := range result.Architectures {
+			result.ArchMap = append(result.ArchMap, Architecture{Arch: a, SubArches: []Arch{}})
+		}
+		result.Architectures = nil
+	}
+}
+
+// DiffProfiles computes the differences between the profiles a and b.
+func DiffProfiles(a, b *Seccomp) (*ProfileDiff, error) {
+	rulesA, err := profileRules(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "first profile")
+	}
+	rulesB, err := profileRules(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "second profile")
+	}
+
+	toSet := func(rules []rule) map[string]map[string]bool {
+		set := make(map[string]map[string]bool)
+		for _, r := range rules {
+			if set[r.name] == nil {
+				set[r.name] = make(map[string]bool)
+			}
+			set[r.name][r.condition+"\x00"+r.action] = true
+		}
+		return set
+	}
+	setA, setB := toSet(rulesA), toSet(rulesB)
+
+	diff := &ProfileDiff{}
+	if a.DefaultAction != b.DefaultAction {
+		diff.DefaultAction = []Action{a.DefaultAction, b.DefaultAction}
+	}
+	for name, ra := range setA {
+		rb, ok := setB[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, name)
+			continue
+		}
+		if len(ra) != len(rb) {
+			diff.Changed = append(diff.Changed, name)
+			continue
+		}
+		for key := range ra {
+			if !rb[key] {
+				diff.Changed = append(diff.Changed, name)
+				break
+			}
+		}
+	}
+	for name := range setB {
+		if _, ok := setA[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	archesA, archesB := profileArches(a), profileArches(b)
+	for arch := range archesA {
+		if !archesB[arch] {
+			diff.RemovedArches = append(diff.RemovedArches, arch)
+		}
+	}
+	for arch := range archesB {
+		if !archesA[arch] {
+			diff.AddedArches = append(diff.AddedArches, arch)
+		}
+	}
+	sortArches(diff.AddedArches)
+	sortArches(diff.RemovedArches)
+
+	return diff, nil
+}
+
+func sortArches(arches []Arch) {
+	sort.Slice(arches, func(i, j int) bool { return arches[i] < arches[j] })
+}
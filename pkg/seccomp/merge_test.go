@@ -0,0 +1,90 @@
+package seccomp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeProfiles(t *testing.T) {
+	a := &Seccomp{
+		DefaultAction: ActErrno,
+		ArchMap:       []Architecture{{Arch: ArchX86_64, SubArches: []Arch{ArchX86}}},
+		Syscalls: []*Syscall{
+			{Names: []string{"read", "write"}, Action: ActAllow},
+			{Name: "ptrace", Action: ActAllow, Includes: Filter{Caps: []string{"CAP_SYS_PTRACE"}}},
+		},
+	}
+	b := &Seccomp{
+		DefaultAction: ActErrno,
+		Architectures: []Arch{ArchX86_64, ArchAARCH64},
+		Syscalls: []*Syscall{
+			{Names: []string{"write", "close"}, Action: ActAllow},
+		},
+	}
+
+	union, err := MergeProfiles(MergeUnion, a, b)
+	require.Nil(t, err)
+	require.Len(t, union.Syscalls, 2)
+	require.Equal(t, []string{"read", "write", "close"}, union.Syscalls[0].Names)
+	require.Equal(t, []string{"ptrace"}, union.Syscalls[1].Names)
+	require.Equal(t, []Architecture{
+		{Arch: ArchX86_64, SubArches: []Arch{ArchX86}},
+		{Arch: ArchAARCH64, SubArches: []Arch{}},
+	}, union.ArchMap)
+	require.Empty(t, union.Architectures)
+
+	intersection, err := MergeProfiles(MergeIntersection, a, b)
+	require.Nil(t, err)
+	require.Len(t, intersection.Syscalls, 1)
+	require.Equal(t, []string{"write"}, intersection.Syscalls[0].Names)
+	require.Equal(t, []Architecture{{Arch: ArchX86_64, SubArches: []Arch{ArchX86}}}, intersection.ArchMap)
+
+	// Conflicting actions
+	c := &Seccomp{
+		DefaultAction: ActAllow,
+		Syscalls:      []*Syscall{{Name: "write", Action: ActLog}},
+	}
+	_, err = MergeProfiles(MergeUnion, a, c)
+	require.NotNil(t, err)
+	conflictErr, ok := err.(*MergeConflictError)
+	require.True(t, ok)
+	require.Equal(t, []Action{ActErrno, ActAllow}, conflictErr.DefaultActions)
+	require.Equal(t, []MergeConflict{{Name: "write", Actions: []string{string(ActAllow), string(ActLog)}}}, conflictErr.Conflicts)
+
+	_, err = MergeProfiles(MergeUnion)
+	require.NotNil(t, err)
+}
+
+func TestDiffProfiles(t *testing.T) {
+	a := &Seccomp{
+		DefaultAction: ActErrno,
+		Architectures: []Arch{ArchX86_64},
+		Syscalls: []*Syscall{
+			{Names: []string{"read", "write", "ptrace"}, Action: ActAllow},
+		},
+	}
+	b := &Seccomp{
+		DefaultAction: ActErrno,
+		ArchMap:       []Architecture{{Arch: ArchAARCH64}},
+		Syscalls: []*Syscall{
+			{Names: []string{"write", "read"}, Action: ActAllow},
+			{Name: "ptrace", Action: ActAllow, Includes: Filter{Caps: []string{"CAP_SYS_PTRACE"}}},
+			{Name: "close", Action: ActAllow},
+		},
+	}
+
+	diff, err := DiffProfiles(a, b)
+	require.Nil(t, err)
+	require.False(t, diff.Empty())
+	require.Empty(t, diff.DefaultAction)
+	require.Equal(t, []string{"close"}, diff.Added)
+	require.Empty(t, diff.Removed)
+	require.Equal(t, []string{"ptrace"}, diff.Changed)
+	require.Equal(t, []Arch{ArchAARCH64}, diff.AddedArches)
+	require.Equal(t, []Arch{ArchX86_64}, diff.RemovedArches)
+
+	diff, err = DiffProfiles(a, a)
+	require.Nil(t, err)
+	require.True(t, diff.Empty())
+}
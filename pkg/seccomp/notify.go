@@ -0,0 +1,118 @@
+package seccomp
+
+import (
+	"github.com/pkg/errors"
+)
+
+// notifyUnsupportedSyscalls lists the syscalls which must not be forwarded
+// to a user-space supervisor. The OCI runtime needs them to send the notify
+// file descriptor to the listener which would otherwise deadlock.
+var notifyUnsupportedSyscalls = map[string]bool{
+	"write": true,
+}
+
+// UsesNotify returns true if the profile contains SCMP_ACT_NOTIFY rules.
+func UsesNotify(profile *Seccomp) bool {
+	if profile == nil {
+		return false
+	}
+	for _, call := range profile.Syscalls {
+		if call != nil && call.Action == ActNotify {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNotify validates the SCMP_ACT_NOTIFY rules of the profile. A
+// listener path must be set if any such rule is present, the default action
+// must not be SCMP_ACT_NOTIFY and syscalls required by the OCI runtime to
+// hand over the notify file descriptor must not be forwarded. Note that
+// ValidateNotify does not check whether the host supports seccomp notify,
+// use IsNotifySupported for that.
+func ValidateNotify(profile *Seccomp) error {
+	if profile == nil {
+		return nil
+	}
+	if profile.DefaultAction == ActNotify {
+		return errors.Errorf("%s cannot be used as default action", ActNotify)
+	}
+	if !UsesNotify(profile) {
+		if profile.ListenerPath != "" {
+			return errors.New("listener path set but no syscall uses the notify action")
+		}
+		return nil
+	}
+	if profile.ListenerPath == "" {
+		return errors.Errorf("listener path required for %s rules", ActNotify)
+	}
+	for _, call := range profile.Syscalls {
+		if call == nil || call.Action != ActNotify {
+			continue
+		}
+		names := call.Names
+		if call.Name != "" {
+			names = append(names, call.Name)
+		}
+		for _, name := range names {
+			if notifyUnsupportedSyscalls[name] {
+				return errors.Errorf("%s cannot be used for the %s syscall", ActNotify, name)
+			}
+		}
+	}
+	return nil
+}
+
+// NotifyProfile returns a copy of the base profile which forwards the
+// specified syscalls to the user-space supervisor listening on listenerPath.
+// The notify rules take precedence over the rules of the base profile for
+// the specified syscalls, which are removed from it.
+func NotifyProfile(base *Seccomp, listenerPath string, syscalls []string) (*Seccomp, error) {
+	if base == nil {
+		return nil, errors.New("no base profile specified")
+	}
+	if len(syscalls) == 0 {
+		return nil, errors.New("no syscalls specified")
+	}
+
+	notified := make(map[string]bool, len(syscalls))
+	for _, name := range syscalls {
+		notified[name] = true
+	}
+
+	profile := *base
+	profile.ListenerPath = listenerPath
+	profile.Syscalls = []*Syscall{{
+		Names:   syscalls,
+		Action:  ActNotify,
+		Args:    []*Arg{},
+		Comment: "forwarded to the seccomp agent",
+	}}
+	for _, call := range base.Syscalls {
+		if call == nil {
+			continue
+		}
+		names := call.Names
+		if call.Name != "" {
+			names = []string{call.Name}
+		}
+		var kept []string
+		for _, name := range names {
+			if !notified[name] {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		newCall := *call
+		newCall.Name = ""
+		newCall.Names = kept
+		profile.Syscalls = append(profile.Syscalls, &newCall)
+	}
+
+	if err := ValidateNotify(&profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
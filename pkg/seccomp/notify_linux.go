@@ -0,0 +1,31 @@
+package seccomp
+
+import (
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// seccompGetActionAvail is SECCOMP_GET_ACTION_AVAIL from linux/seccomp.h.
+	seccompGetActionAvail = 2
+	// seccompRetUserNotif is SECCOMP_RET_USER_NOTIF from linux/seccomp.h.
+	seccompRetUserNotif = 0x7fc00000
+)
+
+var (
+	notifySupported bool
+	notifyOnce      sync.Once
+)
+
+// IsNotifySupported returns true if the kernel supports forwarding syscalls
+// to a user-space supervisor via SCMP_ACT_NOTIFY (Linux 5.0 and newer).
+func IsNotifySupported() bool {
+	notifyOnce.Do(func() {
+		action := uint32(seccompRetUserNotif)
+		_, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompGetActionAvail, 0, uintptr(unsafe.Pointer(&action)))
+		notifySupported = errno == 0
+	})
+	return notifySupported
+}
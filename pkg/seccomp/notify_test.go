@@ -0,0 +1,78 @@
+package seccomp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyProfile(t *testing.T) {
+	base := &Seccomp{
+		DefaultAction: ActErrno,
+		Syscalls: []*Syscall{
+			{Names: []string{"read", "mount", "close"}, Action: ActAllow},
+			{Name: "umount2", Action: ActAllow},
+		},
+	}
+	require.False(t, UsesNotify(base))
+
+	profile, err := NotifyProfile(base, "/run/agent.sock", []string{"mount", "umount2"})
+	require.Nil(t, err)
+	require.True(t, UsesNotify(profile))
+	require.Equal(t, "/run/agent.sock", profile.ListenerPath)
+	require.Len(t, profile.Syscalls, 2)
+	require.Equal(t, ActNotify, profile.Syscalls[0].Action)
+	require.Equal(t, []string{"mount", "umount2"}, profile.Syscalls[0].Names)
+	require.Equal(t, []string{"read", "close"}, profile.Syscalls[1].Names)
+	// The base profile must not be modified
+	require.Len(t, base.Syscalls, 2)
+	require.Empty(t, base.ListenerPath)
+
+	_, err = NotifyProfile(base, "", []string{"mount"})
+	require.NotNil(t, err)
+
+	_, err = NotifyProfile(base, "/run/agent.sock", []string{"write"})
+	require.NotNil(t, err)
+}
+
+func TestValidateNotify(t *testing.T) {
+	for _, tc := range []struct {
+		profile   *Seccomp
+		shouldErr bool
+	}{
+		{ // no notify rules
+			profile:   &Seccomp{DefaultAction: ActErrno},
+			shouldErr: false,
+		},
+		{ // notify as default action
+			profile:   &Seccomp{DefaultAction: ActNotify, ListenerPath: "/run/agent.sock"},
+			shouldErr: true,
+		},
+		{ // listener path without notify rules
+			profile:   &Seccomp{DefaultAction: ActErrno, ListenerPath: "/run/agent.sock"},
+			shouldErr: true,
+		},
+		{ // missing listener path
+			profile: &Seccomp{
+				DefaultAction: ActErrno,
+				Syscalls:      []*Syscall{{Name: "mount", Action: ActNotify}},
+			},
+			shouldErr: true,
+		},
+		{ // valid notify profile
+			profile: &Seccomp{
+				DefaultAction: ActErrno,
+				ListenerPath:  "/run/agent.sock",
+				Syscalls:      []*Syscall{{Name: "mount", Action: ActNotify}},
+			},
+			shouldErr: false,
+		},
+	} {
+		err := ValidateNotify(tc.profile)
+		if tc.shouldErr {
+			require.NotNil(t, err)
+		} else {
+			require.Nil(t, err)
+		}
+	}
+}
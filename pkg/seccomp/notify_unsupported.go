@@ -0,0 +1,8 @@
+// +build !linux
+
+package seccomp
+
+// IsNotifySupported returns false on unsupported systems.
+func IsNotifySupported() bool {
+	return false
+}
@@ -0,0 +1,198 @@
+package seccomp
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// syscallMaxArguments is the maximum number of arguments of a Linux syscall.
+const syscallMaxArguments = 6
+
+var validActions = map[Action]bool{
+	ActKill:        true,
+	ActKillProcess: true,
+	ActKillThread:  true,
+	ActTrap:        true,
+	ActErrno:       true,
+	ActTrace:       true,
+	ActAllow:       true,
+	ActLog:         true,
+	ActNotify:      true,
+}
+
+var validOperators = map[Operator]bool{
+	OpNotEqual:     true,
+	OpLessThan:     true,
+	OpLessEqual:    true,
+	OpEqualTo:      true,
+	OpGreaterEqual: true,
+	OpGreaterThan:  true,
+	OpMaskedEqual:  true,
+}
+
+// RuleBuilder allows for building syscall rules with argument filters
+// without writing the JSON by hand. Use NewRule to create a builder and
+// Build to validate and return the rule, for instance:
+//
+//	// allow clone(2) only without CLONE_NEWUSER
+//	rule, err := NewRule("clone").
+//		Allow().
+//		ArgMaskedEqual(0, unix.CLONE_NEWUSER, 0).
+//		Build()
+type RuleBuilder struct {
+	call Syscall
+}
+
+// NewRule returns a builder for a rule matching the specified syscalls. The
+// action of the rule defaults to SCMP_ACT_ALLOW.
+func NewRule(names ...string) *RuleBuilder {
+	return &RuleBuilder{
+		call: Syscall{
+			Names:  names,
+			Action: ActAllow,
+			Args:   []*Arg{},
+		},
+	}
+}
+
+// Action sets the action of the rule.
+func (b *RuleBuilder) Action(action Action) *RuleBuilder {
+	b.call.Action = action
+	b.call.ErrnoRet = nil
+	return b
+}
+
+// Allow sets the action of the rule to SCMP_ACT_ALLOW.
+func (b *RuleBuilder) Allow() *RuleBuilder {
+	return b.Action(ActAllow)
+}
+
+// Errno sets the action of the rule to SCMP_ACT_ERRNO returning the
+// specified errno.
+func (b *RuleBuilder) Errno(errno uint) *RuleBuilder {
+	b.call.Action = ActErrno
+	b.call.ErrnoRet = &errno
+	return b
+}
+
+// Arg adds a condition comparing the argument at the specified index with
+// value using op.
+func (b *RuleBuilder) Arg(index uint, op Operator, value uint64) *RuleBuilder {
+	b.call.Args = append(b.call.Args, &Arg{Index: index, Value: value, Op: op})
+	return b
+}
+
+// ArgMaskedEqual adds a condition matching if the argument at the specified
+// index masked with mask equals value.
+func (b *RuleBuilder) ArgMaskedEqual(index uint, mask, value uint64) *RuleBuilder {
+	b.call.Args = append(b.call.Args, &Arg{Index: index, Value: mask, ValueTwo: value, Op: OpMaskedEqual})
+	return b
+}
+
+// IncludeCaps restricts the rule to containers having all of the
+// specified capabilities.
+func (b *RuleBuilder) IncludeCaps(caps ...string) *RuleBuilder {
+	b.call.Includes.Caps = append(b.call.Includes.Caps, caps...)
+	return b
+}
+
+// ExcludeCaps restricts the rule to containers having none of the
+// specified capabilities.
+func (b *RuleBuilder) ExcludeCaps(caps ...string) *RuleBuilder {
+	b.call.Excludes.Caps = append(b.call.Excludes.Caps, caps...)
+	return b
+}
+
+// IncludeArches restricts the rule to the specified architectures (e.g.,
+// amd64).
+func (b *RuleBuilder) IncludeArches(arches ...string) *RuleBuilder {
+	b.call.Includes.Arches = append(b.call.Includes.Arches, arches...)
+	return b
+}
+
+// ExcludeArches excludes the rule on the specified architectures (e.g.,
+// amd64).
+func (b *RuleBuilder) ExcludeArches(arches ...string) *RuleBuilder {
+	b.call.Excludes.Arches = append(b.call.Excludes.Arches, arches...)
+	return b
+}
+
+// Comment sets the comment of the rule.
+func (b *RuleBuilder) Comment(comment string) *RuleBuilder {
+	b.call.Comment = comment
+	return b
+}
+
+// Build validates and returns the rule.
+func (b *RuleBuilder) Build() (*Syscall, error) {
+	call := b.call
+	call.Names = append([]string{}, b.call.Names...)
+	call.Args = append([]*Arg{}, b.call.Args...)
+	if err := ValidateRule(&call); err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+// ValidateRule validates the specified rule against the constraints of
+// libseccomp: syscalls have at most six arguments, actions and operators
+// must be known and conditions on the same argument cannot be combined
+// into a single rule (they would be added as separate rules matching if
+// any of them matches).
+func ValidateRule(call *Syscall) error {
+	if call == nil {
+		return errors.New("rule is nil")
+	}
+	if call.Name != "" && len(call.Names) != 0 {
+		return errors.New("'name' and 'names' were specified in the rule, use either 'name' or 'names'")
+	}
+	names := call.Names
+	if call.Name != "" {
+		names = []string{call.Name}
+	}
+	if len(names) == 0 {
+		return errors.New("rule does not match any syscall")
+	}
+	for _, name := range names {
+		if name == "" {
+			return errors.New("empty string is not a valid syscall")
+		}
+	}
+
+	if !validActions[call.Action] {
+		return errors.Errorf("invalid action %q", call.Action)
+	}
+	if call.ErrnoRet != nil && call.Action != ActErrno && call.Action != ActTrace {
+		return errors.Errorf("errnoRet is only supported for %s and %s", ActErrno, ActTrace)
+	}
+
+	seen := make(map[uint]bool)
+	for _, arg := range call.Args {
+		if arg == nil {
+			return errors.New("argument condition is nil")
+		}
+		if arg.Index >= syscallMaxArguments {
+			return errors.Errorf("argument index %d out of range: syscalls have at most %d arguments", arg.Index, syscallMaxArguments)
+		}
+		if !validOperators[arg.Op] {
+			return errors.Errorf("invalid operator %q for argument %d", arg.Op, arg.Index)
+		}
+		if arg.Op == OpMaskedEqual && arg.Value == 0 && arg.ValueTwo != 0 {
+			return errors.Errorf("argument %d can never match: value %#x is not covered by an empty mask", arg.Index, arg.ValueTwo)
+		}
+		if seen[arg.Index] {
+			return errors.Errorf("multiple conditions on argument %d: use separate rules instead", arg.Index)
+		}
+		seen[arg.Index] = true
+	}
+
+	for _, caps := range [][]string{call.Includes.Caps, call.Excludes.Caps} {
+		for _, c := range caps {
+			if !strings.HasPrefix(c, "CAP_") {
+				return errors.Errorf("invalid capability %q", c)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package seccomp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestRuleBuilder(t *testing.T) {
+	rule, err := NewRule("clone").
+		ArgMaskedEqual(0, unix.CLONE_NEWUSER, 0).
+		ExcludeCaps("CAP_SYS_ADMIN").
+		Comment("no user namespaces").
+		Build()
+	require.Nil(t, err)
+	require.Equal(t, &Syscall{
+		Names:    []string{"clone"},
+		Action:   ActAllow,
+		Args:     []*Arg{{Index: 0, Value: unix.CLONE_NEWUSER, ValueTwo: 0, Op: OpMaskedEqual}},
+		Comment:  "no user namespaces",
+		Excludes: Filter{Caps: []string{"CAP_SYS_ADMIN"}},
+	}, rule)
+
+	rule, err = NewRule("personality").Errno(uint(unix.EPERM)).Arg(0, OpNotEqual, 0x0008).Build()
+	require.Nil(t, err)
+	require.Equal(t, ActErrno, rule.Action)
+	require.Equal(t, uint(unix.EPERM), *rule.ErrnoRet)
+
+	for _, b := range []*RuleBuilder{
+		NewRule(),                                 // no syscall
+		NewRule(""),                               // empty syscall
+		NewRule("read").Action("SCMP_ACT_FOO"),    // invalid action
+		NewRule("read").Arg(6, OpEqualTo, 0),      // index out of range
+		NewRule("read").Arg(0, "SCMP_CMP_FOO", 0), // invalid operator
+		NewRule("read").Arg(0, OpEqualTo, 0).Arg(0, OpEqualTo, 1), // same argument
+		NewRule("read").ArgMaskedEqual(0, 0, 1),                   // never matches
+		NewRule("read").IncludeCaps("SYS_ADMIN"),                  // invalid capability
+	} {
+		_, err := b.Build()
+		require.NotNil(t, err)
+	}
+
+	require.NotNil(t, ValidateRule(&Syscall{Name: "read", Action: ActAllow, ErrnoRet: new(uint)}))
+}
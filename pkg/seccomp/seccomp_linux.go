@@ -45,6 +45,16 @@ func LoadProfileFromConfig(config *Seccomp, specgen *specs.Spec) (*specs.LinuxSe
 	return setupSeccomp(config, specgen)
 }
 
+// LoadProfileByName takes the name of a built-in profile variant (see
+// ProfileNames) and a spec to retrieve a LinuxSeccomp.
+func LoadProfileByName(name string, rs *specs.Spec) (*specs.LinuxSeccomp, error) {
+	config, err := ProfileByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return setupSeccomp(config, rs)
+}
+
 var nativeToSeccomp = map[string]Arch{
 	"amd64":       ArchX86_64,
 	"arm64":       ArchAARCH64,
@@ -76,6 +86,10 @@ func setupSeccomp(config *Seccomp, rs *specs.Spec) (*specs.LinuxSeccomp, error)
 		return nil, nil
 	}
 
+	if err := ValidateNotify(config); err != nil {
+		return nil, err
+	}
+
 	newConfig := &specs.LinuxSeccomp{}
 
 	var arch string
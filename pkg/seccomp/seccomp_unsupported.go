@@ -34,6 +34,11 @@ func LoadProfileFromConfig(config *Seccomp, specgen *specs.Spec) (*specs.LinuxSe
 	return nil, errNotSupported
 }
 
+// LoadProfileByName returns an error on unsupported systems
+func LoadProfileByName(name string, rs *specs.Spec) (*specs.LinuxSeccomp, error) {
+	return nil, errNotSupported
+}
+
 // IsEnabled returns true if seccomp is enabled for the host.
 func IsEnabled() bool {
 	return false
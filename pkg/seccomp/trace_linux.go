@@ -0,0 +1,177 @@
+package seccomp
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GenerateOptions allows for customizing the profile generated by
+// GenerateProfile.
+type GenerateOptions struct {
+	// Template is the profile used as base for the generated one. The
+	// default profile is used if nil.
+	Template *Seccomp
+	// Architectures restricts the architectures of the generated profile
+	// to the specified ones (e.g., SCMP_ARCH_X86_64). Sub-architectures
+	// are kept for every selected architecture. All architectures of the
+	// template are kept if empty.
+	Architectures []Arch
+	// AllowUnlisted adds syscalls which have been observed but which are
+	// not allowed by the template to the generated profile. They are
+	// dropped otherwise.
+	AllowUnlisted bool
+}
+
+// ParseSyscallTrace reads the names of observed syscalls from the specified
+// reader. Each line is expected to start with the name of a syscall which
+// may be followed by its arguments in strace notation (e.g.,
+// `openat(AT_FDCWD, ...)`). Empty lines and lines starting with `#` are
+// ignored. The returned names are sorted and deduplicated.
+func ParseSyscallTrace(r io.Reader) ([]string, error) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.Fields(line)[0]
+		if i := strings.Index(name, "("); i >= 0 {
+			name = name[:i]
+		}
+		if name == "" {
+			continue
+		}
+		seen[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading syscall trace")
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GenerateProfile generates a minimal seccomp profile which only allows the
+// specified syscalls. The rules (including argument filters, capability and
+// architecture conditions) are taken from the template profile, such that a
+// syscall is never granted more permissions than in the template. Syscalls
+// which are not part of the template are dropped unless
+// options.AllowUnlisted is set.
+func GenerateProfile(syscalls []string, options *GenerateOptions) (*Seccomp, error) {
+	if options == nil {
+		options = &GenerateOptions{}
+	}
+	template := options.Template
+	if template == nil {
+		template = DefaultProfile()
+	}
+
+	observed := make(map[string]bool, len(syscalls))
+	for _, name := range syscalls {
+		observed[name] = true
+	}
+
+	profile := &Seccomp{
+		DefaultAction: template.DefaultAction,
+	}
+
+	archMap, architectures, err := filterArches(template, options.Architectures)
+	if err != nil {
+		return nil, err
+	}
+	profile.ArchMap = archMap
+	profile.Architectures = architectures
+
+	listed := make(map[string]bool)
+	for _, call := range template.Syscalls {
+		names := call.Names
+		if call.Name != "" {
+			names = []string{call.Name}
+		}
+		var kept []string
+		for _, name := range names {
+			if observed[name] {
+				kept = append(kept, name)
+				listed[name] = true
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		newCall := *call
+		newCall.Name = ""
+		newCall.Names = kept
+		profile.Syscalls = append(profile.Syscalls, &newCall)
+	}
+
+	if options.AllowUnlisted {
+		var unlisted []string
+		for _, name := range syscalls {
+			if !listed[name] {
+				unlisted = append(unlisted, name)
+				listed[name] = true
+			}
+		}
+		if len(unlisted) > 0 {
+			sort.Strings(unlisted)
+			profile.Syscalls = append(profile.Syscalls, &Syscall{
+				Names:   unlisted,
+				Action:  ActAllow,
+				Args:    []*Arg{},
+				Comment: "observed syscalls not listed in the template",
+			})
+		}
+	}
+
+	if profile.Syscalls == nil {
+		profile.Syscalls = []*Syscall{}
+	}
+	return profile, nil
+}
+
+// filterArches returns the archMap and architectures of the template
+// restricted to the specified architectures.
+func filterArches(template *Seccomp, arches []Arch) ([]Architecture, []Arch, error) {
+	if len(arches) == 0 {
+		return template.ArchMap, template.Architectures, nil
+	}
+
+	wanted := make(map[Arch]bool, len(arches))
+	for _, arch := range arches {
+		wanted[arch] = true
+	}
+
+	var archMap []Architecture
+	for _, a := range template.ArchMap {
+		if wanted[a.Arch] {
+			archMap = append(archMap, a)
+			delete(wanted, a.Arch)
+		}
+	}
+	var architectures []Arch
+	for _, a := range template.Architectures {
+		if wanted[a] {
+			architectures = append(architectures, a)
+			delete(wanted, a)
+		}
+	}
+
+	if len(wanted) > 0 {
+		var missing []string
+		for arch := range wanted {
+			missing = append(missing, string(arch))
+		}
+		sort.Strings(missing)
+		return nil, nil, errors.Errorf("architectures not supported by the template profile: %s", strings.Join(missing, ", "))
+	}
+	return archMap, architectures, nil
+}
@@ -0,0 +1,57 @@
+package seccomp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSyscallTrace(t *testing.T) {
+	trace := `# strace -f -c output
+openat(AT_FDCWD, "/etc/passwd", O_RDONLY) = 3
+read(3, "", 4096) = 0
+
+read
+close 3
+`
+	names, err := ParseSyscallTrace(strings.NewReader(trace))
+	require.Nil(t, err)
+	require.Equal(t, []string{"close", "openat", "read"}, names)
+}
+
+func TestGenerateProfile(t *testing.T) {
+	profile, err := GenerateProfile([]string{"read", "write", "socket", "notasyscall"}, nil)
+	require.Nil(t, err)
+	require.Equal(t, ActErrno, profile.DefaultAction)
+	require.Equal(t, arches(), profile.ArchMap)
+
+	allowed := make(map[string]int)
+	for _, call := range profile.Syscalls {
+		require.Empty(t, call.Name)
+		for _, name := range call.Names {
+			allowed[name]++
+		}
+	}
+	require.Equal(t, 1, allowed["read"])
+	require.Equal(t, 1, allowed["write"])
+	// socket has several conditional rules in the default profile
+	require.True(t, allowed["socket"] > 1)
+	require.NotContains(t, allowed, "notasyscall")
+
+	profile, err = GenerateProfile([]string{"read", "notasyscall"}, &GenerateOptions{
+		Architectures: []Arch{ArchAARCH64},
+		AllowUnlisted: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, profile.ArchMap, 1)
+	require.Equal(t, ArchAARCH64, profile.ArchMap[0].Arch)
+	require.Len(t, profile.Syscalls, 2)
+	require.Equal(t, []string{"notasyscall"}, profile.Syscalls[1].Names)
+	require.Equal(t, ActAllow, profile.Syscalls[1].Action)
+
+	_, err = GenerateProfile([]string{"read"}, &GenerateOptions{
+		Architectures: []Arch{ArchRISCV64},
+	})
+	require.NotNil(t, err)
+}
@@ -12,6 +12,13 @@ type Seccomp struct {
 	Architectures []Arch         `json:"architectures,omitempty"`
 	ArchMap       []Architecture `json:"archMap,omitempty"`
 	Syscalls      []*Syscall     `json:"syscalls"`
+	// ListenerPath is the path of the UNIX socket the seccomp notify file
+	// descriptor is sent to. Only used by profiles with SCMP_ACT_NOTIFY
+	// rules.
+	ListenerPath string `json:"listenerPath,omitempty"`
+	// ListenerMetadata is opaque data passed to the seccomp agent along
+	// with the notify file descriptor.
+	ListenerMetadata string `json:"listenerMetadata,omitempty"`
 }
 
 // Architecture is used to represent a specific architecture
@@ -67,6 +74,9 @@ const (
 	ActTrace      Action = "SCMP_ACT_TRACE"
 	ActAllow      Action = "SCMP_ACT_ALLOW"
 	ActLog        Action = "SCMP_ACT_LOG"
+	// ActNotify forwards the system call to a user-space supervisor
+	// listening on the profile's listener path.
+	ActNotify Action = "SCMP_ACT_NOTIFY"
 )
 
 // Operator used to match syscall arguments in Seccomp
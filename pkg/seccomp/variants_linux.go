@@ -0,0 +1,126 @@
+package seccomp
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Names of the built-in profile variants.
+const (
+	// ProfileDefault is the default profile.
+	ProfileDefault = "default"
+	// ProfileRestricted is the default profile without the syscalls
+	// required for nested containers (mount, namespace handling) and
+	// without the rules granted by capabilities, such that adding
+	// capabilities does not relax the profile.
+	ProfileRestricted = "restricted"
+	// ProfileMinimal only allows the syscalls typically required by
+	// simple services and is derived from the restricted profile.
+	ProfileMinimal = "minimal"
+)
+
+// restrictedDeniedSyscalls lists the syscalls allowed by the default profile
+// which are denied by the restricted one.
+var restrictedDeniedSyscalls = map[string]bool{
+	"fsconfig":   true,
+	"fsmount":    true,
+	"fsopen":     true,
+	"fspick":     true,
+	"keyctl":     true,
+	"mount":      true,
+	"move_mount": true,
+	"open_tree":  true,
+	"pivot_root": true,
+	"reboot":     true,
+	"setns":      true,
+	"syslog":     true,
+	"umount":     true,
+	"umount2":    true,
+	"unshare":    true,
+}
+
+// minimalSyscalls lists the syscalls allowed by the minimal profile.
+var minimalSyscalls = []string{
+	"accept", "accept4", "access", "arch_prctl", "bind", "brk", "capget",
+	"chdir", "clock_getres", "clock_getres_time64", "clock_gettime",
+	"clock_gettime64", "clock_nanosleep", "clock_nanosleep_time64", "clone",
+	"close", "connect", "dup", "dup2", "dup3", "epoll_create", "epoll_create1",
+	"epoll_ctl", "epoll_pwait", "epoll_wait", "eventfd2", "execve", "exit",
+	"exit_group", "faccessat", "faccessat2", "fchdir", "fcntl", "fcntl64",
+	"fstat", "fstat64", "fstatat64", "fsync", "futex", "futex_time64",
+	"get_robust_list", "getcwd", "getdents64", "getegid", "geteuid",
+	"getgid", "getgroups", "getpeername", "getpid", "getppid", "getrandom",
+	"getrlimit", "getsockname", "getsockopt", "gettid", "gettimeofday",
+	"getuid", "ioctl", "kill", "listen", "lseek", "lstat", "madvise", "mmap",
+	"mmap2", "mprotect", "mremap", "munmap", "nanosleep", "newfstatat",
+	"open", "openat", "pipe", "pipe2", "poll", "ppoll", "ppoll_time64",
+	"prctl", "pread64", "prlimit64", "pselect6", "pselect6_time64",
+	"pwrite64", "read", "readlink", "readlinkat", "readv", "recvfrom",
+	"recvmsg", "restart_syscall", "rseq", "rt_sigaction", "rt_sigprocmask",
+	"rt_sigreturn", "sched_getaffinity", "sched_yield", "select", "sendmsg",
+	"sendto", "set_robust_list", "set_tid_address", "setsockopt",
+	"shutdown", "sigaltstack", "socket", "stat", "stat64", "statx",
+	"sysinfo", "tgkill", "umask", "uname", "wait4", "write", "writev",
+}
+
+// ProfileNames returns the names of the built-in profile variants ordered
+// from the least to the most restrictive one.
+func ProfileNames() []string {
+	return []string{ProfileDefault, ProfileRestricted, ProfileMinimal}
+}
+
+// ProfileByName returns the built-in profile variant with the specified
+// name.
+func ProfileByName(name string) (*Seccomp, error) {
+	switch name {
+	case ProfileDefault:
+		return DefaultProfile(), nil
+	case ProfileRestricted:
+		return restrictedProfile(), nil
+	case ProfileMinimal:
+		return GenerateProfile(minimalSyscalls, &GenerateOptions{Template: restrictedProfile()})
+	default:
+		return nil, errors.Errorf("unknown seccomp profile %q", name)
+	}
+}
+
+// DiffVariants returns the differences between the built-in profile
+// variants from and to. For instance, DiffVariants(ProfileDefault,
+// ProfileRestricted) lists the syscalls denied when switching from the
+// default to the restricted profile in ProfileDiff.Removed.
+func DiffVariants(from, to string) (*ProfileDiff, error) {
+	a, err := ProfileByName(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ProfileByName(to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffProfiles(a, b)
+}
+
+// restrictedProfile derives the restricted profile from the default one.
+func restrictedProfile() *Seccomp {
+	profile := DefaultProfile()
+	syscalls := []*Syscall{}
+	for _, call := range profile.Syscalls {
+		if len(call.Includes.Caps) > 0 {
+			continue
+		}
+		var names []string
+		for _, name := range call.Names {
+			if !restrictedDeniedSyscalls[name] {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		call.Names = names
+		// The capabilities are ignored by this profile.
+		call.Excludes.Caps = nil
+		syscalls = append(syscalls, call)
+	}
+	profile.Syscalls = syscalls
+	return profile
+}
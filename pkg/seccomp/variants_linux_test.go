@@ -0,0 +1,38 @@
+package seccomp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileByName(t *testing.T) {
+	for _, name := range ProfileNames() {
+		profile, err := ProfileByName(name)
+		require.Nil(t, err, name)
+		require.Nil(t, Validate(profile).Err(), name)
+	}
+
+	_, err := ProfileByName("unknown")
+	require.NotNil(t, err)
+}
+
+func TestDiffVariants(t *testing.T) {
+	diff, err := DiffVariants(ProfileDefault, ProfileRestricted)
+	require.Nil(t, err)
+	require.Empty(t, diff.Added)
+	require.Contains(t, diff.Removed, "mount")
+	require.Contains(t, diff.Removed, "unshare")
+	// only granted with CAP_SYS_PTRACE by the default profile
+	require.Contains(t, diff.Removed, "ptrace")
+
+	diff, err = DiffVariants(ProfileRestricted, ProfileMinimal)
+	require.Nil(t, err)
+	require.Empty(t, diff.Added)
+	require.Empty(t, diff.Changed)
+	require.Contains(t, diff.Removed, "io_setup")
+	require.NotContains(t, diff.Removed, "read")
+
+	_, err = DiffVariants(ProfileDefault, "unknown")
+	require.NotNil(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 
+	"github.com/containers/common/pkg/umask"
 	"github.com/containers/storage/pkg/lockfile"
 	"github.com/pkg/errors"
 )
@@ -95,7 +96,7 @@ func (d *Driver) Store(id string, data []byte) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(d.secretsDataFilePath, marshalled, 0600)
+	err = umask.AtomicWriteFile(d.secretsDataFilePath, marshalled, 0600)
 	if err != nil {
 		return err
 	}
@@ -119,7 +120,7 @@ func (d *Driver) Delete(id string) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(d.secretsDataFilePath, marshalled, 0600)
+	err = umask.AtomicWriteFile(d.secretsDataFilePath, marshalled, 0600)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/containers/common/pkg/umask"
 	"github.com/pkg/errors"
 )
 
@@ -177,7 +178,7 @@ func (s *SecretsManager) store(entry *Secret) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(s.secretsDBPath, marshalled, 0600)
+	err = umask.AtomicWriteFile(s.secretsDBPath, marshalled, 0600)
 	if err != nil {
 		return err
 	}
@@ -203,7 +204,7 @@ func (s *SecretsManager) delete(nameOrID string) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(s.secretsDBPath, marshalled, 0600)
+	err = umask.AtomicWriteFile(s.secretsDBPath, marshalled, 0600)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,165 @@
+package signal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// platformTable holds the signals of a target platform.
+type platformTable struct {
+	signals map[string]syscall.Signal
+	// rtmin and rtmax are the range of real-time signals, if any.
+	rtmin, rtmax int
+}
+
+// Signal numbers shared by the Linux architectures, except for mips.
+var linuxSignals = map[string]syscall.Signal{
+	"ABRT": 6, "ALRM": 14, "BUS": 7, "CHLD": 17, "CLD": 17, "CONT": 18,
+	"FPE": 8, "HUP": 1, "ILL": 4, "INT": 2, "IO": 29, "IOT": 6, "KILL": 9,
+	"PIPE": 13, "POLL": 29, "PROF": 27, "PWR": 30, "QUIT": 3, "SEGV": 11,
+	"STKFLT": 16, "STOP": 19, "SYS": 31, "TERM": 15, "TRAP": 5, "TSTP": 20,
+	"TTIN": 21, "TTOU": 22, "URG": 23, "USR1": 10, "USR2": 12, "VTALRM": 26,
+	"WINCH": 28, "XCPU": 24, "XFSZ": 25,
+}
+
+// Signal numbers of the Linux mips architectures.
+var linuxMipsSignals = map[string]syscall.Signal{
+	"ABRT": 6, "ALRM": 14, "BUS": 10, "CHLD": 18, "CLD": 18, "CONT": 25,
+	"EMT": 7, "FPE": 8, "HUP": 1, "ILL": 4, "INT": 2, "IO": 22, "IOT": 6,
+	"KILL": 9, "PIPE": 13, "POLL": 22, "PROF": 29, "PWR": 19, "QUIT": 3,
+	"SEGV": 11, "STOP": 23, "SYS": 12, "TERM": 15, "TRAP": 5, "TSTP": 24,
+	"TTIN": 26, "TTOU": 27, "URG": 21, "USR1": 16, "USR2": 17, "VTALRM": 28,
+	"WINCH": 20, "XCPU": 30, "XFSZ": 31,
+}
+
+// Signal numbers of the BSD derived platforms.
+var bsdSignals = map[string]syscall.Signal{
+	"ABRT": 6, "ALRM": 14, "BUS": 10, "CHLD": 20, "CONT": 19, "EMT": 7,
+	"FPE": 8, "HUP": 1, "ILL": 4, "INFO": 29, "INT": 2, "IO": 23, "IOT": 6,
+	"KILL": 9, "PIPE": 13, "PROF": 27, "QUIT": 3, "SEGV": 11, "STOP": 17,
+	"SYS": 12, "TERM": 15, "TRAP": 5, "TSTP": 18, "TTIN": 21, "TTOU": 22,
+	"URG": 16, "USR1": 30, "USR2": 31, "VTALRM": 26, "WINCH": 28, "XCPU": 24,
+	"XFSZ": 25,
+}
+
+// platform returns the signals of the target platform.
+func platform(targetOS, targetArch string) (*platformTable, error) {
+	switch targetOS {
+	case "linux":
+		switch targetArch {
+		case "mips", "mipsle", "mips64", "mips64le":
+			return &platformTable{signals: linuxMipsSignals, rtmin: sigrtmin, rtmax: 127}, nil
+		case "alpha", "sparc", "sparc64":
+			return nil, fmt.Errorf("unsupported signal platform %s/%s", targetOS, targetArch)
+		}
+		return &platformTable{signals: linuxSignals, rtmin: sigrtmin, rtmax: 64}, nil
+	case "darwin", "freebsd":
+		return &platformTable{signals: bsdSignals}, nil
+	}
+	return nil, fmt.Errorf("unsupported signal platform %s/%s", targetOS, targetArch)
+}
+
+// parse translates a signal name or number to the signal of the platform.
+func (p *platformTable) parse(rawSignal string) (syscall.Signal, error) {
+	basename := strings.TrimPrefix(rawSignal, "-")
+	if s, err := strconv.Atoi(basename); err == nil {
+		if _, err := p.name(syscall.Signal(s)); err != nil {
+			return -1, fmt.Errorf("invalid signal: %s", basename)
+		}
+		return syscall.Signal(s), nil
+	}
+	name := strings.TrimPrefix(strings.ToUpper(basename), "SIG")
+	if sig, ok := p.signals[name]; ok {
+		return sig, nil
+	}
+	if p.rtmax > 0 {
+		for offset := 0; offset <= p.rtmax-p.rtmin; offset++ {
+			if name == p.rtName(p.rtmin+offset) {
+				return syscall.Signal(p.rtmin + offset), nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("invalid signal: %s", basename)
+}
+
+// name returns the name of the signal of the platform, without the "SIG"
+// prefix.  Of aliases like "CHLD" and "CLD" the alphabetically first is
+// returned.
+func (p *platformTable) name(sig syscall.Signal) (string, error) {
+	var names []string
+	for name, s := range p.signals {
+		if s == sig {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return names[0], nil
+	}
+	if p.rtmax > 0 && int(sig) >= p.rtmin && int(sig) <= p.rtmax {
+		return p.rtName(int(sig)), nil
+	}
+	return "", fmt.Errorf("invalid signal: %d", sig)
+}
+
+// rtName returns the name of a real-time signal, "RTMIN+n" for the lower
+// half of the range and "RTMAX-n" for the upper half.
+func (p *platformTable) rtName(sig int) string {
+	switch {
+	case sig == p.rtmin:
+		return "RTMIN"
+	case sig == p.rtmax:
+		return "RTMAX"
+	case sig-p.rtmin <= (p.rtmax-p.rtmin)/2:
+		return "RTMIN+" + strconv.Itoa(sig-p.rtmin)
+	}
+	return "RTMAX-" + strconv.Itoa(p.rtmax-sig)
+}
+
+// ParseSignalForPlatform translates a signal name or number, i.e. "KILL" or
+// "9", to the signal of the target platform, e.g. of a remote server.
+// Numbers must be valid signals of the target platform.
+func ParseSignalForPlatform(rawSignal, targetOS, targetArch string) (syscall.Signal, error) {
+	p, err := platform(targetOS, targetArch)
+	if err != nil {
+		return -1, err
+	}
+	return p.parse(rawSignal)
+}
+
+// SignalNameForPlatform returns the name of the signal of the target
+// platform, without the "SIG" prefix, e.g. "TERM" for 15.
+func SignalNameForPlatform(sig syscall.Signal, targetOS, targetArch string) (string, error) {
+	p, err := platform(targetOS, targetArch)
+	if err != nil {
+		return "", err
+	}
+	return p.name(sig)
+}
+
+// TranslateSignal translates the signal of the source platform to the same
+// signal of the target platform, e.g. USR1 (30) of a macOS client to USR1
+// (10) of a Linux server.
+func TranslateSignal(sig syscall.Signal, sourceOS, sourceArch, targetOS, targetArch string) (syscall.Signal, error) {
+	name, err := SignalNameForPlatform(sig, sourceOS, sourceArch)
+	if err != nil {
+		return -1, err
+	}
+	translated, err := ParseSignalForPlatform(name, targetOS, targetArch)
+	if err != nil {
+		return -1, fmt.Errorf("signal %s is not supported on %s/%s", name, targetOS, targetArch)
+	}
+	return translated, nil
+}
+
+// ValidateStopSignal checks that the stop signal of a container, as name or
+// number, is a valid signal of the target platform.
+func ValidateStopSignal(rawSignal, targetOS, targetArch string) error {
+	if _, err := ParseSignalForPlatform(rawSignal, targetOS, targetArch); err != nil {
+		return fmt.Errorf("invalid stop signal %q for %s/%s: %v", rawSignal, targetOS, targetArch, err)
+	}
+	return nil
+}
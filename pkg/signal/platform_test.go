@@ -0,0 +1,81 @@
+package signal
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatformTableMatchesHost(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("host signals are only known on Linux")
+	}
+	for name, sig := range signalMap {
+		parsed, err := ParseSignalForPlatform(name, runtime.GOOS, runtime.GOARCH)
+		require.NoError(t, err, name)
+		assert.Equal(t, sig, parsed, name)
+	}
+}
+
+func TestParseSignalForPlatform(t *testing.T) {
+	for _, tc := range []struct {
+		signal, os, arch string
+		expected         syscall.Signal
+	}{
+		{"USR1", "linux", "amd64", 10},
+		{"SIGUSR1", "linux", "mips64le", 16},
+		{"usr1", "darwin", "arm64", 30},
+		{"-TERM", "freebsd", "amd64", 15},
+		{"RTMIN+3", "linux", "arm64", 37},
+		{"RTMAX-1", "linux", "amd64", 63},
+		{"RTMAX", "linux", "mips", 127},
+		{"64", "linux", "amd64", 64},
+		{"29", "darwin", "amd64", 29},
+	} {
+		sig, err := ParseSignalForPlatform(tc.signal, tc.os, tc.arch)
+		require.NoError(t, err, tc.signal)
+		assert.Equal(t, tc.expected, sig, tc.signal)
+	}
+
+	for _, tc := range []struct{ signal, os, arch string }{
+		{"STKFLT", "linux", "mips"},
+		{"INFO", "linux", "amd64"},
+		{"RTMIN", "darwin", "amd64"},
+		{"0", "linux", "amd64"},
+		{"65", "linux", "amd64"},
+		{"33", "darwin", "amd64"},
+		{"TERM", "windows", "amd64"},
+		{"TERM", "linux", "sparc64"},
+	} {
+		_, err := ParseSignalForPlatform(tc.signal, tc.os, tc.arch)
+		assert.Error(t, err, tc.signal)
+		assert.Error(t, ValidateStopSignal(tc.signal, tc.os, tc.arch), tc.signal)
+	}
+	assert.NoError(t, ValidateStopSignal("SIGRTMIN+3", "linux", "amd64"))
+}
+
+func TestSignalNameForPlatform(t *testing.T) {
+	name, err := SignalNameForPlatform(17, "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "CHLD", name)
+	name, err = SignalNameForPlatform(49, "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "RTMIN+15", name)
+	name, err = SignalNameForPlatform(50, "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "RTMAX-14", name)
+	_, err = SignalNameForPlatform(0, "linux", "amd64")
+	assert.Error(t, err)
+
+	sig, err := TranslateSignal(30, "darwin", "arm64", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, syscall.Signal(10), sig)
+	sig, err = TranslateSignal(10, "linux", "amd64", "linux", "mips")
+	require.NoError(t, err)
+	assert.Equal(t, syscall.Signal(16), sig)
+	_, err = TranslateSignal(29, "darwin", "arm64", "linux", "amd64")
+	assert.Error(t, err)
+}
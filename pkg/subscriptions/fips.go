@@ -0,0 +1,148 @@
+package subscriptions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/storage/pkg/idtools"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/selinux/go-selinux/label"
+	"github.com/pkg/errors"
+)
+
+var (
+	// systemFipsFile exists on hosts in FIPS mode using the older
+	// crypto-policies layout.
+	systemFipsFile = "/etc/system-fips"
+	// fipsEnabledFile contains "1" if the kernel runs in FIPS mode.
+	fipsEnabledFile = "/proc/sys/crypto/fips_enabled"
+)
+
+// fipsPolicy is a crypto-policies path of the image mounted in the container
+// in FIPS mode.
+type fipsPolicy struct {
+	source      string
+	destination string
+}
+
+// fipsPolicies are the crypto-policies paths of the older and newer layouts.
+var fipsPolicies = []fipsPolicy{
+	{source: "/usr/share/crypto-policies/back-ends/FIPS", destination: "/etc/crypto-policies/back-ends"},
+	// Newer layouts provide the policy configuration, such that tools
+	// like update-crypto-policies report the FIPS policy.
+	{source: "/usr/share/crypto-policies/default-fips-config", destination: "/etc/crypto-policies/config"},
+}
+
+// FIPSStatus reports whether the FIPS mode subscription is added to
+// containers, and why.
+type FIPSStatus struct {
+	// Inject is set if the FIPS mode subscription is added.
+	Inject bool
+	// Reason explains why the FIPS mode subscription is not added.
+	Reason string
+	// HostIndicators are the host files indicating that the host runs in
+	// FIPS mode.
+	HostIndicators []string
+	// PolicyFiles are the crypto-policies paths of the image mounted in
+	// the container.  Images without crypto-policies only get the
+	// /run/secrets/system-fips file.
+	PolicyFiles []string
+}
+
+// FIPSModeStatus reports whether MountsWithOptions adds the FIPS mode
+// subscription to a container with the image mounted at mountPoint.  An
+// empty mountPoint only reports the host side.
+func FIPSModeStatus(mountPoint string, disableFips bool) (*FIPSStatus, error) {
+	status := &FIPSStatus{}
+	if _, err := os.Stat(systemFipsFile); err == nil {
+		status.HostIndicators = append(status.HostIndicators, systemFipsFile)
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "checking %s", systemFipsFile)
+	}
+	data, err := ioutil.ReadFile(fipsEnabledFile)
+	switch {
+	case err == nil:
+		if strings.TrimSpace(string(data)) == "1" {
+			status.HostIndicators = append(status.HostIndicators, fipsEnabledFile)
+		}
+	case !os.IsNotExist(err):
+		return nil, errors.Wrapf(err, "checking %s", fipsEnabledFile)
+	}
+
+	if mountPoint != "" {
+		for _, policy := range fipsPolicies {
+			if _, err := os.Stat(filepath.Join(mountPoint, policy.source)); err == nil {
+				status.PolicyFiles = append(status.PolicyFiles, policy.source)
+			} else if !os.IsNotExist(err) {
+				return nil, errors.Wrapf(err, "checking FIPS crypto-policies %s", policy.source)
+			}
+		}
+	}
+
+	switch {
+	case disableFips:
+		status.Reason = "FIPS mode is disabled for the container"
+	case len(status.HostIndicators) == 0:
+		status.Reason = "host is not in FIPS mode: " + systemFipsFile + " does not exist and " + fipsEnabledFile + " is not 1"
+	default:
+		status.Inject = true
+	}
+	return status, nil
+}
+
+// addFIPSModeSubscription creates /run/secrets/system-fips in the container
+// root filesystem if the host is in FIPS mode and mounts the FIPS
+// crypto-policies of the status.
+// This enables the container to be FIPS compliant and run openssl in
+// FIPS mode as the host is also in FIPS mode.
+func addFIPSModeSubscription(mounts *[]rspec.Mount, status *FIPSStatus, containerWorkingDir, mountPoint, mountLabel string, uid, gid int) error {
+	subscriptionsDir := "/run/secrets"
+	ctrDirOnHost := filepath.Join(containerWorkingDir, subscriptionsDir)
+	if _, err := os.Stat(ctrDirOnHost); os.IsNotExist(err) {
+		if err = idtools.MkdirAllAs(ctrDirOnHost, 0755, uid, gid); err != nil { //nolint
+			return err
+		}
+		if err = label.Relabel(ctrDirOnHost, mountLabel, false); err != nil {
+			return errors.Wrapf(err, "applying correct labels on %q", ctrDirOnHost)
+		}
+	}
+	fipsFile := filepath.Join(ctrDirOnHost, "system-fips")
+	// In the event of restart, it is possible for the FIPS mode file to already exist
+	if _, err := os.Stat(fipsFile); os.IsNotExist(err) {
+		file, err := os.Create(fipsFile)
+		if err != nil {
+			return errors.Wrap(err, "creating system-fips file in container for FIPS mode")
+		}
+		defer file.Close()
+	}
+
+	if !mountExists(*mounts, subscriptionsDir) {
+		m := rspec.Mount{
+			Source:      ctrDirOnHost,
+			Destination: subscriptionsDir,
+			Type:        "bind",
+			Options:     []string{"bind", "rprivate"},
+		}
+		*mounts = append(*mounts, m)
+	}
+
+	for _, policy := range fipsPolicies {
+		found := false
+		for _, file := range status.PolicyFiles {
+			found = found || file == policy.source
+		}
+		if !found || mountExists(*mounts, policy.destination) {
+			continue
+		}
+		m := rspec.Mount{
+			Source:      filepath.Join(mountPoint, policy.source),
+			Destination: policy.destination,
+			Type:        "bind",
+			Options:     []string{"bind", "rprivate"},
+		}
+		*mounts = append(*mounts, m)
+	}
+	return nil
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/containers/common/pkg/umask"
-	"github.com/containers/storage/pkg/idtools"
 	rspec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/opencontainers/selinux/go-selinux/label"
 	"github.com/pkg/errors"
@@ -147,6 +146,49 @@ func getMountsMap(path string) (string, string, error) { //nolint
 	return "", "", errors.Errorf("unable to get host and container dir from path: %s", path)
 }
 
+// MountOptions are the options of MountsWithOptions.
+type MountOptions struct {
+	// MountLabel is the MAC/SELinux label for container content.
+	MountLabel string
+	// ContainerWorkingDir holds the private data for storing subscriptions
+	// on the host mounted in the container.
+	ContainerWorkingDir string
+	// MountFile overrides the mounts.conf files, for testing purposes only.
+	MountFile string
+	// MountPoint is the container image mountpoint.
+	MountPoint string
+	// UID and GID are assigned to content created for subscriptions.
+	UID int
+	GID int
+	// Rootless indicates whether the container is running in rootless mode.
+	Rootless bool
+	// DisableFips indicates whether the system should ignore FIPS mode.
+	DisableFips bool
+	// Template holds the values substituted in subscription files ending
+	// with TemplateSuffix.  If nil, templates are copied unchanged.
+	Template *TemplateData
+	// AdditionalSources are subscription sources of this container in the
+	// "host_path[:container_path]" format of mounts.conf, added to the
+	// sources of the mounts.conf file.
+	AdditionalSources []string
+	// Destinations overrides the container destination of subscription
+	// sources, indexed by the destination they would be mounted on
+	// otherwise, e.g. {"/run/secrets": "/run/entitlements"}.
+	Destinations map[string]string
+	// UserMounts are the mounts of the container.  Subscriptions of the
+	// mounts.conf files mounted on the destination of a user mount are
+	// skipped, while conflicting AdditionalSources are an error.
+	UserMounts []rspec.Mount
+}
+
+// subscriptionSource is a subscription source to be mounted in the container.
+type subscriptionSource struct {
+	host string
+	ctr  string
+	// origin is the mounts.conf file of the source, if any.
+	origin string
+}
+
 // MountsWithUIDGID copies, adds, and mounts the subscriptions to the container root filesystem
 // mountLabel: MAC/SELinux label for container content
 // containerWorkingDir: Private data for storing subscriptions on the host mounted in container.
@@ -157,49 +199,135 @@ func getMountsMap(path string) (string, string, error) { //nolint
 // rootless: indicates whether container is running in rootless mode
 // disableFips: indicates whether system should ignore fips mode
 func MountsWithUIDGID(mountLabel, containerWorkingDir, mountFile, mountPoint string, uid, gid int, rootless, disableFips bool) []rspec.Mount {
-	var (
-		subscriptionMounts []rspec.Mount
-		mountFiles         []string
-	)
+	mounts, err := MountsWithOptions(&MountOptions{
+		MountLabel:          mountLabel,
+		ContainerWorkingDir: containerWorkingDir,
+		MountFile:           mountFile,
+		MountPoint:          mountPoint,
+		UID:                 uid,
+		GID:                 gid,
+		Rootless:            rootless,
+		DisableFips:         disableFips,
+	})
+	if err != nil {
+		logrus.Errorf("error mounting subscriptions: %v", err)
+	}
+	return mounts
+}
+
+// MountsWithOptions copies, adds, and mounts the subscriptions to the
+// container root filesystem like MountsWithUIDGID, with the per-container
+// sources and destinations of the options.  Errors of the mounts.conf
+// entries are logged and the entries skipped, errors of the
+// AdditionalSources are returned.
+func MountsWithOptions(options *MountOptions) ([]rspec.Mount, error) {
+	sources, err := subscriptionSources(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptionMounts []rspec.Mount
+	for _, source := range sources {
+		m, err := addSubscription(source, options.MountLabel, options.ContainerWorkingDir, options.UID, options.GID, options.Template)
+		if err != nil {
+			if source.origin == "" {
+				return nil, err
+			}
+			logrus.Warnf("error mounting subscriptions, skipping entry in %s: %v", source.origin, err)
+			continue
+		}
+		if m != nil {
+			subscriptionMounts = append(subscriptionMounts, *m)
+		}
+	}
+
+	status, err := FIPSModeStatus(options.MountPoint, options.DisableFips)
+	if err != nil {
+		logrus.Errorf("error checking FIPS mode: %v", err)
+		return subscriptionMounts, nil
+	}
+	if !status.Inject {
+		logrus.Debugf("Not mounting FIPS mode subscription: %s", status.Reason)
+		return subscriptionMounts, nil
+	}
+	if err := addFIPSModeSubscription(&subscriptionMounts, status, options.ContainerWorkingDir, options.MountPoint, options.MountLabel, options.UID, options.GID); err != nil {
+		logrus.Errorf("error adding FIPS mode subscription to container: %v", err)
+	}
+	return subscriptionMounts, nil
+}
+
+// subscriptionSources returns the sources of the first existing mounts.conf
+// file and the additional sources with their destinations overridden.
+// Sources with the same destination as a user mount or a previous source are
+// skipped or rejected.
+func subscriptionSources(options *MountOptions) ([]subscriptionSource, error) {
+	var mountFiles []string
 	// Add subscriptions from paths given in the mounts.conf files
 	// mountFile will have a value if the hidden --default-mounts-file flag is set
 	// Note for testing purposes only
-	if mountFile == "" {
+	if options.MountFile == "" {
 		mountFiles = append(mountFiles, []string{OverrideMountsFile, DefaultMountsFile}...)
-		if rootless {
+		if options.Rootless {
 			mountFiles = append([]string{UserOverrideMountsFile}, mountFiles...)
 		}
 	} else {
-		mountFiles = append(mountFiles, mountFile)
+		mountFiles = append(mountFiles, options.MountFile)
 	}
+
+	var sources []subscriptionSource
 	for _, file := range mountFiles {
 		if _, err := os.Stat(file); err == nil {
-			mounts, err := addSubscriptionsFromMountsFile(file, mountLabel, containerWorkingDir, uid, gid)
-			if err != nil {
-				logrus.Warnf("error mounting subscriptions, skipping entry in %s: %v", file, err)
+			for _, path := range getMounts(file) {
+				hostDirOrFile, ctrDirOrFile, err := getMountsMap(path)
+				if err != nil {
+					logrus.Warnf("error mounting subscriptions, skipping entry in %s: %v", file, err)
+					continue
+				}
+				sources = append(sources, subscriptionSource{host: hostDirOrFile, ctr: ctrDirOrFile, origin: file})
 			}
-			subscriptionMounts = mounts
 			break
 		}
 	}
-
-	// Only add FIPS subscription mount if disableFips=false
-	if disableFips {
-		return subscriptionMounts
+	for _, path := range options.AdditionalSources {
+		hostDirOrFile, ctrDirOrFile, err := getMountsMap(path)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(hostDirOrFile) || !filepath.IsAbs(ctrDirOrFile) {
+			return nil, errors.Errorf("invalid subscription source %q: paths must be absolute", path)
+		}
+		sources = append(sources, subscriptionSource{host: hostDirOrFile, ctr: ctrDirOrFile})
 	}
-	// Add FIPS mode subscription if /etc/system-fips exists on the host
-	_, err := os.Stat("/etc/system-fips")
-	switch {
-	case err == nil:
-		if err := addFIPSModeSubscription(&subscriptionMounts, containerWorkingDir, mountPoint, mountLabel, uid, gid); err != nil {
-			logrus.Errorf("error adding FIPS mode subscription to container: %v", err)
+
+	var result []subscriptionSource
+	destinations := make(map[string]bool)
+	for _, source := range sources {
+		if dest, ok := options.Destinations[filepath.Clean(source.ctr)]; ok {
+			if !filepath.IsAbs(dest) {
+				return nil, errors.Errorf("invalid destination %q of subscriptions mounted on %q: path must be absolute", dest, source.ctr)
+			}
+			source.ctr = dest
+		}
+		source.ctr = filepath.Clean(source.ctr)
+
+		var conflict string
+		switch {
+		case mountExists(options.UserMounts, source.ctr):
+			conflict = "a container mount"
+		case destinations[source.ctr]:
+			conflict = "another subscription"
+		}
+		if conflict != "" {
+			if source.origin == "" {
+				return nil, errors.Errorf("subscriptions from %q conflict with %s on %q", source.host, conflict, source.ctr)
+			}
+			logrus.Debugf("Skipping subscriptions from %q in %s: %q is used by %s", source.host, source.origin, source.ctr, conflict)
+			continue
 		}
-	case os.IsNotExist(err):
-		logrus.Debug("/etc/system-fips does not exist on host, not mounting FIPS mode subscription")
-	default:
-		logrus.Errorf("stat /etc/system-fips failed for FIPS mode subscription: %v", err)
+		destinations[source.ctr] = true
+		result = append(result, source)
 	}
-	return subscriptionMounts
+	return result, nil
 }
 
 func rchown(chowndir string, uid, gid int) error {
@@ -208,159 +336,99 @@ func rchown(chowndir string, uid, gid int) error {
 	})
 }
 
-// addSubscriptionsFromMountsFile copies the contents of host directory to container directory
-// and returns a list of mounts
-func addSubscriptionsFromMountsFile(filePath, mountLabel, containerWorkingDir string, uid, gid int) ([]rspec.Mount, error) {
-	var mounts []rspec.Mount
-	defaultMountsPaths := getMounts(filePath)
-	for _, path := range defaultMountsPaths {
-		hostDirOrFile, ctrDirOrFile, err := getMountsMap(path)
-		if err != nil {
-			return nil, err
+// addSubscription copies the contents of the host directory or file of the
+// source to the container directory, expanding templates, and returns its
+// mount, or nil if the host path does not exist.
+func addSubscription(source subscriptionSource, mountLabel, containerWorkingDir string, uid, gid int, template *TemplateData) (*rspec.Mount, error) {
+	hostDirOrFile, ctrDirOrFile := source.host, source.ctr
+	// skip if the hostDirOrFile path doesn't exist
+	fileInfo, err := os.Stat(hostDirOrFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if source.origin == "" {
+				return nil, errors.Wrap(err, "subscription source")
+			}
+			logrus.Warnf("Path %q from %q doesn't exist, skipping", hostDirOrFile, source.origin)
+			return nil, nil
 		}
-		// skip if the hostDirOrFile path doesn't exist
-		fileInfo, err := os.Stat(hostDirOrFile)
+		return nil, err
+	}
+
+	ctrDirOrFileOnHost := filepath.Join(containerWorkingDir, ctrDirOrFile)
+
+	// In the event of a restart, don't want to copy subscriptions over again as they already would exist in ctrDirOrFileOnHost
+	_, err = os.Stat(ctrDirOrFileOnHost)
+	if os.IsNotExist(err) {
+
+		hostDirOrFile, err = resolveSymbolicLink(hostDirOrFile)
 		if err != nil {
-			if os.IsNotExist(err) {
-				logrus.Warnf("Path %q from %q doesn't exist, skipping", hostDirOrFile, filePath)
-				continue
-			}
 			return nil, err
 		}
 
-		ctrDirOrFileOnHost := filepath.Join(containerWorkingDir, ctrDirOrFile)
+		// Don't let the umask have any influence on the file and directory creation
+		oldUmask := umask.Set(0)
+		defer umask.Set(oldUmask)
 
-		// In the event of a restart, don't want to copy subscriptions over again as they already would exist in ctrDirOrFileOnHost
-		_, err = os.Stat(ctrDirOrFileOnHost)
-		if os.IsNotExist(err) {
-
-			hostDirOrFile, err = resolveSymbolicLink(hostDirOrFile)
+		switch mode := fileInfo.Mode(); {
+		case mode.IsDir():
+			if err = os.MkdirAll(ctrDirOrFileOnHost, mode.Perm()); err != nil {
+				return nil, errors.Wrap(err, "making container directory")
+			}
+			data, err := getHostSubscriptionData(hostDirOrFile, mode.Perm())
 			if err != nil {
-				return nil, err
+				return nil, errors.Wrap(err, "getting host subscription data")
 			}
-
-			// Don't let the umask have any influence on the file and directory creation
-			oldUmask := umask.Set(0)
-			defer umask.Set(oldUmask)
-
-			switch mode := fileInfo.Mode(); {
-			case mode.IsDir():
-				if err = os.MkdirAll(ctrDirOrFileOnHost, mode.Perm()); err != nil {
-					return nil, errors.Wrap(err, "making container directory")
-				}
-				data, err := getHostSubscriptionData(hostDirOrFile, mode.Perm())
-				if err != nil {
-					return nil, errors.Wrap(err, "getting host subscription data")
-				}
-				for _, s := range data {
-					if err := s.saveTo(ctrDirOrFileOnHost); err != nil {
-						return nil, errors.Wrapf(err, "error saving data to container filesystem on host %q", ctrDirOrFileOnHost)
-					}
+			for _, s := range data {
+				if err := s.expand(template).saveTo(ctrDirOrFileOnHost); err != nil {
+					return nil, errors.Wrapf(err, "error saving data to container filesystem on host %q", ctrDirOrFileOnHost)
 				}
-			case mode.IsRegular():
-				data, err := readFileOrDir("", hostDirOrFile, mode.Perm())
-				if err != nil {
-					return nil, err
-
-				}
-				for _, s := range data {
-					if err := os.MkdirAll(filepath.Dir(ctrDirOrFileOnHost), s.dirMode); err != nil {
-						return nil, err
-					}
-					if err := ioutil.WriteFile(ctrDirOrFileOnHost, s.data, s.mode); err != nil {
-						return nil, errors.Wrap(err, "saving data to container filesystem")
-					}
-				}
-			default:
-				return nil, errors.Errorf("unsupported file type for: %q", hostDirOrFile)
 			}
-
-			err = label.Relabel(ctrDirOrFileOnHost, mountLabel, false)
+		case mode.IsRegular():
+			data, err := readFileOrDir("", hostDirOrFile, mode.Perm())
 			if err != nil {
-				return nil, errors.Wrap(err, "error applying correct labels")
+				return nil, err
+
 			}
-			if uid != 0 || gid != 0 {
-				if err := rchown(ctrDirOrFileOnHost, uid, gid); err != nil {
+			for _, s := range data {
+				// The destination of a file is explicit, only the
+				// content of a template is expanded.
+				s = s.expand(template)
+				if err := os.MkdirAll(filepath.Dir(ctrDirOrFileOnHost), s.dirMode); err != nil {
 					return nil, err
 				}
+				if err := ioutil.WriteFile(ctrDirOrFileOnHost, s.data, s.mode); err != nil {
+					return nil, errors.Wrap(err, "saving data to container filesystem")
+				}
 			}
-		} else if err != nil {
-			return nil, err
-		}
-
-		m := rspec.Mount{
-			Source:      ctrDirOrFileOnHost,
-			Destination: ctrDirOrFile,
-			Type:        "bind",
-			Options:     []string{"bind", "rprivate"},
+		default:
+			return nil, errors.Errorf("unsupported file type for: %q", hostDirOrFile)
 		}
 
-		mounts = append(mounts, m)
-	}
-	return mounts, nil
-}
-
-// addFIPSModeSubscription creates /run/secrets/system-fips in the container
-// root filesystem if /etc/system-fips exists on hosts.
-// This enables the container to be FIPS compliant and run openssl in
-// FIPS mode as the host is also in FIPS mode.
-func addFIPSModeSubscription(mounts *[]rspec.Mount, containerWorkingDir, mountPoint, mountLabel string, uid, gid int) error {
-	subscriptionsDir := "/run/secrets"
-	ctrDirOnHost := filepath.Join(containerWorkingDir, subscriptionsDir)
-	if _, err := os.Stat(ctrDirOnHost); os.IsNotExist(err) {
-		if err = idtools.MkdirAllAs(ctrDirOnHost, 0755, uid, gid); err != nil { //nolint
-			return err
-		}
-		if err = label.Relabel(ctrDirOnHost, mountLabel, false); err != nil {
-			return errors.Wrapf(err, "applying correct labels on %q", ctrDirOnHost)
-		}
-	}
-	fipsFile := filepath.Join(ctrDirOnHost, "system-fips")
-	// In the event of restart, it is possible for the FIPS mode file to already exist
-	if _, err := os.Stat(fipsFile); os.IsNotExist(err) {
-		file, err := os.Create(fipsFile)
+		err = label.Relabel(ctrDirOrFileOnHost, mountLabel, false)
 		if err != nil {
-			return errors.Wrap(err, "creating system-fips file in container for FIPS mode")
+			return nil, errors.Wrap(err, "error applying correct labels")
 		}
-		defer file.Close()
-	}
-
-	if !mountExists(*mounts, subscriptionsDir) {
-		m := rspec.Mount{
-			Source:      ctrDirOnHost,
-			Destination: subscriptionsDir,
-			Type:        "bind",
-			Options:     []string{"bind", "rprivate"},
-		}
-		*mounts = append(*mounts, m)
-	}
-
-	srcBackendDir := "/usr/share/crypto-policies/back-ends/FIPS"
-	destDir := "/etc/crypto-policies/back-ends"
-	srcOnHost := filepath.Join(mountPoint, srcBackendDir)
-	if _, err := os.Stat(srcOnHost); err != nil {
-		if os.IsNotExist(err) {
-			return nil
+		if uid != 0 || gid != 0 {
+			if err := rchown(ctrDirOrFileOnHost, uid, gid); err != nil {
+				return nil, err
+			}
 		}
-		return errors.Wrap(err, "FIPS Backend directory")
+	} else if err != nil {
+		return nil, err
 	}
 
-	if !mountExists(*mounts, destDir) {
-		m := rspec.Mount{
-			Source:      srcOnHost,
-			Destination: destDir,
-			Type:        "bind",
-			Options:     []string{"bind", "rprivate"},
-		}
-		*mounts = append(*mounts, m)
-	}
-	return nil
+	return &rspec.Mount{
+		Source:      ctrDirOrFileOnHost,
+		Destination: ctrDirOrFile,
+		Type:        "bind",
+		Options:     []string{"bind", "rprivate"},
+	}, nil
 }
 
 // mountExists checks if a mount already exists in the spec
 func mountExists(mounts []rspec.Mount, dest string) bool {
 	for _, mount := range mounts {
-		if mount.Destination == dest {
+		if filepath.Clean(mount.Destination) == filepath.Clean(dest) {
 			return true
 		}
 	}
@@ -0,0 +1,188 @@
+package subscriptions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSources creates two subscription sources and a mounts.conf file
+// mounting the first one and returns the test directory.
+func setupSources(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "subscriptions")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, name := range []string{"secrets", "entitlements"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, "key"), []byte(name), 0600))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "mounts.conf"), []byte(filepath.Join(dir, "secrets")+":/run/secrets\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "workdir"), 0755))
+	return dir
+}
+
+func destinations(mounts []rspec.Mount) []string {
+	var dests []string
+	for _, m := range mounts {
+		dests = append(dests, m.Destination)
+	}
+	return dests
+}
+
+func TestMountsWithOptions(t *testing.T) {
+	dir := setupSources(t)
+	options := MountOptions{
+		ContainerWorkingDir: filepath.Join(dir, "workdir"),
+		MountFile:           filepath.Join(dir, "mounts.conf"),
+		DisableFips:         true,
+	}
+
+	mounts, err := MountsWithOptions(&options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/run/secrets"}, destinations(mounts))
+	data, err := ioutil.ReadFile(filepath.Join(mounts[0].Source, "key"))
+	require.NoError(t, err)
+	assert.Equal(t, "secrets", string(data))
+
+	// Additional sources and overridden destinations
+	options.ContainerWorkingDir = filepath.Join(dir, "workdir2")
+	options.AdditionalSources = []string{filepath.Join(dir, "entitlements") + ":/etc/pki/entitlement"}
+	options.Destinations = map[string]string{"/run/secrets": "/run/other-secrets"}
+	mounts, err = MountsWithOptions(&options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/run/other-secrets", "/etc/pki/entitlement"}, destinations(mounts))
+	data, err = ioutil.ReadFile(filepath.Join(mounts[1].Source, "key"))
+	require.NoError(t, err)
+	assert.Equal(t, "entitlements", string(data))
+
+	// mounts.conf entries conflicting with user mounts are skipped
+	options.ContainerWorkingDir = filepath.Join(dir, "workdir3")
+	options.Destinations = nil
+	options.UserMounts = []rspec.Mount{{Destination: "/run/secrets/"}}
+	mounts, err = MountsWithOptions(&options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/etc/pki/entitlement"}, destinations(mounts))
+
+	// Conflicting additional sources are rejected
+	options.UserMounts = []rspec.Mount{{Destination: "/etc/pki/entitlement"}}
+	_, err = MountsWithOptions(&options)
+	assert.Error(t, err)
+
+	options.UserMounts = nil
+	options.AdditionalSources = []string{filepath.Join(dir, "entitlements") + ":/run/secrets"}
+	_, err = MountsWithOptions(&options)
+	assert.Error(t, err)
+
+	for _, source := range []string{"relative:/run/secrets", filepath.Join(dir, "missing")} {
+		options.AdditionalSources = []string{source}
+		_, err = MountsWithOptions(&options)
+		assert.Error(t, err, source)
+	}
+
+	options.AdditionalSources = nil
+	options.Destinations = map[string]string{"/run/secrets": "relative"}
+	_, err = MountsWithOptions(&options)
+	assert.Error(t, err)
+}
+
+func TestMountsWithTemplates(t *testing.T) {
+	dir := setupSources(t)
+	template := "server=${HOSTNAME}.example.com\nname=${CONTAINER_NAME}\nimage=${IMAGE_NAME}\nregion=${REGION}\nhome=${HOME} $USER\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secrets", "rhsm.conf.template"), []byte(template), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "single.template"), []byte("${CONTAINER_ID}"), 0644))
+
+	options := MountOptions{
+		ContainerWorkingDir: filepath.Join(dir, "workdir"),
+		MountFile:           filepath.Join(dir, "mounts.conf"),
+		DisableFips:         true,
+		AdditionalSources:   []string{filepath.Join(dir, "single.template") + ":/etc/container-id"},
+		Template: &TemplateData{
+			ContainerName: "web",
+			ContainerID:   "abc123",
+			ImageName:     "quay.io/example/web:latest",
+			Hostname:      "web-1",
+			Variables:     map[string]string{"REGION": "eu", "HOSTNAME": "ignored"},
+		},
+	}
+	mounts, err := MountsWithOptions(&options)
+	require.NoError(t, err)
+	require.Len(t, mounts, 2)
+
+	data, err := ioutil.ReadFile(filepath.Join(mounts[0].Source, "rhsm.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "server=web-1.example.com\nname=web\nimage=quay.io/example/web:latest\nregion=eu\nhome=${HOME} $USER\n", string(data))
+	_, err = os.Stat(filepath.Join(mounts[0].Source, "rhsm.conf.template"))
+	assert.True(t, os.IsNotExist(err))
+	data, err = ioutil.ReadFile(filepath.Join(mounts[0].Source, "key"))
+	require.NoError(t, err)
+	assert.Equal(t, "secrets", string(data))
+
+	data, err = ioutil.ReadFile(mounts[1].Source)
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", string(data))
+
+	// Without template data, templates are copied unchanged
+	options.ContainerWorkingDir = filepath.Join(dir, "workdir2")
+	options.Template = nil
+	mounts, err = MountsWithOptions(&options)
+	require.NoError(t, err)
+	data, err = ioutil.ReadFile(filepath.Join(mounts[0].Source, "rhsm.conf.template"))
+	require.NoError(t, err)
+	assert.Equal(t, template, string(data))
+}
+
+func TestFIPSModeStatus(t *testing.T) {
+	dir := setupSources(t)
+	oldSystemFipsFile, oldFipsEnabledFile := systemFipsFile, fipsEnabledFile
+	defer func() { systemFipsFile, fipsEnabledFile = oldSystemFipsFile, oldFipsEnabledFile }()
+	systemFipsFile = filepath.Join(dir, "system-fips")
+	fipsEnabledFile = filepath.Join(dir, "fips_enabled")
+
+	mountPoint := filepath.Join(dir, "rootfs")
+	require.NoError(t, os.MkdirAll(filepath.Join(mountPoint, "/usr/share/crypto-policies/back-ends/FIPS"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPoint, "/usr/share/crypto-policies/default-fips-config"), []byte("FIPS\n"), 0644))
+
+	// Host not in FIPS mode
+	require.NoError(t, ioutil.WriteFile(fipsEnabledFile, []byte("0\n"), 0644))
+	status, err := FIPSModeStatus(mountPoint, false)
+	require.NoError(t, err)
+	assert.False(t, status.Inject)
+	assert.NotEmpty(t, status.Reason)
+	assert.Empty(t, status.HostIndicators)
+	assert.Equal(t, []string{"/usr/share/crypto-policies/back-ends/FIPS", "/usr/share/crypto-policies/default-fips-config"}, status.PolicyFiles)
+
+	// Newer hosts only report FIPS mode in the kernel
+	require.NoError(t, ioutil.WriteFile(fipsEnabledFile, []byte("1\n"), 0644))
+	status, err = FIPSModeStatus(mountPoint, false)
+	require.NoError(t, err)
+	assert.True(t, status.Inject)
+	assert.Equal(t, []string{fipsEnabledFile}, status.HostIndicators)
+
+	status, err = FIPSModeStatus(mountPoint, true)
+	require.NoError(t, err)
+	assert.False(t, status.Inject)
+	assert.NotEmpty(t, status.Reason)
+
+	require.NoError(t, ioutil.WriteFile(systemFipsFile, nil, 0644))
+	status, err = FIPSModeStatus("", false)
+	require.NoError(t, err)
+	assert.True(t, status.Inject)
+	assert.Equal(t, []string{systemFipsFile, fipsEnabledFile}, status.HostIndicators)
+	assert.Empty(t, status.PolicyFiles)
+
+	mounts, err := MountsWithOptions(&MountOptions{
+		ContainerWorkingDir: filepath.Join(dir, "workdir"),
+		MountFile:           filepath.Join(dir, "mounts.conf"),
+		MountPoint:          mountPoint,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/run/secrets", "/etc/crypto-policies/back-ends", "/etc/crypto-policies/config"}, destinations(mounts))
+	_, err = os.Stat(filepath.Join(dir, "workdir", "run", "secrets", "system-fips"))
+	assert.NoError(t, err)
+}
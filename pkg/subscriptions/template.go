@@ -0,0 +1,81 @@
+package subscriptions
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// TemplateSuffix is the suffix of subscription files whose content is
+	// expanded when they are copied into the container.  The suffix is
+	// removed from the name of the copied file, e.g. "rhsm.conf.template"
+	// is copied as "rhsm.conf".
+	TemplateSuffix = ".template"
+
+	// ContainerNameVariable is the template variable of the container name.
+	ContainerNameVariable = "CONTAINER_NAME"
+	// ContainerIDVariable is the template variable of the container ID.
+	ContainerIDVariable = "CONTAINER_ID"
+	// ImageNameVariable is the template variable of the image name.
+	ImageNameVariable = "IMAGE_NAME"
+	// HostnameVariable is the template variable of the container hostname.
+	HostnameVariable = "HOSTNAME"
+)
+
+// templateVariableRegexp matches the "${NAME}" references of templates.
+var templateVariableRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// TemplateData holds the values substituted in subscription templates.
+type TemplateData struct {
+	ContainerName string
+	ContainerID   string
+	ImageName     string
+	Hostname      string
+	// Variables are additional variables, e.g. from the environment of
+	// the container.  They do not override the fields above.
+	Variables map[string]string
+}
+
+// variables returns the template variables of the data.  Empty fields are
+// not defined.
+func (d *TemplateData) variables() map[string]string {
+	vars := make(map[string]string, len(d.Variables)+4)
+	for name, value := range d.Variables {
+		vars[name] = value
+	}
+	for name, value := range map[string]string{
+		ContainerNameVariable: d.ContainerName,
+		ContainerIDVariable:   d.ContainerID,
+		ImageNameVariable:     d.ImageName,
+		HostnameVariable:      d.Hostname,
+	} {
+		if value != "" {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// expandTemplate replaces the "${NAME}" references of the defined variables
+// in content.  References of undefined variables are left unchanged, so
+// shell snippets in the content survive the expansion.
+func expandTemplate(content []byte, vars map[string]string) []byte {
+	return templateVariableRegexp.ReplaceAllFunc(content, func(ref []byte) []byte {
+		if value, ok := vars[string(ref[2:len(ref)-1])]; ok {
+			return []byte(value)
+		}
+		return ref
+	})
+}
+
+// expand expands the subscription if it is a template and returns it under
+// its name without the TemplateSuffix.  Other subscriptions are returned
+// unchanged.  A nil data disables templating.
+func (s subscriptionData) expand(data *TemplateData) subscriptionData {
+	if data == nil || !strings.HasSuffix(s.name, TemplateSuffix) {
+		return s
+	}
+	s.name = strings.TrimSuffix(s.name, TemplateSuffix)
+	s.data = expandTemplate(s.data, data.variables())
+	return s
+}
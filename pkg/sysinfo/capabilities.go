@@ -0,0 +1,129 @@
+package sysinfo
+
+import (
+	"fmt"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// Capabilities describes which resource limits can be applied to the
+// containers of the current user.
+type Capabilities struct {
+	// CgroupVersion is 1 or 2.
+	CgroupVersion int
+	// Rootless is set if the capabilities are those of an unprivileged
+	// user.
+	Rootless bool
+	// Scope is the cgroup the limits are managed in: the systemd user
+	// instance of rootless users on cgroup v2 and the root cgroup
+	// otherwise.  It is empty if no cgroup can be managed.
+	Scope string
+	// Controllers are the controllers available in Scope.
+	Controllers []string
+
+	MemoryLimit       bool
+	MemoryReservation bool
+	// SwapLimit is set if swap accounting is enabled.
+	SwapLimit bool
+	// SwapAvailable is set if the host has active swap space.
+	SwapAvailable bool
+
+	CPUShares   bool
+	CPUQuota    bool
+	CPURealtime bool
+
+	Cpuset bool
+	// Cpus and Mems are the CPUs and memory nodes available in Scope.
+	Cpus string
+	Mems string
+
+	PidsLimit bool
+
+	BlkioWeight   bool
+	BlkioThrottle bool
+
+	HugetlbLimit bool
+}
+
+// HasController returns true if the controller is available in Scope.
+func (c *Capabilities) HasController(controller string) bool {
+	for _, ctrl := range c.Controllers {
+		if ctrl == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings returns a warning for each of the requested resource limits
+// which cannot be applied, for engines to report before creating the
+// container.
+func (c *Capabilities) Warnings(resources *rspec.LinuxResources) []string {
+	if resources == nil {
+		return nil
+	}
+	type check struct {
+		name       string
+		controller string
+		requested  bool
+		supported  bool
+	}
+	var checks []check
+	if m := resources.Memory; m != nil {
+		checks = append(checks,
+			check{"memory limit", "memory", m.Limit != nil, c.MemoryLimit},
+			check{"memory reservation", "memory", m.Reservation != nil, c.MemoryReservation},
+			check{"swap limit", "memory", m.Swap != nil, c.SwapLimit},
+		)
+	}
+	if cpu := resources.CPU; cpu != nil {
+		checks = append(checks,
+			check{"CPU shares", "cpu", cpu.Shares != nil, c.CPUShares},
+			check{"CPU quota", "cpu", cpu.Quota != nil || cpu.Period != nil, c.CPUQuota},
+			check{"CPU real-time scheduling", "cpu", cpu.RealtimeRuntime != nil || cpu.RealtimePeriod != nil, c.CPURealtime},
+			check{"cpuset", "cpuset", cpu.Cpus != "" || cpu.Mems != "", c.Cpuset},
+		)
+	}
+	if resources.Pids != nil {
+		checks = append(checks, check{"pids limit", "pids", true, c.PidsLimit})
+	}
+	if b := resources.BlockIO; b != nil {
+		checks = append(checks,
+			check{"block IO weight", c.ioController(), b.Weight != nil || len(b.WeightDevice) > 0, c.BlkioWeight},
+			check{"block IO throttling", c.ioController(), len(b.ThrottleReadBpsDevice)+len(b.ThrottleWriteBpsDevice)+len(b.ThrottleReadIOPSDevice)+len(b.ThrottleWriteIOPSDevice) > 0, c.BlkioThrottle},
+		)
+	}
+	if len(resources.HugepageLimits) > 0 {
+		checks = append(checks, check{"hugetlb limit", "hugetlb", true, c.HugetlbLimit})
+	}
+
+	var warnings []string
+	for _, ch := range checks {
+		if ch.requested && !ch.supported {
+			warnings = append(warnings, fmt.Sprintf("%s discarded: %s", ch.name, c.reason(ch.controller)))
+		}
+	}
+	return warnings
+}
+
+func (c *Capabilities) ioController() string {
+	if c.CgroupVersion == 1 {
+		return "blkio"
+	}
+	return "io"
+}
+
+// reason explains why a limit of the controller cannot be applied.
+func (c *Capabilities) reason(controller string) string {
+	switch {
+	case c.Scope == "" && c.Rootless:
+		return "rootless containers cannot use resource limits on cgroup v1"
+	case c.Scope == "":
+		return "no cgroup can be managed"
+	case !c.HasController(controller) && c.Rootless:
+		return fmt.Sprintf("the %s controller is not delegated to the user", controller)
+	case !c.HasController(controller):
+		return fmt.Sprintf("the %s controller is not available", controller)
+	}
+	return "not supported by the kernel"
+}
@@ -0,0 +1,197 @@
+package sysinfo
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containers/common/pkg/cgroupv2"
+	"github.com/containers/storage/pkg/unshare"
+	"github.com/pkg/errors"
+)
+
+// cgroupRoot, procSelfCgroup and procSwaps are overridden in tests.
+var (
+	cgroupRoot     = "/sys/fs/cgroup"
+	procSelfCgroup = "/proc/self/cgroup"
+	procSwaps      = "/proc/swaps"
+)
+
+// DetectCapabilities detects which resource limits can be applied to the
+// containers of the current user.
+func DetectCapabilities() (*Capabilities, error) {
+	unified, err := cgroupv2.Enabled()
+	if err != nil {
+		return nil, errors.Wrap(err, "checking cgroup version")
+	}
+	return detectCapabilities(unified, unshare.IsRootless())
+}
+
+func detectCapabilities(unified, rootless bool) (*Capabilities, error) {
+	c := &Capabilities{
+		CgroupVersion: 1,
+		Rootless:      rootless,
+		SwapAvailable: swapAvailable(),
+	}
+	if unified {
+		c.CgroupVersion = 2
+		if err := c.detectUnified(); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if rootless {
+		// The cgroup v1 hierarchies cannot be delegated to users.
+		return c, nil
+	}
+	cgMounts, err := findCgroupMountpoints()
+	if err != nil {
+		return nil, err
+	}
+	c.Scope = "/"
+	for controller := range cgMounts {
+		c.Controllers = append(c.Controllers, controller)
+	}
+	sort.Strings(c.Controllers)
+
+	mem := checkCgroupMem(cgMounts, true)
+	c.MemoryLimit, c.MemoryReservation, c.SwapLimit = mem.MemoryLimit, mem.MemoryReservation, mem.SwapLimit
+	cpu := checkCgroupCPU(cgMounts, true)
+	c.CPUShares, c.CPUQuota, c.CPURealtime = cpu.CPUShares, cpu.CPUCfsQuota, cpu.CPURealtimeRuntime
+	cpuset := checkCgroupCpusetInfo(cgMounts, true)
+	c.Cpuset, c.Cpus, c.Mems = cpuset.Cpuset, cpuset.Cpus, cpuset.Mems
+	blkio := checkCgroupBlkioInfo(cgMounts, true)
+	c.BlkioWeight, c.BlkioThrottle = blkio.BlkioWeight, blkio.BlkioReadBpsDevice
+	c.PidsLimit = c.HasController("pids")
+	c.HugetlbLimit = c.HasController("hugetlb")
+	return c, nil
+}
+
+// detectUnified detects the capabilities on the cgroup v2 hierarchy.
+func (c *Capabilities) detectUnified() error {
+	c.Scope = "/"
+	if c.Rootless {
+		own, err := ownCgroup()
+		if err != nil {
+			return err
+		}
+		c.Scope = delegationScope(own)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(cgroupRoot, c.Scope, "cgroup.controllers"))
+	if err != nil {
+		return errors.Wrapf(err, "reading controllers of cgroup %s", c.Scope)
+	}
+	c.Controllers = strings.Fields(string(data))
+	sort.Strings(c.Controllers)
+
+	if c.HasController("memory") {
+		c.MemoryLimit = true
+		c.MemoryReservation = true
+		c.SwapLimit = c.scopeFileExists("memory.swap.max")
+	}
+	if c.HasController("cpu") {
+		c.CPUShares = true
+		c.CPUQuota = true
+	}
+	if c.HasController("cpuset") {
+		cpus, mems, err := c.effectiveCpuset()
+		if err != nil {
+			return err
+		}
+		c.Cpuset, c.Cpus, c.Mems = true, cpus, mems
+	}
+	if c.HasController("io") {
+		c.BlkioWeight = c.scopeFileExists("io.weight") || c.scopeFileExists("io.bfq.weight")
+		c.BlkioThrottle = true
+	}
+	c.PidsLimit = c.HasController("pids")
+	c.HugetlbLimit = c.HasController("hugetlb")
+	return nil
+}
+
+// scopeFileExists returns true if the controller file exists in Scope.  The
+// root cgroup has no controller files, so its children are checked instead.
+func (c *Capabilities) scopeFileExists(name string) bool {
+	dir := filepath.Join(cgroupRoot, c.Scope)
+	if c.Scope != "/" {
+		return cgroupEnabled(dir, name)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", name))
+	return len(matches) > 0
+}
+
+// effectiveCpuset returns the CPUs and memory nodes available in Scope.
+func (c *Capabilities) effectiveCpuset() (string, string, error) {
+	if c.Scope == "/" {
+		cpus, err := OnlineCPUs()
+		if err != nil {
+			return "", "", err
+		}
+		nodes, err := NUMANodes()
+		if err != nil {
+			return "", "", err
+		}
+		ids := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			ids = append(ids, strconv.Itoa(node.ID))
+		}
+		return cpus, strings.Join(ids, ","), nil
+	}
+	dir := filepath.Join(cgroupRoot, c.Scope)
+	cpus, err := readList(filepath.Join(dir, "cpuset.cpus.effective"))
+	if err != nil {
+		return "", "", err
+	}
+	mems, err := readList(filepath.Join(dir, "cpuset.mems.effective"))
+	if err != nil {
+		return "", "", err
+	}
+	return cpus, mems, nil
+}
+
+// ownCgroup returns the cgroup of the current process on the unified
+// hierarchy.
+func ownCgroup() (string, error) {
+	f, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrapf(err, "reading %s", procSelfCgroup)
+	}
+	return "", errors.Errorf("no cgroup v2 entry in %s", procSelfCgroup)
+}
+
+// delegationScope returns the user@UID.service ancestor of the cgroup, or
+// the cgroup itself if it is not managed by a systemd user instance.
+func delegationScope(path string) string {
+	elements := strings.Split(filepath.Clean(path), "/")
+	for i, e := range elements {
+		if strings.HasPrefix(e, "user@") && strings.HasSuffix(e, ".service") {
+			return "/" + filepath.Join(elements[:i+1]...)
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// swapAvailable returns true if /proc/swaps lists a swap area below its
+// header line.
+func swapAvailable() bool {
+	data, err := ioutil.ReadFile(procSwaps)
+	if err != nil {
+		return false
+	}
+	return len(strings.Split(strings.TrimSpace(string(data)), "\n")) > 1
+}
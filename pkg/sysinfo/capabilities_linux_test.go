@@ -0,0 +1,101 @@
+package sysinfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCgroup(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestDetectCapabilitiesUnified(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-sysinfo-capabilities")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	oldRoot, oldCgroup, oldSwaps := cgroupRoot, procSelfCgroup, procSwaps
+	cgroupRoot = filepath.Join(tmpDir, "cgroup")
+	procSelfCgroup = filepath.Join(tmpDir, "self-cgroup")
+	procSwaps = filepath.Join(tmpDir, "swaps")
+	defer func() { cgroupRoot, procSelfCgroup, procSwaps = oldRoot, oldCgroup, oldSwaps }()
+
+	scope := "user.slice/user-1000.slice/user@1000.service"
+	setupCgroup(t, cgroupRoot, map[string]string{
+		"cgroup.controllers":                            "cpuset cpu io memory hugetlb pids\n",
+		"system.slice/memory.swap.max":                  "max\n",
+		scope + "/cgroup.controllers":                   "memory pids\n",
+		scope + "/app.slice/podman.scope/cgroup.procs":  "",
+		"user.slice/user-1000.slice/cgroup.controllers": "cpu memory pids\n",
+	})
+	require.NoError(t, ioutil.WriteFile(procSelfCgroup, []byte("0::/"+scope+"/app.slice/podman.scope\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(procSwaps, []byte("Filename\tType\tSize\tUsed\tPriority\n"), 0644))
+
+	c, err := detectCapabilities(true, true)
+	require.NoError(t, err)
+	assert.Equal(t, &Capabilities{
+		CgroupVersion:     2,
+		Rootless:          true,
+		Scope:             "/" + scope,
+		Controllers:       []string{"memory", "pids"},
+		MemoryLimit:       true,
+		MemoryReservation: true,
+		PidsLimit:         true,
+	}, c)
+
+	limit := int64(1024)
+	shares := uint64(512)
+	warnings := c.Warnings(&rspec.LinuxResources{
+		Memory: &rspec.LinuxMemory{Limit: &limit, Swap: &limit},
+		CPU:    &rspec.LinuxCPU{Shares: &shares},
+		Pids:   &rspec.LinuxPids{Limit: limit},
+	})
+	assert.Equal(t, []string{
+		"swap limit discarded: not supported by the kernel",
+		"CPU shares discarded: the cpu controller is not delegated to the user",
+	}, warnings)
+
+	c, err = detectCapabilities(true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "/", c.Scope)
+	assert.True(t, c.SwapLimit)
+	assert.True(t, c.CPUShares)
+	assert.True(t, c.BlkioThrottle)
+	assert.False(t, c.BlkioWeight)
+	assert.True(t, c.HugetlbLimit)
+	assert.False(t, c.SwapAvailable)
+	assert.Empty(t, c.Warnings(&rspec.LinuxResources{Memory: &rspec.LinuxMemory{Swap: &limit}}))
+}
+
+func TestCapabilitiesRootlessCgroupV1(t *testing.T) {
+	c, err := detectCapabilities(false, true)
+	require.NoError(t, err)
+	assert.Empty(t, c.Scope)
+	limit := int64(1024)
+	assert.Equal(t, []string{"pids limit discarded: rootless containers cannot use resource limits on cgroup v1"},
+		c.Warnings(&rspec.LinuxResources{Pids: &rspec.LinuxPids{Limit: limit}}))
+}
+
+func TestSwapAvailable(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-sysinfo-swaps")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	oldSwaps := procSwaps
+	procSwaps = filepath.Join(tmpDir, "swaps")
+	defer func() { procSwaps = oldSwaps }()
+
+	assert.False(t, swapAvailable())
+	require.NoError(t, ioutil.WriteFile(procSwaps, []byte("Filename\tType\tSize\tUsed\tPriority\n/dev/zram0\tpartition\t8388604\t0\t100\n"), 0644))
+	assert.True(t, swapAvailable())
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package sysinfo
+
+import "github.com/pkg/errors"
+
+// DetectCapabilities is not supported on this platform.
+func DetectCapabilities() (*Capabilities, error) {
+	return nil, errors.New("resource limits are only supported on Linux")
+}
@@ -0,0 +1,73 @@
+package sysinfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/pkg/errors"
+)
+
+// sysfsCPUPath and sysfsNodePath are overridden in tests.
+var (
+	sysfsCPUPath  = "/sys/devices/system/cpu"
+	sysfsNodePath = "/sys/devices/system/node"
+)
+
+// NUMANode is a NUMA node of the system.
+type NUMANode struct {
+	// ID of the node as used in cpuset.mems.
+	ID int
+	// CPUs local to the node in the cpuset list format (e.g., "0-3,8-11").
+	CPUs string
+}
+
+// OnlineCPUs returns the online CPUs of the system in the cpuset list format.
+func OnlineCPUs() (string, error) {
+	return readList(filepath.Join(sysfsCPUPath, "online"))
+}
+
+// NUMANodes returns the online NUMA nodes of the system, ordered by ID. On
+// systems without NUMA support a single node 0 with all online CPUs is
+// returned.
+func NUMANodes() ([]NUMANode, error) {
+	online, err := readList(filepath.Join(sysfsNodePath, "online"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		cpus, err := OnlineCPUs()
+		if err != nil {
+			return nil, err
+		}
+		return []NUMANode{{ID: 0, CPUs: cpus}}, nil
+	}
+	ids, err := parsers.ParseUintList(online)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing online NUMA nodes %q", online)
+	}
+
+	nodes := make([]NUMANode, 0, len(ids))
+	for id := 0; len(nodes) < len(ids); id++ {
+		if !ids[id] {
+			continue
+		}
+		cpus, err := readList(filepath.Join(sysfsNodePath, "node"+strconv.Itoa(id), "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, NUMANode{ID: id, CPUs: cpus})
+	}
+	return nodes, nil
+}
+
+func readList(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
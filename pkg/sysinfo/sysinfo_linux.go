@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/containers/common/pkg/cgroupv2"
+	"github.com/containers/storage/pkg/unshare"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -35,6 +36,12 @@ func New(quiet bool) *SysInfo {
 	cgMounts, err := findCgroupMountpoints()
 	if err != nil {
 		logrus.Warnf("Failed to parse cgroup information: %v", err)
+	} else if unified, _ := cgroupv2.Enabled(); unified {
+		// The cgroup v1 control files do not exist on the unified
+		// hierarchy.
+		if err := sysInfo.setUnified(quiet); err != nil {
+			logrus.Warnf("Failed to detect cgroup v2 capabilities: %v", err)
+		}
 	} else {
 		sysInfo.cgroupMemInfo = checkCgroupMem(cgMounts, quiet)
 		sysInfo.cgroupCPUInfo = checkCgroupCPU(cgMounts, quiet)
@@ -66,6 +73,46 @@ func New(quiet bool) *SysInfo {
 	return sysInfo
 }
 
+// setUnified sets the cgroup information from the capabilities detected on
+// the cgroup v2 hierarchy.
+func (sysInfo *SysInfo) setUnified(quiet bool) error {
+	c, err := detectCapabilities(true, unshare.IsRootless())
+	if err != nil {
+		return err
+	}
+	sysInfo.cgroupMemInfo = cgroupMemInfo{
+		MemoryLimit:       c.MemoryLimit,
+		SwapLimit:         c.SwapLimit,
+		MemoryReservation: c.MemoryReservation,
+	}
+	sysInfo.cgroupCPUInfo = cgroupCPUInfo{
+		CPUShares:    c.CPUShares,
+		CPUCfsPeriod: c.CPUQuota,
+		CPUCfsQuota:  c.CPUQuota,
+	}
+	sysInfo.cgroupBlkioInfo = cgroupBlkioInfo{
+		BlkioWeight:          c.BlkioWeight,
+		BlkioWeightDevice:    c.BlkioWeight,
+		BlkioReadBpsDevice:   c.BlkioThrottle,
+		BlkioWriteBpsDevice:  c.BlkioThrottle,
+		BlkioReadIOpsDevice:  c.BlkioThrottle,
+		BlkioWriteIOpsDevice: c.BlkioThrottle,
+	}
+	sysInfo.cgroupCpusetInfo = cgroupCpusetInfo{Cpuset: c.Cpuset, Cpus: c.Cpus, Mems: c.Mems}
+	sysInfo.cgroupPids = cgroupPids{PidsLimit: c.PidsLimit}
+	if !quiet {
+		for _, controller := range []string{"memory", "cpu", "cpuset", "io", "pids"} {
+			if !c.HasController(controller) {
+				logrus.Warnf("The %s controller is not available in cgroup %s", controller, c.Scope)
+			}
+		}
+		if c.MemoryLimit && !c.SwapLimit {
+			logrus.Warn("Your kernel does not support swap memory limit")
+		}
+	}
+	return nil
+}
+
 // checkCgroupMem reads the memory information from the memory cgroup mount point.
 func checkCgroupMem(cgMounts map[string]string, quiet bool) cgroupMemInfo {
 	mountPoint, ok := cgMounts["memory"]
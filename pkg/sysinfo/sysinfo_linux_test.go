@@ -102,3 +102,30 @@ func TestNumCPU(t *testing.T) {
 		t.Fatal("CPU returned must be greater than zero")
 	}
 }
+
+func TestNUMANodes(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-sysinfo-numa")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	oldCPUPath, oldNodePath := sysfsCPUPath, sysfsNodePath
+	sysfsCPUPath, sysfsNodePath = filepath.Join(tmpDir, "cpu"), filepath.Join(tmpDir, "node")
+	defer func() { sysfsCPUPath, sysfsNodePath = oldCPUPath, oldNodePath }()
+
+	require.NoError(t, os.MkdirAll(sysfsCPUPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sysfsCPUPath, "online"), []byte("0-7\n"), 0644))
+
+	// Without NUMA support all CPUs belong to node 0.
+	nodes, err := NUMANodes()
+	require.NoError(t, err)
+	require.Equal(t, []NUMANode{{ID: 0, CPUs: "0-7"}}, nodes)
+
+	for node, cpus := range map[string]string{"node0": "0-3\n", "node2": "4-7\n"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sysfsNodePath, node), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(sysfsNodePath, node, "cpulist"), []byte(cpus), 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sysfsNodePath, "online"), []byte("0,2\n"), 0644))
+	nodes, err = NUMANodes()
+	require.NoError(t, err)
+	require.Equal(t, []NUMANode{{ID: 0, CPUs: "0-3"}, {ID: 2, CPUs: "4-7"}}, nodes)
+}
@@ -0,0 +1,161 @@
+package timetype
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// relativeRegex matches the elements of relative expressions like "1w2d" or
+// "3 months", each a number followed by a unit.
+var relativeRegex = regexp.MustCompile(`^\s*([0-9]+(?:\.[0-9]+)?)\s*([a-zµ]+)`)
+
+// Interval is a range of time used by since and until filters.  A zero
+// Start or End leaves the interval open on that side.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains returns true if t is within the interval, including its bounds.
+func (i Interval) Contains(t time.Time) bool {
+	if !i.Start.IsZero() && t.Before(i.Start) {
+		return false
+	}
+	if !i.End.IsZero() && t.After(i.End) {
+		return false
+	}
+	return true
+}
+
+// ParseInterval parses a range "START..END" of times as accepted by
+// ParseTime, e.g. "2h..30m ago" or "2021-03-01..2021-04-01 Europe/Berlin".
+// A trailing "ago" applies to both times; either time may be omitted to
+// leave the interval open.
+func ParseInterval(value string, reference time.Time) (Interval, error) {
+	trimmed := strings.TrimSpace(value)
+	parts := strings.SplitN(trimmed, "..", 2)
+	if len(parts) != 2 {
+		return Interval{}, fmt.Errorf("invalid time range %q: missing \"..\"", value)
+	}
+	if strings.HasSuffix(parts[1], " ago") && !strings.HasSuffix(parts[0], " ago") && strings.TrimSpace(parts[0]) != "" {
+		parts[0] += " ago"
+	}
+
+	var interval Interval
+	var err error
+	if strings.TrimSpace(parts[0]) != "" {
+		if interval.Start, err = ParseTime(parts[0], reference); err != nil {
+			return Interval{}, err
+		}
+	}
+	if strings.TrimSpace(parts[1]) != "" {
+		if interval.End, err = ParseTime(parts[1], reference); err != nil {
+			return Interval{}, err
+		}
+	}
+	if !interval.Start.IsZero() && !interval.End.IsZero() && interval.End.Before(interval.Start) {
+		return Interval{}, fmt.Errorf("invalid time range %q: end is before start", value)
+	}
+	return interval, nil
+}
+
+// ParseTime parses the time of since and until filters.  It accepts the
+// values of GetTimestamp, relative expressions with an optional "ago" suffix
+// and the units of time.ParseDuration plus days ("d"), weeks ("w"), months
+// ("mo") and years ("y"), e.g. "1w2d ago" or "3 months", and timestamps
+// qualified with a timezone name, e.g. "2021-03-01T10:00 Europe/Berlin".
+// Relative expressions are always in the past of the reference time.
+func ParseTime(value string, reference time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	relative := strings.TrimSpace(strings.TrimSuffix(value, "ago"))
+	if t, ok, err := parseRelative(relative, reference); ok {
+		return t, err
+	}
+
+	if i := strings.LastIndexByte(value, ' '); i > 0 {
+		zone := value[i+1:]
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone %q: %v", zone, err)
+		}
+		stamp := strings.TrimSpace(value[:i])
+		if strings.ContainsAny(stamp, "zZ+") || strings.Count(stamp, "-") == 3 {
+			return time.Time{}, fmt.Errorf("timestamp %q has both a timezone offset and name", value)
+		}
+		// Parse the wall clock in UTC and move it into the timezone,
+		// which may have a different offset at the time than now.
+		t, err := parseTimestampValue(stamp, reference.In(time.UTC))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), nil
+	}
+	return parseTimestampValue(value, reference)
+}
+
+// parseTimestampValue parses the value with GetTimestamp.
+func parseTimestampValue(value string, reference time.Time) (time.Time, error) {
+	stamp, err := GetTimestamp(value, reference)
+	if err != nil {
+		return time.Time{}, err
+	}
+	secs, nanoSecs, err := parseTimestamp(stamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse value as time or duration: %q", value)
+	}
+	return time.Unix(secs, nanoSecs), nil
+}
+
+// parseRelative parses a relative expression.  It returns false if the
+// value is not one, so it can be parsed as a timestamp.
+func parseRelative(value string, reference time.Time) (time.Time, bool, error) {
+	if value == "" || !relativeRegex.MatchString(value) {
+		return time.Time{}, false, nil
+	}
+	var (
+		years, months int
+		duration      time.Duration
+	)
+	rest := value
+	for rest != "" {
+		m := relativeRegex.FindStringSubmatch(rest)
+		if m == nil {
+			return time.Time{}, true, fmt.Errorf("invalid relative time %q", value)
+		}
+		rest = strings.TrimSpace(rest[len(m[0]):])
+		number, unit := m[1], m[2]
+
+		switch unit {
+		case "d", "day", "days", "w", "week", "weeks":
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return time.Time{}, true, err
+			}
+			days := n
+			if unit[0] == 'w' {
+				days *= 7
+			}
+			duration += time.Duration(days * float64(24*time.Hour))
+		case "mo", "month", "months", "y", "year", "years":
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return time.Time{}, true, fmt.Errorf("invalid relative time %q: months and years must be integers", value)
+			}
+			if unit[0] == 'y' {
+				years += n
+			} else {
+				months += n
+			}
+		default:
+			d, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return time.Time{}, true, fmt.Errorf("invalid relative time %q: unknown unit %q", value, unit)
+			}
+			duration += d
+		}
+	}
+	return reference.AddDate(-years, -months, 0).Add(-duration), true, nil
+}
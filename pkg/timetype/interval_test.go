@@ -0,0 +1,83 @@
+package timetype
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	reference := time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	cases := []struct {
+		in       string
+		expected time.Time
+	}{
+		{"2h", reference.Add(-2 * time.Hour)},
+		{"30m ago", reference.Add(-30 * time.Minute)},
+		{"1w2d ago", reference.Add(-9 * 24 * time.Hour)},
+		{"3 days", reference.Add(-72 * time.Hour)},
+		{"1.5d", reference.Add(-36 * time.Hour)},
+		{"1mo", time.Date(2021, 3, 3, 12, 0, 0, 0, time.UTC)}, // February has 28 days
+		{"1y2h", time.Date(2020, 3, 31, 10, 0, 0, 0, time.UTC)},
+		{"2021-03-01T10:00:00Z", time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"1136214245", time.Unix(1136214245, 0)},
+		// Berlin is at UTC+1 in March before and UTC+2 after the DST switch
+		{"2021-03-01T10:00 Europe/Berlin", time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{"2021-04-01 Europe/Berlin", time.Date(2021, 4, 1, 0, 0, 0, 0, berlin)},
+	}
+	for _, c := range cases {
+		actual, err := ParseTime(c.in, reference)
+		if err != nil {
+			t.Errorf("ParseTime(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if !actual.Equal(c.expected) {
+			t.Errorf("ParseTime(%q): expected %s, got %s", c.in, c.expected, actual)
+		}
+	}
+
+	for _, in := range []string{"2 fortnights", "1.5mo", "2021-03-01 Mars/Olympus", "2021-03-01T10:00Z Europe/Berlin", "foo"} {
+		if _, err := ParseTime(in, reference); err == nil {
+			t.Errorf("ParseTime(%q): expected error", in)
+		}
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	reference := time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC)
+	interval, err := ParseInterval("2h..30m ago", reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !interval.Start.Equal(reference.Add(-2*time.Hour)) || !interval.End.Equal(reference.Add(-30*time.Minute)) {
+		t.Fatalf("unexpected interval %v", interval)
+	}
+	if !interval.Contains(reference.Add(-time.Hour)) || interval.Contains(reference.Add(-10*time.Minute)) || interval.Contains(reference.Add(-3*time.Hour)) {
+		t.Fatalf("unexpected containment of interval %v", interval)
+	}
+
+	interval, err = ParseInterval("2021-03-01..", reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !interval.End.IsZero() || !interval.Contains(reference.AddDate(10, 0, 0)) || interval.Contains(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected open interval %v", interval)
+	}
+
+	interval, err = ParseInterval("..2021-03-01T00:00:00.5Z", reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !interval.Start.IsZero() || !interval.End.Equal(time.Date(2021, 3, 1, 0, 0, 0, 5e8, time.UTC)) {
+		t.Fatalf("unexpected open interval %v", interval)
+	}
+
+	for _, in := range []string{"2h", "30m..2h ago", "foo..1h"} {
+		if _, err := ParseInterval(in, reference); err == nil {
+			t.Errorf("ParseInterval(%q): expected error", in)
+		}
+	}
+}
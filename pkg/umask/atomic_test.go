@@ -0,0 +1,42 @@
+package umask
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomicWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomic")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.json")
+
+	if runtime.GOOS != "windows" {
+		oldUmask := Set(0077)
+		defer Set(oldUmask)
+	}
+	require.NoError(t, ioutil.WriteFile(path, []byte("old"), 0600))
+	require.NoError(t, AtomicWriteFile(path, []byte("new"), 0644))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	}
+
+	// No temporary files are left behind.
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	assert.Error(t, AtomicWriteFile(filepath.Join(dir, "missing", "file"), nil, 0644))
+}
@@ -3,6 +3,7 @@
 package umask
 
 import (
+	"os"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
@@ -18,3 +19,13 @@ func Check() {
 func Set(value int) int {
 	return syscall.Umask(value)
 }
+
+// syncDir syncs the directory entries, e.g. of a renamed file, to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}